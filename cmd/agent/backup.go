@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+	"github.com/ibyeong-geon/multinic-agent/pkg/hostexec"
+	"github.com/ibyeong-geon/multinic-agent/pkg/netplan"
+)
+
+const backupUsage = `usage:
+  backup list                 백업 목록 출력 (최신순)
+  backup diff <id> [<id>]     백업과 현재 파일(또는 다른 백업)의 차이 출력
+  backup restore [-apply] <id>  백업을 원래 파일로 복원 (-apply: netplan 적용까지 수행)`
+
+// runBackupCommand는 노드의 netplan 백업을 조회, 비교, 복원합니다
+func runBackupCommand(cfg *config.Config, logger *zap.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New(backupUsage)
+	}
+
+	backups := netplan.BackupManagerFromConfig(&cfg.Netplan, logger)
+
+	switch args[0] {
+	case "list":
+		return listBackups(backups)
+	case "diff":
+		return diffBackup(backups, args[1:])
+	case "restore":
+		return restoreBackup(cfg, backups, logger, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], backupUsage)
+	}
+}
+
+// listBackups는 인덱스의 백업 메타데이터를 표로 출력합니다
+func listBackups(backups *netplan.BackupManager) error {
+	entries, err := backups.List()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Printf("no backups in %s\n", backups.Dir())
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tREASON\tSIZE\tSHA256\tREVISION\tSOURCE")
+	for _, entry := range entries {
+		hash := entry.SHA256
+		if len(hash) > 12 {
+			hash = hash[:12]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			entry.ID,
+			entry.CreatedAt.Local().Format(time.DateTime),
+			entry.Reason,
+			entry.Size,
+			valueOrDash(hash),
+			valueOrDash(entry.Revision),
+			entry.Source)
+	}
+	return w.Flush()
+}
+
+// diffBackup은 백업을 원래 파일의 현재 내용 또는 다른 백업과 비교합니다
+func diffBackup(backups *netplan.BackupManager, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New(backupUsage)
+	}
+
+	entry, err := backups.Get(args[0])
+	if err != nil {
+		return err
+	}
+	from, err := backups.Read(entry)
+	if err != nil {
+		return err
+	}
+	fromName := filepath.Join(backups.Dir(), entry.File)
+
+	var to []byte
+	var toName string
+	if len(args) == 2 {
+		other, err := backups.Get(args[1])
+		if err != nil {
+			return err
+		}
+		if to, err = backups.Read(other); err != nil {
+			return err
+		}
+		toName = filepath.Join(backups.Dir(), other.File)
+	} else {
+		toName = entry.Source
+		to, err = os.ReadFile(entry.Source)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	diff := netplan.UnifiedDiff(fromName, toName, from, to)
+	if diff == "" {
+		fmt.Println("no differences")
+		return nil
+	}
+	fmt.Print(diff)
+	return nil
+}
+
+// restoreBackup은 백업을 원래 위치에 복원하고, 요청 시 netplan을 적용합니다
+func restoreBackup(cfg *config.Config, backups *netplan.BackupManager, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("backup restore", flag.ContinueOnError)
+	apply := flags.Bool("apply", false, "apply the restored configuration with netplan")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New(backupUsage)
+	}
+
+	entry, err := backups.Restore(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("restored %s from backup %s\n", entry.Source, entry.ID)
+
+	if !*apply {
+		fmt.Println("not applied - run 'netplan apply' to activate it")
+		return nil
+	}
+
+	executor, err := hostexec.New(&cfg.HostExec, logger)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signalContext()
+	defer stop()
+	netplanManager := netplan.NewNetplanManager(&cfg.Netplan, nil, nil, nil, executor, logger)
+	if err := netplanManager.ValidateNetplan(ctx); err != nil {
+		return err
+	}
+	result, err := netplanManager.ApplyNetplan(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("applied with %s\n", result.Method)
+	return nil
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,575 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+	"github.com/ibyeong-geon/multinic-agent/pkg/database"
+	"github.com/ibyeong-geon/multinic-agent/pkg/hostexec"
+	"github.com/ibyeong-geon/multinic-agent/pkg/inventory"
+	"github.com/ibyeong-geon/multinic-agent/pkg/logger"
+	"github.com/ibyeong-geon/multinic-agent/pkg/netplan"
+	"github.com/ibyeong-geon/multinic-agent/pkg/sysfs"
+)
+
+// commandEnv는 모든 하위 명령이 공유하는 설정과 로거입니다
+type commandEnv struct {
+	cfg    *config.Config
+	logger *zap.Logger
+	// node는 -node 플래그로 지정한 노드 이름입니다 (지정하지 않으면 빈 값)
+	node string
+}
+
+// command는 에이전트 하위 명령입니다
+type command struct {
+	name    string
+	args    string
+	summary string
+	// daemon 명령은 로그를 stdout에 남기고, 나머지는 출력과 섞이지 않도록 stderr로 보냅니다
+	daemon bool
+	// setup은 명령별 플래그를 등록하고 실행 함수를 반환합니다
+	setup func(flags *flag.FlagSet) func(env *commandEnv, args []string) error
+}
+
+var commands = []command{
+	{
+		name:    "run",
+		summary: "DB를 주기적으로 확인하며 netplan을 적용 (기본 명령)",
+		daemon:  true,
+		setup: func(flags *flag.FlagSet) func(*commandEnv, []string) error {
+			return func(env *commandEnv, args []string) error {
+				return runAgent(env, false)
+			}
+		},
+	},
+	{
+		name:    "render",
+		args:    "[-input rows.json|yaml] [-out-dir dir]",
+		summary: "노드의 netplan YAML을 호스트를 건드리지 않고 출력 (-input: DB 대신 행 덤프 사용)",
+		setup: func(flags *flag.FlagSet) func(*commandEnv, []string) error {
+			input := flags.String("input", "", "render from a JSON/YAML dump of NodeInterface rows instead of the database")
+			outDir := flags.String("out-dir", "", "write 99-multinic-<node>.yaml files to this directory instead of stdout")
+			return func(env *commandEnv, args []string) error {
+				return render(env, *input, *outDir)
+			}
+		},
+	},
+	{
+		name:    "diff",
+		summary: "DB 기준으로 생성할 netplan 파일과 디스크의 파일 비교",
+		setup: func(flags *flag.FlagSet) func(*commandEnv, []string) error {
+			return func(env *commandEnv, args []string) error {
+				return diffDesired(env)
+			}
+		},
+	},
+	{
+		name:    "apply",
+		args:    "[-once]",
+		summary: "netplan 적용 (-once: 한 번만 조정하고 포트별 결과를 출력한 뒤 종료)",
+		setup: func(flags *flag.FlagSet) func(*commandEnv, []string) error {
+			once := flags.Bool("once", false, "reconcile once and exit")
+			return func(env *commandEnv, args []string) error {
+				return runAgent(env, *once)
+			}
+		},
+	},
+	{
+		name:    "status",
+		summary: "포트별 적용 상태, 파일 동기화 여부, 마지막 적용 결과 출력",
+		setup: func(flags *flag.FlagSet) func(*commandEnv, []string) error {
+			return func(env *commandEnv, args []string) error {
+				return showStatus(env)
+			}
+		},
+	},
+	{
+		name:    "validate-config",
+		args:    "[-show]",
+		summary: "설정(파일과 환경변수)을 검증 (-show: 적용된 설정 출력)",
+		setup: func(flags *flag.FlagSet) func(*commandEnv, []string) error {
+			show := flags.Bool("show", false, "print the effective configuration")
+			return func(env *commandEnv, args []string) error {
+				return validateConfig(env, *show)
+			}
+		},
+	},
+	{
+		name:    "rollback",
+		args:    "[-to <id>] [-apply=false]",
+		summary: "노드 netplan 파일을 이전 백업으로 되돌리고 적용",
+		setup: func(flags *flag.FlagSet) func(*commandEnv, []string) error {
+			to := flags.String("to", "", "backup ID to restore (default: the latest backup that differs from the current file)")
+			apply := flags.Bool("apply", true, "apply the restored configuration with netplan")
+			return func(env *commandEnv, args []string) error {
+				return rollback(env, *to, *apply)
+			}
+		},
+	},
+	{
+		name:    "backup",
+		args:    "list|diff|restore ...",
+		summary: "netplan 백업 조회, 비교, 복원",
+		setup: func(flags *flag.FlagSet) func(*commandEnv, []string) error {
+			return func(env *commandEnv, args []string) error {
+				return runBackupCommand(env.cfg, env.logger, args)
+			}
+		},
+	},
+	{
+		name:    "migrate",
+		args:    "status|up|down|baseline ...",
+		summary: "내장 마이그레이션으로 DB 스키마 조회 및 변경",
+		setup: func(flags *flag.FlagSet) func(*commandEnv, []string) error {
+			return runMigrateCommand
+		},
+	},
+}
+
+// runCommand는 전역 플래그와 하위 명령을 해석해 실행하고 종료 코드를 반환합니다
+//
+//	multinic-agent [-config path] [command] [-node name] [command flags]
+func runCommand(args []string) int {
+	global := flag.NewFlagSet("multinic-agent", flag.ContinueOnError)
+	global.Usage = func() { printUsage(global.Output()) }
+	var configPath string
+	global.StringVar(&configPath, "config", "", "configuration file path")
+	if err := global.Parse(args); err != nil {
+		return 2
+	}
+
+	name := "run"
+	rest := global.Args()
+	if len(rest) > 0 {
+		name, rest = rest[0], rest[1:]
+	}
+	if name == "help" {
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == name {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", name)
+		printUsage(os.Stderr)
+		return 2
+	}
+
+	// 공통 플래그 (-config는 명령 뒤에도 지정 가능)
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.StringVar(&configPath, "config", configPath, "configuration file path")
+	node := flags.String("node", "", "node name (default: agent.node_name or hostname)")
+	run := cmd.setup(flags)
+	if err := flags.Parse(rest); err != nil {
+		return 2
+	}
+
+	// 설정 로드
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Printf("Failed to load configuration: %v", err)
+		return 1
+	}
+	if *node != "" {
+		cfg.Agent.NodeName = *node
+	}
+	loggingCfg := cfg.Logging
+	if !cmd.daemon && loggingCfg.Output == "stdout" {
+		loggingCfg.Output = "stderr"
+	}
+
+	// 로거 초기화
+	zapLogger, err := logger.NewLogger(&loggingCfg)
+	if err != nil {
+		log.Printf("Failed to initialize logger: %v", err)
+		return 1
+	}
+	defer zapLogger.Sync()
+
+	// 설정 로그 출력
+	zapLogger.Info("Configuration loaded",
+		zap.String("command", cmd.name),
+		zap.String("db_host", cfg.Database.Host),
+		zap.Int("db_port", cfg.Database.Port),
+		zap.String("node_name", cfg.Agent.NodeName),
+		zap.Int("check_interval", cfg.Agent.CheckInterval),
+		zap.String("log_level", cfg.Logging.Level),
+	)
+
+	if err := run(&commandEnv{cfg: cfg, logger: zapLogger, node: *node}, flags.Args()); err != nil {
+		zapLogger.Debug("Command failed", zap.String("command", cmd.name), zap.Error(err))
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.name, err)
+		return 1
+	}
+	return 0
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: multinic-agent [-config path] [command] [-node name] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s %s\t%s\n", cmd.name, cmd.args, cmd.summary)
+	}
+	tw.Flush()
+}
+
+// runAgent는 DB 연결과 호스트 실행기를 준비한 뒤 조정 루프를 실행합니다 (once이면 한 번만)
+func runAgent(env *commandEnv, once bool) error {
+	cfg, zapLogger := env.cfg, env.logger
+
+	// 잘못된 설정으로 호스트 네트워크를 건드리지 않도록 시작하지 않음
+	if err := cfg.Validate(); err != nil {
+		zapLogger.Error("Configuration is invalid", zap.Error(err))
+		return fmt.Errorf("invalid configuration (check with `multinic-agent validate-config`): %w", err)
+	}
+
+	zapLogger.Info("Starting agent...")
+
+	// DB 연결
+	dbClient, err := database.NewClient(&cfg.Database, zapLogger)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer dbClient.Close()
+
+	// 실행 환경 자가 점검 (컨테이너 여부, capability, 가능한 작업 보고)
+	hostexec.SelfCheck(&cfg.HostExec, cfg.Netplan.ConfigPath, cfg.Agent.SysfsRoot).Log(zapLogger)
+
+	// 호스트 명령 실행기 (netplan, ip 명령을 호스트 컨텍스트에서 실행)
+	executor, err := hostexec.New(&cfg.HostExec, zapLogger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize host command executor: %w", err)
+	}
+
+	a := newAgent(cfg, dbClient, executor, zapLogger)
+
+	if once {
+		// 적용 중에 중단되어도 실행 중인 명령과 확인 대기를 정리하도록 시그널로 취소
+		ctx, stop := signalContext()
+		defer stop()
+		results, err := a.reconcile(ctx)
+		if err != nil {
+			return err
+		}
+		return printResults(results)
+	}
+
+	// TODO: Kubernetes 클라이언트 초기화
+
+	// Context for graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 시그널 핸들링 (graceful shutdown)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	// 메인 루프를 고루틴으로 시작
+	go runMainLoop(ctx, a)
+
+	// 시그널 대기
+	sig := <-sigChan
+	zapLogger.Info("Received signal", zap.String("signal", sig.String()))
+	zapLogger.Info("Shutting down agent...")
+
+	// Context 취소로 모든 고루틴 종료
+	cancel()
+
+	// 정리 작업을 위한 약간의 대기 시간
+	time.Sleep(2 * time.Second)
+
+	zapLogger.Info("Agent shutdown complete")
+	return nil
+}
+
+// printResults는 apply -once의 포트별 결과를 출력하고, 실패한 포트가 있으면 오류를 반환합니다
+func printResults(results map[string]portStatus) error {
+	if len(results) == 0 {
+		fmt.Println("no interfaces for this node")
+		return nil
+	}
+
+	portIDs := make([]string, 0, len(results))
+	for portID := range results {
+		portIDs = append(portIDs, portID)
+	}
+	sort.Strings(portIDs)
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PORT\tRESULT\tREASON")
+	for _, portID := range portIDs {
+		status := results[portID]
+		result := "applied"
+		if !status.success {
+			result = "failed"
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", portID, result, valueOrDash(status.reason))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d ports not applied", failed, len(results))
+	}
+	return nil
+}
+
+// signalContext는 SIGINT/SIGTERM을 받으면 취소되는 컨텍스트를 반환합니다 (한 번 실행하는 명령용)
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
+
+// newOfflineNetplanManager는 호스트에 접근하지 않는 NetplanManager를 생성합니다 (render, diff, status용)
+// 모두 같은 렌더러를 사용하므로 같은 입력에 대해 같은 YAML을 출력합니다
+func newOfflineNetplanManager(cfg *config.Config, logger *zap.Logger) *netplan.NetplanManager {
+	return netplan.NewRenderer(&cfg.Netplan, logger)
+}
+
+// renderDesired는 DB의 원하는 상태로 노드의 netplan YAML을 생성합니다
+// 호스트에 없는 MAC이나 보호 대상 필터링은 적용되지 않으며, 기존 파일의 라우팅 테이블 번호도 이어받지 않습니다
+func renderDesired(env *commandEnv) (*netplan.NetplanManager, []byte, error) {
+	nodeName, err := resolveNodeName(env.cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dbClient, err := database.NewClient(&env.cfg.Database, env.logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer dbClient.Close()
+
+	interfaces, err := dbClient.GetNodeInterfaces(nodeName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 에이전트와 같은 결과가 되도록 거부된 행은 제외
+	valid := validateInterfaces(nodeName, interfaces, env.logger).Valid
+
+	netplanManager := newOfflineNetplanManager(env.cfg, env.logger)
+	desired, err := netplanManager.RenderNetplanConfig(nodeName, netplan.FromNodeInterfaces(valid))
+	if err != nil {
+		return nil, nil, err
+	}
+	return netplanManager, desired, nil
+}
+
+// diffDesired는 생성할 netplan 파일과 디스크의 파일 차이를 출력합니다
+func diffDesired(env *commandEnv) error {
+	nodeName, err := resolveNodeName(env.cfg)
+	if err != nil {
+		return err
+	}
+	netplanManager, desired, err := renderDesired(env)
+	if err != nil {
+		return err
+	}
+
+	path := netplanManager.NetplanFilePath(nodeName)
+	current, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	diff := netplan.UnifiedDiff(path, "desired ("+nodeName+")", current, desired)
+	if diff == "" {
+		fmt.Println("no differences")
+		return nil
+	}
+	fmt.Print(diff)
+	return nil
+}
+
+// showStatus는 DB의 포트별 적용 상태, 호스트의 MAC 존재 여부, 파일 동기화 여부와 마지막 적용 결과를 출력합니다
+func showStatus(env *commandEnv) error {
+	nodeName, err := resolveNodeName(env.cfg)
+	if err != nil {
+		return err
+	}
+
+	dbClient, err := database.NewClient(&env.cfg.Database, env.logger)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer dbClient.Close()
+
+	interfaces, err := dbClient.GetNodeInterfaces(nodeName)
+	if err != nil {
+		return err
+	}
+
+	// 호스트 링크 조회는 읽기 전용
+	links, linksErr := inventory.NewNetlinkInventory(sysfs.New(env.cfg.Agent.SysfsRoot)).Links()
+
+	fmt.Printf("node: %s\n\n", nodeName)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PORT\tMAC\tSUBNET\tHOST\tNETPLAN\tREASON")
+	for _, iface := range interfaces {
+		host := "unknown"
+		if linksErr == nil {
+			host = "missing"
+			if link, ok := inventory.FindByMAC(links, iface.MacAddress); ok {
+				host = link.Name
+			}
+		}
+		result := "failed"
+		if iface.NetplanSuccess {
+			result = "applied"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			iface.PortID, iface.MacAddress, iface.SubnetName, host, result, valueOrDash(iface.NetplanReason))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(interfaces) == 0 {
+		fmt.Println("(no interfaces in database)")
+	}
+
+	netplanManager := newOfflineNetplanManager(env.cfg, env.logger)
+	path := netplanManager.NetplanFilePath(nodeName)
+	fmt.Printf("\nfile: %s ", path)
+	current, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		fmt.Println("(not present)")
+	case err != nil:
+		fmt.Printf("(unreadable: %v)\n", err)
+	default:
+		valid := validateInterfaces(nodeName, interfaces, env.logger).Valid
+		desired, renderErr := netplanManager.RenderNetplanConfig(nodeName, netplan.FromNodeInterfaces(valid))
+		switch {
+		case renderErr != nil:
+			fmt.Printf("(cannot render desired state: %v)\n", renderErr)
+		case string(desired) == string(current):
+			fmt.Println("(in sync with database)")
+		default:
+			fmt.Println("(differs from database - see 'diff')")
+		}
+	}
+
+	applyLog, err := dbClient.GetLatestApplyLog(nodeName)
+	if err != nil {
+		return err
+	}
+	if applyLog == nil {
+		fmt.Println("last apply: none recorded")
+		return nil
+	}
+	fmt.Printf("last apply: %s method=%s success=%t confirmed=%t reverted=%t duration=%dms\n",
+		applyLog.AppliedAt.Local().Format(time.DateTime),
+		applyLog.Method,
+		applyLog.Success,
+		applyLog.Confirmed,
+		applyLog.Reverted,
+		applyLog.Duration)
+	if applyLog.Error != "" {
+		fmt.Printf("  error: %s\n", applyLog.Error)
+	}
+	return nil
+}
+
+// validateConfig는 설정을 검증하고, 요청 시 비밀번호를 가린 적용 설정을 출력합니다
+func validateConfig(env *commandEnv, show bool) error {
+	if show {
+		data, err := yaml.Marshal(env.cfg.Redacted())
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+	}
+
+	if err := env.cfg.Validate(); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Printf("invalid: %s\n", line)
+		}
+		return errors.New("configuration is invalid")
+	}
+
+	fmt.Println("configuration is valid")
+	return nil
+}
+
+// rollback은 노드의 netplan 파일을 백업으로 되돌립니다
+// ID를 지정하지 않으면 현재 파일과 내용이 다른 가장 최근 백업을 사용합니다
+func rollback(env *commandEnv, id string, apply bool) error {
+	nodeName, err := resolveNodeName(env.cfg)
+	if err != nil {
+		return err
+	}
+
+	netplanManager := newOfflineNetplanManager(env.cfg, env.logger)
+	backups := netplan.BackupManagerFromConfig(&env.cfg.Netplan, env.logger)
+	path := netplanManager.NetplanFilePath(nodeName)
+
+	if id == "" {
+		entry, err := previousBackup(backups, path)
+		if err != nil {
+			return err
+		}
+		id = entry.ID
+	}
+
+	args := []string{id}
+	if apply {
+		args = []string{"-apply", id}
+	}
+	if err := restoreBackup(env.cfg, backups, env.logger, args); err != nil {
+		return err
+	}
+
+	fmt.Println("note: a running agent re-applies the database state on its next check")
+	return nil
+}
+
+// previousBackup은 path의 백업 중 현재 내용과 다른 가장 최근 백업을 찾습니다
+func previousBackup(backups *netplan.BackupManager, path string) (*netplan.BackupEntry, error) {
+	current, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	entries, err := backups.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Source != path || entry.Reason == netplan.BackupReasonRestore {
+			continue
+		}
+		data, err := backups.Read(&entry)
+		if err != nil {
+			continue
+		}
+		if string(data) != string(current) {
+			return &entry, nil
+		}
+	}
+	return nil, fmt.Errorf("no backup of %s differs from the current file", path)
+}
@@ -2,92 +2,77 @@ package main
 
 import (
 	"context"
-	"flag"
-	"log"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
 	"os"
-	"os/signal"
-	"syscall"
+	"slices"
 	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/ibyeong-geon/multinic-agent/internal/config"
+	"github.com/ibyeong-geon/multinic-agent/pkg/connectivity"
 	"github.com/ibyeong-geon/multinic-agent/pkg/database"
-	"github.com/ibyeong-geon/multinic-agent/pkg/logger"
+	"github.com/ibyeong-geon/multinic-agent/pkg/hostexec"
+	"github.com/ibyeong-geon/multinic-agent/pkg/inventory"
 	"github.com/ibyeong-geon/multinic-agent/pkg/netplan"
+	"github.com/ibyeong-geon/multinic-agent/pkg/sriov"
+	"github.com/ibyeong-geon/multinic-agent/pkg/sysfs"
+	"github.com/ibyeong-geon/multinic-agent/pkg/validation"
 )
 
 func main() {
-	// 커맨드라인 플래그 파싱
-	var configPath string
-	flag.StringVar(&configPath, "config", "", "configuration file path")
-	flag.Parse()
-
-	// 설정 로드
-	cfg, err := config.Load(configPath)
-	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
-	}
-
-	// 로거 초기화
-	zapLogger, err := logger.NewLogger(&cfg.Logging)
-	if err != nil {
-		log.Fatalf("Failed to initialize logger: %v", err)
-	}
-	defer zapLogger.Sync()
-
-	// 설정 로그 출력
-	zapLogger.Info("Configuration loaded",
-		zap.String("db_host", cfg.Database.Host),
-		zap.Int("db_port", cfg.Database.Port),
-		zap.String("node_name", cfg.Agent.NodeName),
-		zap.Int("check_interval", cfg.Agent.CheckInterval),
-		zap.String("log_level", cfg.Logging.Level),
-	)
+	os.Exit(runCommand(os.Args[1:]))
+}
 
-	zapLogger.Info("Starting agent...")
+// agent는 노드 하나를 조정(reconcile)하는 데 필요한 구성 요소를 묶습니다
+type agent struct {
+	cfg            *config.Config
+	dbClient       *database.Client
+	inventory      inventory.Inventory
+	netplanManager *netplan.NetplanManager
+	reporter       *hostReporter
+	logger         *zap.Logger
+}
 
-	// DB 연결
-	dbClient, err := database.NewClient(&cfg.Database, zapLogger)
-	if err != nil {
-		zapLogger.Fatal("Failed to connect to database", zap.Error(err))
+// newAgent는 호스트 sysfs/netlink 기반 구성 요소로 agent를 생성합니다
+// NetplanManager는 대기 중인 인터페이스 상태를 유지하므로 루프 전체에서 재사용
+func newAgent(cfg *config.Config, dbClient *database.Client, executor hostexec.HostExecutor, logger *zap.Logger) *agent {
+	hostSysfs := sysfs.New(cfg.Agent.SysfsRoot)
+	hostInventory := inventory.NewNetlinkInventory(hostSysfs)
+	checker := newConnectivityChecker(cfg, dbClient, logger)
+
+	return &agent{
+		cfg:            cfg,
+		dbClient:       dbClient,
+		inventory:      hostInventory,
+		netplanManager: newNetplanManager(cfg, hostSysfs, hostInventory, checker, executor, logger),
+		reporter:       &hostReporter{inventory: hostInventory, dbClient: dbClient, logger: logger},
+		logger:         logger,
 	}
-	defer dbClient.Close()
-
-	// TODO: Kubernetes 클라이언트 초기화
-
-	// Context for graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// 시그널 핸들링 (graceful shutdown)
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	// 메인 루프를 고루틴으로 시작
-	go runMainLoop(ctx, cfg, dbClient, zapLogger)
-
-	// 시그널 대기
-	sig := <-sigChan
-	zapLogger.Info("Received signal", zap.String("signal", sig.String()))
-	zapLogger.Info("Shutting down agent...")
-
-	// Context 취소로 모든 고루틴 종료
-	cancel()
-
-	// 정리 작업을 위한 약간의 대기 시간
-	time.Sleep(2 * time.Second)
+}
 
-	zapLogger.Info("Agent shutdown complete")
+// reconcile은 DB의 원하는 상태를 한 번 적용하고 포트별 결과를 반환합니다
+func (a *agent) reconcile(ctx context.Context) (map[string]portStatus, error) {
+	return processNetworkInterfaces(ctx, a.cfg, a.dbClient, a.netplanManager, a.reporter, a.logger)
 }
 
 // runMainLoop는 주기적으로 DB를 체크하고 필요한 작업을 수행합니다
-func runMainLoop(ctx context.Context, cfg *config.Config, dbClient *database.Client, logger *zap.Logger) {
-	ticker := time.NewTicker(time.Duration(cfg.Agent.CheckInterval) * time.Second)
+func runMainLoop(ctx context.Context, a *agent) {
+	logger := a.logger
+	ticker := time.NewTicker(time.Duration(a.cfg.Agent.CheckInterval) * time.Second)
 	defer ticker.Stop()
 
+	// 링크 이벤트 구독 (핫플러그된 NIC를 다음 주기까지 기다리지 않고 즉시 반영)
+	linkEvents, err := a.inventory.Watch(ctx)
+	if err != nil {
+		logger.Warn("Failed to watch link events - hotplugged interfaces wait for the next check", zap.Error(err))
+	}
+
 	// 시작하자마자 한 번 실행
-	if err := processNetworkInterfaces(cfg, dbClient, logger); err != nil {
+	if _, err := a.reconcile(ctx); err != nil {
 		logger.Error("Failed to process network interfaces", zap.Error(err))
 	}
 
@@ -97,36 +82,92 @@ func runMainLoop(ctx context.Context, cfg *config.Config, dbClient *database.Cli
 			logger.Info("Main loop stopped")
 			return
 		case <-ticker.C:
-			if err := processNetworkInterfaces(cfg, dbClient, logger); err != nil {
+			if _, err := a.reconcile(ctx); err != nil {
+				logger.Error("Failed to process network interfaces", zap.Error(err))
+			}
+		case event, ok := <-linkEvents:
+			if !ok {
+				linkEvents = nil
+				continue
+			}
+			if !a.netplanManager.IsPendingMAC(event.MACAddress) {
+				continue
+			}
+			logger.Info("Pending interface appeared - reconciling",
+				zap.String("interface", event.Name),
+				zap.String("mac", event.MACAddress))
+			if _, err := a.reconcile(ctx); err != nil {
 				logger.Error("Failed to process network interfaces", zap.Error(err))
 			}
 		}
 	}
 }
 
-// processNetworkInterfaces는 네트워크 인터페이스를 처리합니다
-func processNetworkInterfaces(cfg *config.Config, dbClient *database.Client, logger *zap.Logger) error {
-	nodeName := cfg.Agent.NodeName
-	if nodeName == "" {
-		// 노드 이름이 없으면 호스트명 사용
-		hostname, err := os.Hostname()
-		if err != nil {
-			return err
-		}
-		nodeName = hostname
+// newNetplanManager는 설정에 따라 NetplanManager를 생성합니다 (DRY_RUN 환경변수 또는 netplan.dry_run으로 제어)
+func newNetplanManager(cfg *config.Config, hostSysfs sysfs.FS, hostInventory inventory.Inventory, checker *connectivity.Checker, executor hostexec.HostExecutor, logger *zap.Logger) *netplan.NetplanManager {
+	netplanCfg := cfg.Netplan
+	if cfg.Agent.NodeIP != "" {
+		// 노드 IP를 가진 인터페이스는 관리 대상에서 제외
+		netplanCfg.ProtectedAddresses = append(slices.Clone(netplanCfg.ProtectedAddresses), cfg.Agent.NodeIP)
+	}
+	if os.Getenv("DRY_RUN") == "true" {
+		netplanCfg.DryRun = true
+	}
+	if netplanCfg.DryRun {
+		logger.Info("Running in DRY RUN mode - netplan files will not be applied")
+	}
+
+	sriovManager := sriov.NewManager(hostSysfs, logger)
+	return netplan.NewNetplanManager(&netplanCfg, sriovManager, hostInventory, checker, executor, logger)
+}
+
+// newConnectivityChecker는 safe apply 후 확인할 연결 대상을 구성합니다
+// (DB, Kubernetes API 서버, 설정된 host:port 대상)
+func newConnectivityChecker(cfg *config.Config, dbClient *database.Client, logger *zap.Logger) *connectivity.Checker {
+	checker := connectivity.NewChecker(logger)
+	checker.Add("database", dbClient.Ping)
+
+	// Pod에는 API 서버 주소가 환경변수로 주입됨
+	if host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT"); host != "" && port != "" {
+		checker.AddTCP(net.JoinHostPort(host, port))
+	}
+
+	for _, target := range cfg.Netplan.ConnectivityTargets {
+		checker.AddTCP(target)
+	}
+
+	return checker
+}
+
+// resolveNodeName은 설정된 노드 이름을, 없으면 호스트명을 반환합니다
+func resolveNodeName(cfg *config.Config) (string, error) {
+	if cfg.Agent.NodeName != "" {
+		return cfg.Agent.NodeName, nil
+	}
+	return os.Hostname()
+}
+
+// processNetworkInterfaces는 네트워크 인터페이스를 처리하고 포트별 결과를 반환합니다
+func processNetworkInterfaces(ctx context.Context, cfg *config.Config, dbClient *database.Client, netplanManager *netplan.NetplanManager, reporter *hostReporter, logger *zap.Logger) (map[string]portStatus, error) {
+	nodeName, err := resolveNodeName(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	logger.Info("Processing network interfaces", zap.String("node_name", nodeName))
 
+	// 호스트 인터페이스 목록 보고 (DB 포트가 없는 노드도 보고)
+	reporter.report(nodeName)
+
 	// DB에서 네트워크 인터페이스 정보 조회
 	interfaces, err := dbClient.GetNodeInterfaces(nodeName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(interfaces) == 0 {
 		logger.Warn("No interfaces found for node", zap.String("node_name", nodeName))
-		return nil
+		return nil, nil
 	}
 
 	logger.Info("Found interfaces",
@@ -146,72 +187,208 @@ func processNetworkInterfaces(cfg *config.Config, dbClient *database.Client, log
 			zap.String("cr_name", iface.CRName),
 			zap.Bool("netplan_success", iface.NetplanSuccess),
 			zap.String("status", iface.Status),
+			zap.Int("fixed_ip_count", len(iface.FixedIPs)),
+			zap.String("parent_port_id", iface.ParentPortID),
+			zap.Int("vlan_id", iface.VLANID),
+			zap.Bool("group_member", iface.Group != nil),
+			zap.String("vnic_type", iface.VNICType),
+			zap.String("pci_address", iface.PCIAddress),
 		)
 	}
 
-	// Netplan 기능 적용
-	success := processNetplanConfiguration(nodeName, interfaces, logger)
+	// 형식이 잘못된 행은 netplan에 넣지 않고 포트별 사유와 함께 실패로 보고
+	checked := validateInterfaces(nodeName, interfaces, logger)
+
+	// Netplan 기능 적용 (유효한 행이 하나도 없으면 기존 파일을 그대로 둠)
+	results := make(map[string]portStatus, len(interfaces))
+	if len(checked.Valid) > 0 {
+		results = processNetplanConfiguration(ctx, netplanManager, dbClient, nodeName, checked.Valid, logger)
+	}
+	for _, problem := range checked.Problems {
+		results[problem.PortID] = portStatus{reason: checked.Reason(problem.PortID)}
+	}
 
 	// 처리 결과를 DB에 업데이트
-	if err := updateNetplanStatus(dbClient, nodeName, interfaces, success, logger); err != nil {
+	if err := updateNetplanStatus(dbClient, interfaces, results, logger); err != nil {
 		logger.Error("Failed to update netplan status in database", zap.Error(err))
 	}
 
-	return nil
+	return results, nil
 }
 
-// processNetplanConfiguration processes netplan configuration for the given interfaces
-func processNetplanConfiguration(nodeName string, interfaces []database.NodeInterface, logger *zap.Logger) bool {
-	// NetplanManager 생성 (DRY_RUN 환경변수로 제어)
-	dryRun := os.Getenv("DRY_RUN") == "true"
-	if dryRun {
-		logger.Info("Running in DRY RUN mode - netplan files will not be applied")
+// validateInterfaces는 DB 행을 검사하고 거부된 포트를 로그로 남깁니다
+func validateInterfaces(nodeName string, interfaces []database.NodeInterface, logger *zap.Logger) *validation.Result {
+	checked := validation.ValidateInterfaces(interfaces)
+	for _, problem := range checked.Problems {
+		logger.Warn("Rejected invalid interface data",
+			zap.String("node_name", nodeName),
+			zap.String("port_id", problem.PortID),
+			zap.String("kind", problem.Kind),
+			zap.String("message", problem.Message))
 	}
+	return checked
+}
 
-	netplanManager := netplan.NewNetplanManager(logger, dryRun)
+// portStatus는 포트별 netplan 적용 결과입니다
+type portStatus struct {
+	success bool
+	reason  string
+}
 
-	// database.NodeInterface를 netplan.InterfaceData로 변환
-	netplanInterfaces := make([]netplan.InterfaceData, 0, len(interfaces))
-	for _, iface := range interfaces {
-		netplanInterfaces = append(netplanInterfaces, netplan.InterfaceData{
-			PortID:         iface.PortID,
-			MACAddress:     iface.MacAddress,
-			SubnetName:     iface.SubnetName,
-			CIDR:           iface.CIDR,
-			NetworkID:      iface.NetworkID,
-			NetplanSuccess: iface.NetplanSuccess,
-		})
-	}
+// processNetplanConfiguration processes netplan configuration for the given interfaces
+// and returns whether each port was applied (and why not), keyed by port ID
+func processNetplanConfiguration(ctx context.Context, netplanManager *netplan.NetplanManager, dbClient *database.Client, nodeName string, interfaces []database.NodeInterface, logger *zap.Logger) map[string]portStatus {
+	results := make(map[string]portStatus, len(interfaces))
 
 	// Netplan 구성 처리
-	if err := netplanManager.ProcessInterfaces(nodeName, netplanInterfaces); err != nil {
+	result, err := netplanManager.ProcessInterfaces(ctx, nodeName, netplan.FromNodeInterfaces(interfaces))
+	if err != nil {
 		logger.Error("Failed to process netplan configuration",
 			zap.String("node", nodeName),
 			zap.Error(err))
-		return false
 	}
 
-	return true
+	// 적용까지 진행된 경우 적용 방법과 결과를 기록
+	if result != nil && result.Apply != nil {
+		if recordErr := dbClient.RecordApplyResult(nodeName, toApplyLog(result.Apply, err)); recordErr != nil {
+			logger.Error("Failed to record netplan apply result", zap.Error(recordErr))
+		}
+	}
+
+	// 호스트에 MAC이 없거나 보호 대상이라 적용되지 않은 포트는 사유와 함께 실패로 보고
+	for _, iface := range interfaces {
+		switch {
+		case err != nil:
+			results[iface.PortID] = portStatus{reason: err.Error()}
+		case !result.Applied(iface.PortID):
+			results[iface.PortID] = portStatus{reason: result.Reason(iface.PortID)}
+		default:
+			results[iface.PortID] = portStatus{success: true}
+		}
+	}
+
+	return results
+}
+
+// toApplyLog는 netplan.ApplyResult를 DB 기록 형식으로 변환합니다
+func toApplyLog(apply *netplan.ApplyResult, err error) database.ApplyLog {
+	applyLog := database.ApplyLog{
+		Method:    apply.Method,
+		Success:   err == nil,
+		Confirmed: apply.Confirmed,
+		Reverted:  apply.Reverted,
+		Duration:  apply.Duration.Milliseconds(),
+	}
+	if err != nil {
+		applyLog.Error = err.Error()
+	}
+	if last, ok := apply.LastAttempt(); ok {
+		exitCode := last.ExitCode
+		applyLog.ExitCode = &exitCode
+		applyLog.Stdout = last.Stdout
+		applyLog.Stderr = last.Stderr
+	}
+	if len(apply.Attempts) > 0 {
+		if attempts, marshalErr := json.Marshal(apply.Attempts); marshalErr == nil {
+			applyLog.Attempts = string(attempts)
+		}
+	}
+
+	return applyLog
 }
 
 // updateNetplanStatus updates the netplan status in the database
-func updateNetplanStatus(dbClient *database.Client, nodeName string, interfaces []database.NodeInterface, success bool, logger *zap.Logger) error {
+func updateNetplanStatus(dbClient *database.Client, interfaces []database.NodeInterface, results map[string]portStatus, logger *zap.Logger) error {
 	for _, iface := range interfaces {
+		status := results[iface.PortID]
+		// DB에는 잘린 사유가 저장되므로 같은 길이로 비교
+		status.reason = database.TruncateReason(status.reason)
+
 		// 상태가 변경된 경우에만 업데이트
-		if iface.NetplanSuccess != success {
-			if err := dbClient.UpdateNetplanSuccess(iface.PortID, success); err != nil {
+		if iface.NetplanSuccess != status.success || iface.NetplanReason != status.reason {
+			if err := dbClient.UpdateNetplanStatus(iface.PortID, status.success, status.reason); err != nil {
 				logger.Error("Failed to update netplan status for interface",
 					zap.String("port_id", iface.PortID),
-					zap.Bool("success", success),
+					zap.Bool("success", status.success),
 					zap.Error(err))
 				return err
 			}
 
 			logger.Info("Updated netplan status",
 				zap.String("port_id", iface.PortID),
-				zap.Bool("success", success))
+				zap.Bool("success", status.success),
+				zap.String("reason", status.reason))
 		}
 	}
 
 	return nil
 }
+
+// hostReporter는 노드의 호스트 인터페이스 목록을 DB에 보고합니다
+// 마지막으로 보고한 목록과 달라진 경우에만 기록합니다
+type hostReporter struct {
+	inventory inventory.Inventory
+	dbClient  *database.Client
+	logger    *zap.Logger
+	lastHash  string
+}
+
+// report는 변경이 있을 때 호스트 인터페이스 목록을 기록합니다 (실패는 로그만 남김)
+func (r *hostReporter) report(nodeName string) {
+	links, err := r.inventory.Links()
+	if err != nil {
+		r.logger.Warn("Failed to list host interfaces for report", zap.Error(err))
+		return
+	}
+
+	hostInterfaces := toHostInterfaces(links)
+
+	data, err := json.Marshal(hostInterfaces)
+	if err != nil {
+		r.logger.Warn("Failed to encode host interfaces", zap.Error(err))
+		return
+	}
+	sum := sha256.Sum256(append([]byte(nodeName+"\n"), data...))
+	hash := hex.EncodeToString(sum[:])
+	if hash == r.lastHash {
+		return
+	}
+
+	if err := r.dbClient.ReportHostInterfaces(nodeName, hostInterfaces); err != nil {
+		r.logger.Error("Failed to report host interfaces", zap.Error(err))
+		return
+	}
+	r.lastHash = hash
+
+	r.logger.Info("Reported host interfaces",
+		zap.String("node_name", nodeName),
+		zap.Int("count", len(hostInterfaces)))
+}
+
+// toHostInterfaces는 inventory.Link를 database.HostInterface로 변환합니다
+func toHostInterfaces(links []inventory.Link) []database.HostInterface {
+	hostInterfaces := make([]database.HostInterface, 0, len(links))
+	for _, link := range links {
+		// 루프백 등 MAC이 없는 링크는 포트와 매칭될 수 없으므로 제외
+		if link.MACAddress == "" || link.MACAddress == "00:00:00:00:00:00" {
+			continue
+		}
+		hostInterfaces = append(hostInterfaces, database.HostInterface{
+			Name:         link.Name,
+			MacAddress:   link.MACAddress,
+			PermanentMAC: link.PermanentMAC,
+			Driver:       link.Driver,
+			PCIAddress:   link.PCIAddress,
+			Kind:         link.Kind,
+			Physical:     link.Physical,
+			OperState:    link.OperState,
+			Carrier:      link.Carrier,
+			Speed:        link.Speed,
+			MTU:          link.MTU,
+			Master:       link.Master,
+			Addresses:    link.Addresses,
+		})
+	}
+
+	return hostInterfaces
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ibyeong-geon/multinic-agent/pkg/database"
+)
+
+const migrateUsage = `usage:
+  migrate status              적용된 스키마 버전과 마이그레이션 목록 출력
+  migrate up [<version>]      지정한 버전(기본: 최신)까지 스키마를 올림
+  migrate down <version>      지정한 버전까지 스키마를 내림 (0이면 모든 테이블 삭제)
+  migrate baseline <version>  실행하지 않고 적용된 것으로 기록 (스크립트로 만든 기존 DB용)`
+
+// runMigrateCommand는 내장 마이그레이션으로 DB 스키마를 조회하거나 변경합니다
+func runMigrateCommand(env *commandEnv, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	action, args := args[0], args[1:]
+
+	var version int
+	switch {
+	case action == "status" && len(args) == 0:
+	case action == "up" && len(args) == 0:
+		version = database.SchemaVersion
+	case (action == "up" || action == "down" || action == "baseline") && len(args) == 1:
+		v, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		version = v
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	// 스키마가 맞지 않아도 연결해야 하므로 버전 확인 없이 연결
+	dbClient, err := database.Connect(&env.cfg.Database, env.logger)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer dbClient.Close()
+
+	switch action {
+	case "status":
+		return printMigrationStatus(dbClient)
+	case "up", "down":
+		current, err := dbClient.CurrentSchemaVersion()
+		if err != nil && !errors.Is(err, database.ErrNoSchemaVersion) {
+			return err
+		}
+		if action == "up" && version < current {
+			return fmt.Errorf("schema is already at version %d; use `migrate down %d` to go back", current, version)
+		}
+		if action == "down" && version > current {
+			return fmt.Errorf("schema is at version %d; use `migrate up %d` to go forward", current, version)
+		}
+		if err := dbClient.MigrateTo(version); err != nil {
+			return err
+		}
+		fmt.Printf("schema version: %d -> %d\n", current, version)
+		return nil
+	default:
+		if err := dbClient.Baseline(version); err != nil {
+			return err
+		}
+		fmt.Printf("schema version: recorded %d without running migrations\n", version)
+		return nil
+	}
+}
+
+// printMigrationStatus는 마이그레이션별 적용 여부를 표로 출력합니다
+// 조회만 하므로 schema_version 테이블이 없는 DB에서도 아무것도 만들지 않습니다
+func printMigrationStatus(dbClient *database.Client) error {
+	current, err := dbClient.CurrentSchemaVersion()
+	switch {
+	case errors.Is(err, database.ErrNoSchemaVersion):
+		fmt.Printf("schema version: none, no schema_version table (required: %d)\n\n", database.SchemaVersion)
+	case err != nil:
+		return err
+	default:
+		fmt.Printf("schema version: %d (required: %d)\n\n", current, database.SchemaVersion)
+	}
+	statuses, err := dbClient.MigrationStatuses()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, status := range statuses {
+		applied := "-"
+		if status.AppliedAt != nil {
+			applied = status.AppliedAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, applied)
+	}
+	return w.Flush()
+}
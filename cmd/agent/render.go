@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ibyeong-geon/multinic-agent/pkg/database"
+	"github.com/ibyeong-geon/multinic-agent/pkg/netplan"
+)
+
+// render는 노드의 netplan YAML을 생성합니다
+// input이 있으면 DB 대신 행 덤프에서 읽고, 기존 netplan 파일도 참고하지 않으므로
+// 같은 입력에 대해 항상 같은 바이트를 출력합니다 (golden 파일 비교용)
+func render(env *commandEnv, input, outDir string) error {
+	if input == "" {
+		nodeName, err := resolveNodeName(env.cfg)
+		if err != nil {
+			return err
+		}
+		_, desired, err := renderDesired(env)
+		if err != nil {
+			return err
+		}
+		return writeRendered(outDir, nodeName, desired)
+	}
+
+	rows, err := database.ReadInterfaceFixture(input)
+	if err != nil {
+		return err
+	}
+	byNode := database.InterfacesByNode(rows)
+	nodes := database.NodeNames(rows)
+
+	switch {
+	case env.node != "":
+		if _, ok := byNode[env.node]; !ok {
+			return fmt.Errorf("node %q not found in %s (nodes: %s)", env.node, input, strings.Join(nodes, ", "))
+		}
+		nodes = []string{env.node}
+	case outDir == "" && len(nodes) > 1:
+		return fmt.Errorf("%s has %d nodes (%s) - select one with -node or use -out-dir", input, len(nodes), strings.Join(nodes, ", "))
+	case len(nodes) == 0:
+		return fmt.Errorf("%s has no interfaces", input)
+	}
+
+	renderer := newOfflineNetplanManager(env.cfg, env.logger)
+	for _, nodeName := range nodes {
+		valid := validateInterfaces(nodeName, byNode[nodeName], env.logger).Valid
+		desired, err := renderer.RenderNetplanConfig(nodeName, netplan.FromNodeInterfaces(valid))
+		if err != nil {
+			return fmt.Errorf("node %s: %w", nodeName, err)
+		}
+		if err := writeRendered(outDir, nodeName, desired); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRendered는 결과를 stdout 또는 outDir/99-multinic-<node>.yaml에 씁니다
+func writeRendered(outDir, nodeName string, data []byte) error {
+	if outDir == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(outDir, fmt.Sprintf("99-multinic-%s.yaml", nodeName))
+	if current, err := os.ReadFile(path); err == nil && slices.Equal(current, data) {
+		fmt.Printf("unchanged %s\n", path)
+		return nil
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s\n", path)
+	return nil
+}
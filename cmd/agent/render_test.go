@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+)
+
+// update는 기대 파일을 현재 렌더링 결과로 다시 씁니다 (go test ./cmd/agent -run TestRenderGolden -update)
+var update = flag.Bool("update", false, "rewrite the golden netplan files")
+
+// testdata/render의 각 디렉터리는 입력 덤프(input.yaml 또는 input.json)와
+// 노드별 기대 파일(99-multinic-<node>.yaml)을 가집니다
+func TestRenderGolden(t *testing.T) {
+	cfg, err := config.Load(filepath.Join("testdata", "render", "config.yaml"))
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+
+	cases, err := filepath.Glob(filepath.Join("testdata", "render", "*", "input.*"))
+	if err != nil || len(cases) == 0 {
+		t.Fatalf("no render fixtures found: %v", err)
+	}
+	for _, input := range cases {
+		dir := filepath.Dir(input)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			outDir := t.TempDir()
+			env := &commandEnv{cfg: cfg, logger: zap.NewNop()}
+			if err := render(env, input, outDir); err != nil {
+				t.Fatalf("render: %v", err)
+			}
+
+			got, _ := filepath.Glob(filepath.Join(outDir, "99-multinic-*.yaml"))
+			want, _ := filepath.Glob(filepath.Join(dir, "99-multinic-*.yaml"))
+			if *update {
+				for _, path := range want {
+					os.Remove(path)
+				}
+				for _, path := range got {
+					data, _ := os.ReadFile(path)
+					if err := os.WriteFile(filepath.Join(dir, filepath.Base(path)), data, 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				return
+			}
+
+			names := func(paths []string) []string {
+				var base []string
+				for _, path := range paths {
+					base = append(base, filepath.Base(path))
+				}
+				return base
+			}
+			if !slices.Equal(names(got), names(want)) {
+				t.Fatalf("rendered %v, want %v", names(got), names(want))
+			}
+			for _, path := range want {
+				expected, _ := os.ReadFile(path)
+				actual, _ := os.ReadFile(filepath.Join(outDir, filepath.Base(path)))
+				if string(actual) != string(expected) {
+					t.Errorf("%s drifted from the golden file:\n%s\nwant\n%s", filepath.Base(path), actual, expected)
+				}
+			}
+		})
+	}
+}
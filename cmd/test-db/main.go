@@ -1,91 +1,257 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/ibyeong-geon/multinic-agent/internal/config"
 	"github.com/ibyeong-geon/multinic-agent/pkg/database"
 	"github.com/ibyeong-geon/multinic-agent/pkg/logger"
+	"github.com/ibyeong-geon/multinic-agent/pkg/netplan"
+	"github.com/ibyeong-geon/multinic-agent/pkg/validation"
 )
 
+// 행 검사(pkg/validation) 외에 이 도구가 보고하는 문제 종류
+const (
+	issueDuplicateMAC  = "duplicate-mac"
+	issueMissingSubnet = "missing-subnet"
+	issueRenderFailed  = "render-failed"
+)
+
+// Issue는 검사에서 발견된 문제 하나입니다
+type Issue struct {
+	Node   string `json:"node"`
+	PortID string `json:"port_id,omitempty"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+// NodeReport는 노드 하나의 조회 및 렌더링 결과입니다
+type NodeReport struct {
+	Name        string  `json:"name"`
+	Interfaces  int     `json:"interfaces"`
+	Applied     int     `json:"applied"`
+	Netplan     string  `json:"netplan,omitempty"`
+	RenderError string  `json:"render_error,omitempty"`
+	Issues      []Issue `json:"issues"`
+}
+
+// Report는 전체 검사 결과입니다
+type Report struct {
+	Nodes  []NodeReport `json:"nodes"`
+	Issues int          `json:"issues"`
+}
+
 func main() {
-	// 설정 로드
-	cfg, err := config.Load("config/config.yaml")
+	os.Exit(run(os.Args[1:]))
+}
+
+// run은 DB의 노드별 인터페이스를 조회하고 netplan을 렌더링해 문제를 보고합니다
+// -mark-success를 지정하지 않으면 DB에 쓰지 않습니다
+// 종료 코드: 0 문제 없음, 1 문제 발견 또는 오류, 2 잘못된 인자
+func run(args []string) int {
+	flags := flag.NewFlagSet("test-db", flag.ContinueOnError)
+	configPath := flags.String("config", "config/config.yaml", "path to the config file")
+	nodeName := flags.String("node", "", "inspect only this node (default: all active nodes)")
+	format := flags.String("format", "table", "output format: table or json")
+	showNetplan := flags.Bool("show-netplan", false, "print each node's rendered netplan YAML (table format)")
+	markSuccess := flags.String("mark-success", "", "set netplan_success=true for this port ID (writes to the DB)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown format %q (table or json)\n", *format)
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		return 1
 	}
 
-	// 로거 초기화
-	zapLogger, err := logger.NewLogger(&cfg.Logging)
+	// 결과 출력과 섞이지 않도록 로그는 stderr로 보냄
+	loggingCfg := cfg.Logging
+	if loggingCfg.Output == "stdout" {
+		loggingCfg.Output = "stderr"
+	}
+	zapLogger, err := logger.NewLogger(&loggingCfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize logger: %v", err)
+		fmt.Fprintf(os.Stderr, "failed to initialize logger: %v\n", err)
+		return 1
 	}
 	defer zapLogger.Sync()
 
-	// DB 연결 테스트
-	fmt.Println("Connecting to database...")
 	dbClient, err := database.NewClient(&cfg.Database, zapLogger)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fmt.Fprintf(os.Stderr, "failed to connect to database: %v\n", err)
+		return 1
 	}
 	defer dbClient.Close()
 
-	fmt.Println("\n✅ Database connected successfully!\n")
+	// 명시적으로 요청한 경우에만 DB를 수정
+	if *markSuccess != "" {
+		if err := dbClient.UpdateNetplanSuccess(*markSuccess, true); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "marked port %s as netplan_success=true\n", *markSuccess)
+	}
 
-	// 테스트용 노드 이름
-	testNodes := []string{"worker-node-1", "worker-node-2", "worker-node-3"}
+	nodes := []string{*nodeName}
+	if *nodeName == "" {
+		if nodes, err = dbClient.ListNodeNames(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+	}
+
+	report, err := inspect(dbClient, netplan.NewRenderer(&cfg.Netplan, zapLogger), nodes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
 
-	for _, nodeName := range testNodes {
-		fmt.Printf("=== 📍 Interfaces for %s ===\n", nodeName)
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = printTable(os.Stdout, report, *showNetplan)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	if report.Issues > 0 {
+		return 1
+	}
+	return 0
+}
 
+// inspect는 노드별로 인터페이스를 조회해 검사하고 netplan을 렌더링합니다
+func inspect(dbClient *database.Client, renderer *netplan.NetplanManager, nodes []string) (*Report, error) {
+	report := &Report{Nodes: make([]NodeReport, 0, len(nodes))}
+	// MAC은 노드 간에도 겹치면 안 되므로 전체에서 추적
+	type macOwner struct{ node, portID string }
+	macOwners := make(map[string]macOwner)
+
+	for _, nodeName := range nodes {
 		interfaces, err := dbClient.GetNodeInterfaces(nodeName)
 		if err != nil {
-			log.Printf("Error getting interfaces for %s: %v", nodeName, err)
-			continue
+			return nil, fmt.Errorf("node %s: %w", nodeName, err)
+		}
+		orphans, err := dbClient.GetOrphanInterfaces(nodeName)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", nodeName, err)
 		}
 
-		if len(interfaces) == 0 {
-			fmt.Printf("❌ No interfaces found for %s\n", nodeName)
-			continue
+		node := NodeReport{Name: nodeName, Interfaces: len(interfaces), Issues: []Issue{}}
+		addIssue := func(portID, kind, format string, args ...any) {
+			node.Issues = append(node.Issues, Issue{
+				Node:   nodeName,
+				PortID: portID,
+				Kind:   kind,
+				Detail: fmt.Sprintf(format, args...),
+			})
+		}
+
+		// 에이전트와 같은 검사로 거부될 행을 보고
+		checked := validation.ValidateInterfaces(interfaces)
+		for _, problem := range checked.Problems {
+			addIssue(problem.PortID, problem.Kind, "%s", problem.Message)
 		}
 
 		for _, iface := range interfaces {
-			netplanIcon := "❌"
 			if iface.NetplanSuccess {
-				netplanIcon = "✅"
+				node.Applied++
 			}
 
-			fmt.Printf("  • %s: %s %s\n",
-				iface.SubnetName,
-				iface.MacAddress,
-				netplanIcon,
-			)
-			fmt.Printf("    ├─ CIDR: %s\n", iface.CIDR)
-			fmt.Printf("    ├─ Port ID: %s\n", iface.PortID)
-			fmt.Printf("    ├─ Network ID: %s\n", iface.NetworkID)
-			fmt.Printf("    ├─ CR: %s/%s\n", iface.CRNamespace, iface.CRName)
-			fmt.Printf("    ├─ Netplan Applied: %t\n", iface.NetplanSuccess)
-			fmt.Printf("    └─ Status: %s\n", iface.Status)
+			// 노드 안의 중복은 위에서 보고하므로 다른 노드와의 중복만 확인
+			// (트렁크 서브포트는 부모 포트의 MAC을 공유할 수 있음)
+			if iface.ParentPortID != "" {
+				continue
+			}
+			mac := strings.ToLower(iface.MacAddress)
+			if owner, ok := macOwners[mac]; ok && owner.node != nodeName {
+				addIssue(iface.PortID, issueDuplicateMAC, "MAC %s is also used by port %s on %s", iface.MacAddress, owner.portID, owner.node)
+			} else if !ok {
+				macOwners[mac] = macOwner{node: nodeName, portID: iface.PortID}
+			}
 		}
-		fmt.Println()
-	}
 
-	// UpdateNetplanSuccess 기능 테스트
-	fmt.Println("=== 🔧 Testing UpdateNetplanSuccess ===")
-	if len(testNodes) > 0 {
-		interfaces, err := dbClient.GetNodeInterfaces(testNodes[0])
-		if err == nil && len(interfaces) > 0 {
-			testInterface := interfaces[0]
-			fmt.Printf("Updating netplan success for port %s...\n", testInterface.PortID)
+		for _, orphan := range orphans {
+			addIssue(orphan.PortID, issueMissingSubnet, "subnet %s does not exist or is not active (MAC %s is not configured)",
+				orphan.SubnetID, orphan.MacAddress)
+		}
 
-			// true로 업데이트
-			err := dbClient.UpdateNetplanSuccess(testInterface.PortID, true)
+		if len(checked.Valid) > 0 {
+			desired, err := renderer.RenderNetplanConfig(nodeName, netplan.FromNodeInterfaces(checked.Valid))
 			if err != nil {
-				log.Printf("Failed to update netplan success: %v", err)
+				node.RenderError = err.Error()
+				addIssue("", issueRenderFailed, "%v", err)
 			} else {
-				fmt.Printf("✅ Successfully updated netplan success to true\n")
+				node.Netplan = string(desired)
+			}
+		}
+
+		report.Issues += len(node.Issues)
+		report.Nodes = append(report.Nodes, node)
+	}
+
+	return report, nil
+}
+
+// printTable은 노드 요약과 문제 목록을 표로 출력합니다
+func printTable(out io.Writer, report *Report, showNetplan bool) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tPORTS\tAPPLIED\tRENDER\tISSUES")
+	for _, node := range report.Nodes {
+		render := "ok"
+		switch {
+		case node.RenderError != "":
+			render = "failed"
+		case node.Netplan == "":
+			render = "-"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%d\n", node.Name, node.Interfaces, node.Applied, render, len(node.Issues))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if report.Issues > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NODE\tPORT\tISSUE\tDETAIL")
+		for _, node := range report.Nodes {
+			for _, issue := range node.Issues {
+				portID := issue.PortID
+				if portID == "" {
+					portID = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issue.Node, portID, issue.Kind, issue.Detail)
 			}
 		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if showNetplan {
+		for _, node := range report.Nodes {
+			if node.Netplan == "" {
+				continue
+			}
+			fmt.Fprintf(out, "\n# %s\n%s", node.Name, node.Netplan)
+		}
 	}
+
+	return nil
 }
@@ -15,6 +15,7 @@ type Config struct {
 	Agent      AgentConfig      `yaml:"agent"`
 	Kubernetes KubernetesConfig `yaml:"kubernetes"`
 	Netplan    NetplanConfig    `yaml:"netplan"`
+	HostExec   HostExecConfig   `yaml:"host_exec"`
 	Logging    LoggingConfig    `yaml:"logging"`
 }
 
@@ -28,6 +29,10 @@ type DatabaseConfig struct {
 	Charset   string `yaml:"charset"`
 	ParseTime bool   `yaml:"parse_time"`
 	Loc       string `yaml:"loc"`
+	// Driver는 데이터베이스 종류입니다 (mysql 또는 postgres)
+	Driver string `yaml:"driver"`
+	// SSLMode는 PostgreSQL 연결의 sslmode입니다 (비어 있으면 드라이버 기본값 require)
+	SSLMode string `yaml:"sslmode"`
 }
 
 // AgentConfig는 에이전트 동작 설정입니다
@@ -36,6 +41,9 @@ type AgentConfig struct {
 	RetryCount    int    `yaml:"retry_count"`
 	RetryInterval int    `yaml:"retry_interval"`
 	NodeName      string `yaml:"node_name"`
+	// NodeIP는 Kubernetes 노드 IP로, 이 주소를 가진 인터페이스는 관리하지 않습니다
+	NodeIP    string `yaml:"node_ip"`
+	SysfsRoot string `yaml:"sysfs_root"`
 }
 
 // KubernetesConfig는 Kubernetes 관련 설정입니다
@@ -47,9 +55,49 @@ type KubernetesConfig struct {
 
 // NetplanConfig는 Netplan 관련 설정입니다
 type NetplanConfig struct {
-	ConfigPath string `yaml:"config_path"`
-	BackupPath string `yaml:"backup_path"`
-	DryRun     bool   `yaml:"dry_run"`
+	ConfigPath     string `yaml:"config_path"`
+	BackupPath     string `yaml:"backup_path"`
+	DryRun         bool   `yaml:"dry_run"`
+	PolicyRouting  bool   `yaml:"policy_routing"`
+	RouteTableBase int    `yaml:"route_table_base"`
+	SRIOVMode      string `yaml:"sriov_mode"`
+	// DB의 MAC이 호스트에 나타날 때까지 기다리는 시간(초) (지정하지 않으면 120, 0이면 기다리지 않음)
+	MACWaitTimeout *int   `yaml:"mac_wait_timeout"`
+	ConflictPolicy string `yaml:"conflict_policy"`
+	// 에이전트가 절대 건드리지 않는 인터페이스 (MAC, 이름 패턴, 주소)
+	ProtectedMACs       []string `yaml:"protected_macs"`
+	ProtectedInterfaces []string `yaml:"protected_interfaces"`
+	ProtectedAddresses  []string `yaml:"protected_addresses"`
+	// 적용 후 연결 확인에 실패하면 이전 설정으로 되돌림 (netplan try와 유사)
+	SafeApply           bool     `yaml:"safe_apply"`
+	SafeApplyTimeout    int      `yaml:"safe_apply_timeout"`
+	ConnectivityTargets []string `yaml:"connectivity_targets"`
+	// 백업 보존 정책 (파일별 개수, 보존 일수, 전체 용량 MB)
+	BackupMaxCount   int `yaml:"backup_max_count"`
+	BackupMaxAgeDays int `yaml:"backup_max_age_days"`
+	BackupMaxSizeMB  int `yaml:"backup_max_size_mb"`
+	// 부팅 시 systemd-networkd-wait-online이 기다리지 않도록 인터페이스를 optional로 표시
+	// 지정하지 않으면(nil) true이며, false로 명시해야 부팅 시 인터페이스를 기다림
+	OptionalInterfaces *bool `yaml:"optional_interfaces"`
+	// DHCP 인터페이스의 dhcp4-overrides 기본값 (지정하지 않으면 netplan 기본값, 서브넷 설정이 우선)
+	DHCP4UseRoutes   *bool `yaml:"dhcp4_use_routes"`
+	DHCP4UseDNS      *bool `yaml:"dhcp4_use_dns"`
+	DHCP4UseHostname *bool `yaml:"dhcp4_use_hostname"`
+	DHCP4RouteMetric int   `yaml:"dhcp4_route_metric"`
+}
+
+// HostExecConfig는 호스트 명령(netplan, ip 등) 실행 방식 설정입니다
+type HostExecConfig struct {
+	// Mode: auto, direct, nsenter, chroot, none
+	Mode string `yaml:"mode"`
+	// Namespaces는 nsenter 모드에서 진입할 네임스페이스입니다 (mount, uts, ipc, net, pid, cgroup)
+	Namespaces []string `yaml:"namespaces"`
+	// TargetPID는 nsenter 모드에서 네임스페이스를 빌려올 프로세스입니다
+	TargetPID int `yaml:"target_pid"`
+	// HostRoot는 chroot 모드에서 사용할 호스트 루트 파일시스템 경로입니다
+	HostRoot string `yaml:"host_root"`
+	// Timeout은 명령별 제한 시간(초)입니다
+	Timeout int `yaml:"timeout"`
 }
 
 // LoggingConfig는 로깅 관련 설정입니다
@@ -114,6 +162,12 @@ func loadFromEnv(config *Config) {
 	if v := os.Getenv("DB_LOC"); v != "" {
 		config.Database.Loc = v
 	}
+	if v := os.Getenv("DB_DRIVER"); v != "" {
+		config.Database.Driver = v
+	}
+	if v := os.Getenv("DB_SSLMODE"); v != "" {
+		config.Database.SSLMode = v
+	}
 
 	// Agent
 	if v := os.Getenv("AGENT_CHECK_INTERVAL"); v != "" {
@@ -134,6 +188,12 @@ func loadFromEnv(config *Config) {
 	if v := os.Getenv("NODE_NAME"); v != "" {
 		config.Agent.NodeName = v
 	}
+	if v := os.Getenv("NODE_IP"); v != "" {
+		config.Agent.NodeIP = v
+	}
+	if v := os.Getenv("AGENT_SYSFS_ROOT"); v != "" {
+		config.Agent.SysfsRoot = v
+	}
 
 	// Kubernetes
 	if v := os.Getenv("KUBECONFIG"); v != "" {
@@ -153,9 +213,102 @@ func loadFromEnv(config *Config) {
 	if v := os.Getenv("NETPLAN_BACKUP_PATH"); v != "" {
 		config.Netplan.BackupPath = v
 	}
+	if v := os.Getenv("NETPLAN_BACKUP_MAX_COUNT"); v != "" {
+		if count, err := strconv.Atoi(v); err == nil {
+			config.Netplan.BackupMaxCount = count
+		}
+	}
+	if v := os.Getenv("NETPLAN_BACKUP_MAX_AGE_DAYS"); v != "" {
+		if days, err := strconv.Atoi(v); err == nil {
+			config.Netplan.BackupMaxAgeDays = days
+		}
+	}
+	if v := os.Getenv("NETPLAN_BACKUP_MAX_SIZE_MB"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			config.Netplan.BackupMaxSizeMB = size
+		}
+	}
 	if v := os.Getenv("NETPLAN_DRY_RUN"); v != "" {
 		config.Netplan.DryRun = strings.ToLower(v) == "true"
 	}
+	if v := os.Getenv("NETPLAN_POLICY_ROUTING"); v != "" {
+		config.Netplan.PolicyRouting = strings.ToLower(v) == "true"
+	}
+	if v := os.Getenv("NETPLAN_ROUTE_TABLE_BASE"); v != "" {
+		if base, err := strconv.Atoi(v); err == nil {
+			config.Netplan.RouteTableBase = base
+		}
+	}
+	if v := os.Getenv("NETPLAN_SRIOV_MODE"); v != "" {
+		config.Netplan.SRIOVMode = v
+	}
+	if v := os.Getenv("NETPLAN_MAC_WAIT_TIMEOUT"); v != "" {
+		if timeout, err := strconv.Atoi(v); err == nil {
+			config.Netplan.MACWaitTimeout = &timeout
+		}
+	}
+
+	if v := os.Getenv("NETPLAN_CONFLICT_POLICY"); v != "" {
+		config.Netplan.ConflictPolicy = v
+	}
+	if v := os.Getenv("NETPLAN_PROTECTED_MACS"); v != "" {
+		config.Netplan.ProtectedMACs = splitList(v)
+	}
+	if v := os.Getenv("NETPLAN_PROTECTED_INTERFACES"); v != "" {
+		config.Netplan.ProtectedInterfaces = splitList(v)
+	}
+	if v := os.Getenv("NETPLAN_PROTECTED_ADDRESSES"); v != "" {
+		config.Netplan.ProtectedAddresses = splitList(v)
+	}
+	if v := os.Getenv("NETPLAN_SAFE_APPLY"); v != "" {
+		config.Netplan.SafeApply = strings.ToLower(v) == "true"
+	}
+	if v := os.Getenv("NETPLAN_SAFE_APPLY_TIMEOUT"); v != "" {
+		if timeout, err := strconv.Atoi(v); err == nil {
+			config.Netplan.SafeApplyTimeout = timeout
+		}
+	}
+	if v := os.Getenv("NETPLAN_CONNECTIVITY_TARGETS"); v != "" {
+		config.Netplan.ConnectivityTargets = splitList(v)
+	}
+	if v := os.Getenv("NETPLAN_OPTIONAL_INTERFACES"); v != "" {
+		config.Netplan.OptionalInterfaces = parseBool(v)
+	}
+	if v := os.Getenv("NETPLAN_DHCP4_USE_ROUTES"); v != "" {
+		config.Netplan.DHCP4UseRoutes = parseBool(v)
+	}
+	if v := os.Getenv("NETPLAN_DHCP4_USE_DNS"); v != "" {
+		config.Netplan.DHCP4UseDNS = parseBool(v)
+	}
+	if v := os.Getenv("NETPLAN_DHCP4_USE_HOSTNAME"); v != "" {
+		config.Netplan.DHCP4UseHostname = parseBool(v)
+	}
+	if v := os.Getenv("NETPLAN_DHCP4_ROUTE_METRIC"); v != "" {
+		if metric, err := strconv.Atoi(v); err == nil {
+			config.Netplan.DHCP4RouteMetric = metric
+		}
+	}
+
+	// Host exec
+	if v := os.Getenv("HOST_EXEC_MODE"); v != "" {
+		config.HostExec.Mode = v
+	}
+	if v := os.Getenv("HOST_EXEC_NAMESPACES"); v != "" {
+		config.HostExec.Namespaces = splitList(v)
+	}
+	if v := os.Getenv("HOST_EXEC_TARGET_PID"); v != "" {
+		if pid, err := strconv.Atoi(v); err == nil {
+			config.HostExec.TargetPID = pid
+		}
+	}
+	if v := os.Getenv("HOST_EXEC_ROOT"); v != "" {
+		config.HostExec.HostRoot = v
+	}
+	if v := os.Getenv("HOST_EXEC_TIMEOUT"); v != "" {
+		if timeout, err := strconv.Atoi(v); err == nil {
+			config.HostExec.Timeout = timeout
+		}
+	}
 
 	// Logging
 	if v := os.Getenv("LOG_LEVEL"); v != "" {
@@ -175,8 +328,14 @@ func loadFromEnv(config *Config) {
 // setDefaults는 기본값을 설정합니다
 func setDefaults(config *Config) {
 	// Database defaults
+	if config.Database.Driver == "" {
+		config.Database.Driver = "mysql"
+	}
 	if config.Database.Port == 0 {
 		config.Database.Port = 3306
+		if config.Database.Driver == "postgres" {
+			config.Database.Port = 5432
+		}
 	}
 	if config.Database.Charset == "" {
 		config.Database.Charset = "utf8mb4"
@@ -198,6 +357,9 @@ func setDefaults(config *Config) {
 	if config.Agent.RetryInterval == 0 {
 		config.Agent.RetryInterval = 5
 	}
+	if config.Agent.SysfsRoot == "" {
+		config.Agent.SysfsRoot = "/sys"
+	}
 
 	// Kubernetes defaults
 	if config.Kubernetes.LabelPrefix == "" {
@@ -214,6 +376,51 @@ func setDefaults(config *Config) {
 	if config.Netplan.BackupPath == "" {
 		config.Netplan.BackupPath = "/var/backups/netplan"
 	}
+	if config.Netplan.BackupMaxCount == 0 {
+		config.Netplan.BackupMaxCount = 20
+	}
+	if config.Netplan.BackupMaxAgeDays == 0 {
+		config.Netplan.BackupMaxAgeDays = 30
+	}
+	if config.Netplan.BackupMaxSizeMB == 0 {
+		config.Netplan.BackupMaxSizeMB = 10
+	}
+	if config.Netplan.RouteTableBase == 0 {
+		config.Netplan.RouteTableBase = 100
+	}
+	if config.Netplan.SRIOVMode == "" {
+		config.Netplan.SRIOVMode = "sysfs"
+	}
+	if config.Netplan.MACWaitTimeout == nil {
+		timeout := 120
+		config.Netplan.MACWaitTimeout = &timeout
+	}
+	if config.Netplan.SafeApplyTimeout == 0 {
+		config.Netplan.SafeApplyTimeout = 120
+	}
+	if config.Netplan.ConflictPolicy == "" {
+		config.Netplan.ConflictPolicy = "refuse"
+	}
+	if config.Netplan.OptionalInterfaces == nil {
+		config.Netplan.OptionalInterfaces = parseBool("true")
+	}
+
+	// Host exec defaults
+	if config.HostExec.Mode == "" {
+		config.HostExec.Mode = "auto"
+	}
+	if len(config.HostExec.Namespaces) == 0 {
+		config.HostExec.Namespaces = []string{"mount", "uts", "ipc", "net"}
+	}
+	if config.HostExec.TargetPID == 0 {
+		config.HostExec.TargetPID = 1
+	}
+	if config.HostExec.HostRoot == "" {
+		config.HostExec.HostRoot = "/host"
+	}
+	if config.HostExec.Timeout == 0 {
+		config.HostExec.Timeout = 60
+	}
 
 	// Logging defaults
 	if config.Logging.Level == "" {
@@ -226,3 +433,20 @@ func setDefaults(config *Config) {
 		config.Logging.Output = "stdout"
 	}
 }
+
+// splitList는 쉼표로 구분된 환경변수 값을 목록으로 변환합니다
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseBool은 환경변수 값을 bool 포인터로 변환합니다
+func parseBool(v string) *bool {
+	b := strings.ToLower(v) == "true"
+	return &b
+}
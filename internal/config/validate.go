@@ -0,0 +1,135 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Validate는 설정 값의 범위와 형식을 검사하고 발견된 모든 문제를 함께 반환합니다
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// Database
+	if c.Database.Host == "" {
+		add("database.host is required")
+	}
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		add("database.port %d is out of range", c.Database.Port)
+	}
+	if c.Database.Username == "" {
+		add("database.username is required")
+	}
+	if c.Database.Database == "" {
+		add("database.database is required")
+	}
+	if !slices.Contains([]string{"mysql", "postgres"}, c.Database.Driver) {
+		add("database.driver %q must be mysql or postgres", c.Database.Driver)
+	}
+	if c.Database.SSLMode != "" && !slices.Contains([]string{"disable", "require", "verify-ca", "verify-full"}, c.Database.SSLMode) {
+		add("database.sslmode %q must be disable, require, verify-ca or verify-full", c.Database.SSLMode)
+	}
+
+	// Agent
+	if c.Agent.CheckInterval <= 0 {
+		add("agent.check_interval must be positive, got %d", c.Agent.CheckInterval)
+	}
+	if c.Agent.NodeIP != "" && net.ParseIP(c.Agent.NodeIP) == nil {
+		add("agent.node_ip %q is not an IP address", c.Agent.NodeIP)
+	}
+
+	// Netplan
+	if c.Netplan.ConfigPath == "" {
+		add("netplan.config_path is required")
+	}
+	if c.Netplan.BackupPath == "" {
+		add("netplan.backup_path is required")
+	}
+	if c.Netplan.RouteTableBase <= 0 {
+		add("netplan.route_table_base must be positive, got %d", c.Netplan.RouteTableBase)
+	}
+	if !slices.Contains([]string{"sysfs", "netplan"}, c.Netplan.SRIOVMode) {
+		add("netplan.sriov_mode %q must be sysfs or netplan", c.Netplan.SRIOVMode)
+	}
+	if c.Netplan.MACWaitTimeout != nil && *c.Netplan.MACWaitTimeout < 0 {
+		add("netplan.mac_wait_timeout must not be negative, got %d", *c.Netplan.MACWaitTimeout)
+	}
+	if !slices.Contains([]string{"refuse", "adopt"}, c.Netplan.ConflictPolicy) {
+		add("netplan.conflict_policy %q must be refuse or adopt", c.Netplan.ConflictPolicy)
+	}
+	for _, mac := range c.Netplan.ProtectedMACs {
+		if _, err := net.ParseMAC(mac); err != nil {
+			add("netplan.protected_macs: %q is not a MAC address", mac)
+		}
+	}
+	for _, pattern := range c.Netplan.ProtectedInterfaces {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			add("netplan.protected_interfaces: invalid pattern %q", pattern)
+		}
+	}
+	for _, address := range c.Netplan.ProtectedAddresses {
+		if net.ParseIP(address) == nil {
+			if _, _, err := net.ParseCIDR(address); err != nil {
+				add("netplan.protected_addresses: %q is neither an IP address nor a CIDR", address)
+			}
+		}
+	}
+	if c.Netplan.SafeApplyTimeout <= 0 {
+		add("netplan.safe_apply_timeout must be positive, got %d", c.Netplan.SafeApplyTimeout)
+	}
+	for _, target := range c.Netplan.ConnectivityTargets {
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			add("netplan.connectivity_targets: %q is not host:port", target)
+		}
+	}
+	if c.Netplan.BackupMaxCount < 0 || c.Netplan.BackupMaxAgeDays < 0 || c.Netplan.BackupMaxSizeMB < 0 {
+		add("netplan backup retention limits must not be negative")
+	}
+	if c.Netplan.DHCP4RouteMetric < 0 {
+		add("netplan.dhcp4_route_metric must not be negative, got %d", c.Netplan.DHCP4RouteMetric)
+	}
+
+	// Host exec
+	if !slices.Contains([]string{"auto", "direct", "nsenter", "chroot", "none"}, c.HostExec.Mode) {
+		add("host_exec.mode %q must be auto, direct, nsenter, chroot or none", c.HostExec.Mode)
+	}
+	for _, ns := range c.HostExec.Namespaces {
+		if !slices.Contains([]string{"mount", "uts", "ipc", "net", "pid", "cgroup", "user"}, ns) {
+			add("host_exec.namespaces: unknown namespace %q", ns)
+		}
+	}
+	if c.HostExec.TargetPID <= 0 {
+		add("host_exec.target_pid must be positive, got %d", c.HostExec.TargetPID)
+	}
+	if c.HostExec.Timeout <= 0 {
+		add("host_exec.timeout must be positive, got %d", c.HostExec.Timeout)
+	}
+
+	// Logging
+	if !slices.Contains([]string{"debug", "info", "warn", "error"}, c.Logging.Level) {
+		add("logging.level %q must be debug, info, warn or error", c.Logging.Level)
+	}
+	if !slices.Contains([]string{"json", "text", "console"}, c.Logging.Format) {
+		add("logging.format %q must be json, text or console", c.Logging.Format)
+	}
+	if c.Logging.Output == "file" && c.Logging.FilePath == "" {
+		add("logging.file_path is required when logging.output is file")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted는 비밀번호를 가린 설정 사본을 반환합니다 (출력용)
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	if redacted.Database.Password != "" {
+		redacted.Database.Password = strings.Repeat("*", 8)
+	}
+	return &redacted
+}
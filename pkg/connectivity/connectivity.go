@@ -0,0 +1,93 @@
+package connectivity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// probeTimeout bounds a single attempt of one probe
+	probeTimeout = 5 * time.Second
+	// retryInterval is the pause between rounds of failed probes
+	retryInterval = 2 * time.Second
+)
+
+// Probe checks that one target is reachable
+type Probe func(ctx context.Context) error
+
+type check struct {
+	name  string
+	probe Probe
+}
+
+// Checker confirms that the node can still reach the targets it depends on
+// (database, API server, configured endpoints) after a network change
+type Checker struct {
+	checks []check
+	logger *zap.Logger
+}
+
+// NewChecker creates a Checker without any probes
+func NewChecker(logger *zap.Logger) *Checker {
+	return &Checker{logger: logger}
+}
+
+// Add registers a named probe
+func (c *Checker) Add(name string, probe Probe) {
+	c.checks = append(c.checks, check{name: name, probe: probe})
+}
+
+// AddTCP registers a probe that opens a TCP connection to addr (host:port)
+func (c *Checker) AddTCP(addr string) {
+	c.Add("tcp "+addr, func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+}
+
+// Len returns the number of registered probes
+func (c *Checker) Len() int {
+	return len(c.checks)
+}
+
+// Check runs every probe once and returns the failures
+func (c *Checker) Check(ctx context.Context) error {
+	var errs []error
+	for _, ch := range c.checks {
+		probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+		err := ch.probe(probeCtx)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ch.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WaitReachable retries the probes until all of them pass in the same round
+// or ctx expires, in which case the last failures are returned
+func (c *Checker) WaitReachable(ctx context.Context) error {
+	for {
+		err := c.Check(ctx)
+		if err == nil {
+			return nil
+		}
+
+		c.logger.Warn("Connectivity check failed - retrying", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("connectivity not confirmed: %w", err)
+		case <-time.After(retryInterval):
+		}
+	}
+}
@@ -0,0 +1,768 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+)
+
+// Client는 데이터베이스 클라이언트입니다 (MySQL/MariaDB 또는 PostgreSQL)
+type Client struct {
+	db      *sql.DB
+	dialect dialect
+	logger  *zap.Logger
+}
+
+// NodeInterface는 노드의 네트워크 인터페이스 정보입니다 (조인된 결과)
+type NodeInterface struct {
+	InterfaceID    int       `db:"interface_id" json:"interface_id" yaml:"interface_id"`
+	PortID         string    `db:"port_id" json:"port_id" yaml:"port_id"`
+	NodeID         string    `db:"node_id" json:"node_id" yaml:"node_id"`
+	NodeName       string    `db:"node_name" json:"node_name" yaml:"node_name"`
+	MacAddress     string    `db:"macaddress" json:"macaddress" yaml:"macaddress"`
+	SubnetID       string    `db:"subnet_id" json:"subnet_id" yaml:"subnet_id"`
+	SubnetName     string    `db:"subnet_name" json:"subnet_name" yaml:"subnet_name"`
+	CIDR           string    `db:"cidr" json:"cidr" yaml:"cidr"`
+	NetworkID      string    `db:"network_id" json:"network_id" yaml:"network_id"`
+	CRNamespace    string    `db:"cr_namespace" json:"cr_namespace" yaml:"cr_namespace"`
+	CRName         string    `db:"cr_name" json:"cr_name" yaml:"cr_name"`
+	NetplanSuccess bool      `db:"netplan_success" json:"netplan_success" yaml:"netplan_success"`
+	NetplanReason  string    `db:"netplan_reason" json:"netplan_reason" yaml:"netplan_reason"`
+	Status         string    `db:"status" json:"status" yaml:"status"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at" yaml:"created_at"`
+	ModifiedAt     time.Time `db:"modified_at" json:"modified_at" yaml:"modified_at"`
+	MTU            int       `db:"mtu" json:"mtu" yaml:"mtu"`
+	ParentPortID   string    `db:"parent_port_id" json:"parent_port_id" yaml:"parent_port_id"`
+	VLANID         int       `db:"vlan_id" json:"vlan_id" yaml:"vlan_id"`
+	// SR-IOV 포트의 원하는 상태 (vnic_type이 direct인 경우)
+	VNICType        string          `db:"vnic_type" json:"vnic_type" yaml:"vnic_type"`
+	PCIAddress      string          `db:"pci_address" json:"pci_address" yaml:"pci_address"`
+	PFPCIAddress    string          `db:"pf_pci_address" json:"pf_pci_address" yaml:"pf_pci_address"`
+	SRIOVVFCount    int             `db:"sriov_vf_count" json:"sriov_vf_count" yaml:"sriov_vf_count"`
+	SRIOVSwitchMode string          `db:"sriov_switch_mode" json:"sriov_switch_mode" yaml:"sriov_switch_mode"`
+	FixedIPs        []FixedIP       `json:"fixed_ips" yaml:"fixed_ips"`
+	Group           *InterfaceGroup `json:"group,omitempty" yaml:"group,omitempty"`
+	// 서브넷별 netplan 옵션 (nil이면 에이전트 설정을 따름)
+	Optional         *bool `db:"optional" json:"optional,omitempty" yaml:"optional,omitempty"`
+	DHCP4UseRoutes   *bool `db:"dhcp4_use_routes" json:"dhcp4_use_routes,omitempty" yaml:"dhcp4_use_routes,omitempty"`
+	DHCP4UseDNS      *bool `db:"dhcp4_use_dns" json:"dhcp4_use_dns,omitempty" yaml:"dhcp4_use_dns,omitempty"`
+	DHCP4UseHostname *bool `db:"dhcp4_use_hostname" json:"dhcp4_use_hostname,omitempty" yaml:"dhcp4_use_hostname,omitempty"`
+	DHCP4RouteMetric *int  `db:"dhcp4_route_metric" json:"dhcp4_route_metric,omitempty" yaml:"dhcp4_route_metric,omitempty"`
+	// LinkLocal은 쉼표로 구분한 주소 체계 목록입니다 (빈 문자열이면 비활성화, nil이면 netplan 기본값)
+	LinkLocal *string `db:"link_local" json:"link_local,omitempty" yaml:"link_local,omitempty"`
+}
+
+// InterfaceGroup는 여러 포트를 묶는 본드/브리지 정보입니다
+type InterfaceGroup struct {
+	GroupID            string `db:"group_id" json:"group_id" yaml:"group_id"`
+	GroupName          string `db:"group_name" json:"group_name" yaml:"group_name"`
+	GroupType          string `db:"group_type" json:"group_type" yaml:"group_type"`
+	BondMode           string `db:"bond_mode" json:"bond_mode" yaml:"bond_mode"`
+	LACPRate           string `db:"lacp_rate" json:"lacp_rate" yaml:"lacp_rate"`
+	MIIMonitorInterval int    `db:"mii_monitor_interval" json:"mii_monitor_interval" yaml:"mii_monitor_interval"`
+	PrimaryPortID      string `db:"primary_port_id" json:"primary_port_id" yaml:"primary_port_id"`
+	STP                *bool  `db:"stp" json:"stp,omitempty" yaml:"stp,omitempty"`
+	MTU                int    `db:"mtu" json:"mtu" yaml:"mtu"`
+}
+
+// FixedIP는 포트에 할당된 고정 IP 정보입니다 (포트당 여러 개 가능)
+type FixedIP struct {
+	PortID     string `db:"port_id" json:"port_id" yaml:"port_id"`
+	IPAddress  string `db:"ip_address" json:"ip_address" yaml:"ip_address"`
+	SubnetID   string `db:"subnet_id" json:"subnet_id" yaml:"subnet_id"`
+	SubnetName string `db:"subnet_name" json:"subnet_name" yaml:"subnet_name"`
+	CIDR       string `db:"cidr" json:"cidr" yaml:"cidr"`
+	GatewayIP  string `db:"gateway_ip" json:"gateway_ip" yaml:"gateway_ip"`
+	// PolicyRouting이 nil이면 에이전트 설정을 따릅니다
+	PolicyRouting *bool `db:"policy_routing" json:"policy_routing,omitempty" yaml:"policy_routing,omitempty"`
+	RouteTable    int   `db:"route_table" json:"route_table" yaml:"route_table"`
+}
+
+// NewClient는 새로운 데이터베이스 클라이언트를 생성합니다
+// DB 스키마 버전이 SchemaVersion과 다르면 연결을 닫고 오류를 반환합니다
+func NewClient(cfg *config.DatabaseConfig, logger *zap.Logger) (*Client, error) {
+	client, err := Connect(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// 쿼리가 기대하는 컬럼과 다른 스키마에서는 실행하지 않음
+	if err := client.CheckSchemaVersion(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// Connect는 스키마 버전을 확인하지 않고 데이터베이스에 연결합니다 (migrate 명령용)
+func Connect(cfg *config.DatabaseConfig, logger *zap.Logger) (*Client, error) {
+	d, err := dialectFor(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	// 데이터베이스 연결
+	db, err := sql.Open(d.driverName(), d.dsn(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// 연결 테스트
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	// 연결 풀 설정
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	logger.Info("Database connected successfully",
+		zap.String("driver", d.name()),
+		zap.String("host", cfg.Host),
+		zap.Int("port", cfg.Port),
+		zap.String("database", cfg.Database),
+	)
+
+	return &Client{
+		db:      db,
+		dialect: d,
+		logger:  logger,
+	}, nil
+}
+
+// exec, query, queryRow는 쿼리의 ? 파라미터를 드라이버 표기로 바꿔 실행합니다
+func (c *Client) exec(query string, args ...any) (sql.Result, error) {
+	return c.db.Exec(c.dialect.rebind(query), args...)
+}
+
+func (c *Client) query(query string, args ...any) (*sql.Rows, error) {
+	return c.db.Query(c.dialect.rebind(query), args...)
+}
+
+func (c *Client) queryRow(query string, args ...any) *sql.Row {
+	return c.db.QueryRow(c.dialect.rebind(query), args...)
+}
+
+// Close는 데이터베이스 연결을 닫습니다
+func (c *Client) Close() error {
+	return c.db.Close()
+}
+
+// Ping은 데이터베이스 연결 가능 여부를 확인합니다
+func (c *Client) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
+// GetNodeInterfaces는 특정 노드의 네트워크 인터페이스 정보를 조회합니다
+func (c *Client) GetNodeInterfaces(nodeName string) ([]NodeInterface, error) {
+	query := `
+		SELECT 
+			mi.id as interface_id,
+			mi.port_id,
+			n.attached_node_id as node_id,
+			n.attached_node_name as node_name,
+			mi.macaddress,
+			ms.subnet_id,
+			ms.subnet_name,
+			ms.cidr,
+			ms.network_id,
+			mi.cr_namespace,
+			mi.cr_name,
+			mi.netplan_success,
+			mi.netplan_reason,
+			mi.status,
+			mi.created_at,
+			mi.modified_at,
+			ms.mtu,
+			mi.parent_port_id,
+			mi.vlan_id,
+			mi.vnic_type,
+			mi.pci_address,
+			mi.pf_pci_address,
+			mi.sriov_vf_count,
+			mi.sriov_switch_mode,
+			g.group_id,
+			g.group_name,
+			g.group_type,
+			g.bond_mode,
+			g.lacp_rate,
+			g.mii_monitor_interval,
+			g.primary_port_id,
+			g.stp,
+			g.mtu,
+			ms.optional,
+			ms.dhcp4_use_routes,
+			ms.dhcp4_use_dns,
+			ms.dhcp4_use_hostname,
+			ms.dhcp4_route_metric,
+			ms.link_local
+		FROM multi_interface mi
+		JOIN node_table n ON mi.attached_node_id = n.attached_node_id
+		JOIN multi_subnet ms ON mi.subnet_id = ms.subnet_id
+		LEFT JOIN multi_interface_group g ON mi.group_id = g.group_id AND g.status = 'active'
+		WHERE n.attached_node_name = ? 
+		  AND mi.status = 'active'
+		  AND n.status = 'active'
+		  AND ms.status = 'active'
+		ORDER BY ms.subnet_name, mi.port_id
+	`
+
+	rows, err := c.query(query, nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query interfaces: %w", err)
+	}
+	defer rows.Close()
+
+	var interfaces []NodeInterface
+	for rows.Next() {
+		var iface NodeInterface
+		var mtu, vlanID sql.NullInt64
+		var parentPortID, reason sql.NullString
+		var vnicType, pciAddress, pfPCIAddress, switchMode sql.NullString
+		var vfCount sql.NullInt64
+		var group nullableGroup
+		var optional, useRoutes, useDNS, useHostname sql.NullBool
+		var routeMetric sql.NullInt64
+		var linkLocal sql.NullString
+		err := rows.Scan(
+			&iface.InterfaceID,
+			&iface.PortID,
+			&iface.NodeID,
+			&iface.NodeName,
+			&iface.MacAddress,
+			&iface.SubnetID,
+			&iface.SubnetName,
+			&iface.CIDR,
+			&iface.NetworkID,
+			&iface.CRNamespace,
+			&iface.CRName,
+			&iface.NetplanSuccess,
+			&reason,
+			&iface.Status,
+			&iface.CreatedAt,
+			&iface.ModifiedAt,
+			&mtu,
+			&parentPortID,
+			&vlanID,
+			&vnicType,
+			&pciAddress,
+			&pfPCIAddress,
+			&vfCount,
+			&switchMode,
+			&group.GroupID,
+			&group.GroupName,
+			&group.GroupType,
+			&group.BondMode,
+			&group.LACPRate,
+			&group.MIIMonitorInterval,
+			&group.PrimaryPortID,
+			&group.STP,
+			&group.MTU,
+			&optional,
+			&useRoutes,
+			&useDNS,
+			&useHostname,
+			&routeMetric,
+			&linkLocal,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		iface.NetplanReason = reason.String
+		iface.MTU = int(mtu.Int64)
+		iface.ParentPortID = parentPortID.String
+		iface.VLANID = int(vlanID.Int64)
+		iface.VNICType = vnicType.String
+		iface.PCIAddress = pciAddress.String
+		iface.PFPCIAddress = pfPCIAddress.String
+		iface.SRIOVVFCount = int(vfCount.Int64)
+		iface.SRIOVSwitchMode = switchMode.String
+		iface.Group = group.toGroup()
+		iface.Optional = nullBoolPtr(optional)
+		iface.DHCP4UseRoutes = nullBoolPtr(useRoutes)
+		iface.DHCP4UseDNS = nullBoolPtr(useDNS)
+		iface.DHCP4UseHostname = nullBoolPtr(useHostname)
+		if routeMetric.Valid {
+			metric := int(routeMetric.Int64)
+			iface.DHCP4RouteMetric = &metric
+		}
+		if linkLocal.Valid {
+			iface.LinkLocal = &linkLocal.String
+		}
+		interfaces = append(interfaces, iface)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	// 포트별 고정 IP 조회 후 인터페이스에 연결
+	fixedIPs, err := c.getNodeFixedIPs(nodeName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range interfaces {
+		interfaces[i].FixedIPs = fixedIPs[interfaces[i].PortID]
+	}
+
+	c.logger.Debug("Retrieved node interfaces",
+		zap.String("node_name", nodeName),
+		zap.Int("count", len(interfaces)),
+	)
+
+	return interfaces, nil
+}
+
+// nullableGroup은 LEFT JOIN된 그룹 컬럼의 스캔 대상입니다
+type nullableGroup struct {
+	GroupID            sql.NullString
+	GroupName          sql.NullString
+	GroupType          sql.NullString
+	BondMode           sql.NullString
+	LACPRate           sql.NullString
+	MIIMonitorInterval sql.NullInt64
+	PrimaryPortID      sql.NullString
+	STP                sql.NullBool
+	MTU                sql.NullInt64
+}
+
+// toGroup은 그룹에 속하지 않은 경우 nil을 반환합니다
+func (g nullableGroup) toGroup() *InterfaceGroup {
+	if !g.GroupID.Valid {
+		return nil
+	}
+
+	group := &InterfaceGroup{
+		GroupID:            g.GroupID.String,
+		GroupName:          g.GroupName.String,
+		GroupType:          g.GroupType.String,
+		BondMode:           g.BondMode.String,
+		LACPRate:           g.LACPRate.String,
+		MIIMonitorInterval: int(g.MIIMonitorInterval.Int64),
+		PrimaryPortID:      g.PrimaryPortID.String,
+		MTU:                int(g.MTU.Int64),
+	}
+	if g.STP.Valid {
+		stp := g.STP.Bool
+		group.STP = &stp
+	}
+
+	return group
+}
+
+// getNodeFixedIPs는 특정 노드의 포트별 고정 IP 목록을 조회합니다
+func (c *Client) getNodeFixedIPs(nodeName string) (map[string][]FixedIP, error) {
+	query := `
+		SELECT 
+			ip.port_id,
+			ip.ip_address,
+			ms.subnet_id,
+			ms.subnet_name,
+			ms.cidr,
+			ms.gateway_ip,
+			ms.policy_routing,
+			ms.route_table
+		FROM multi_interface_ip ip
+		JOIN multi_interface mi ON ip.port_id = mi.port_id
+		JOIN node_table n ON mi.attached_node_id = n.attached_node_id
+		JOIN multi_subnet ms ON ip.subnet_id = ms.subnet_id
+		WHERE n.attached_node_name = ? 
+		  AND ip.status = 'active'
+		  AND mi.status = 'active'
+		  AND ms.status = 'active'
+		ORDER BY ip.port_id, ms.subnet_name, ip.ip_address
+	`
+
+	rows, err := c.query(query, nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fixed ips: %w", err)
+	}
+	defer rows.Close()
+
+	fixedIPs := make(map[string][]FixedIP)
+	for rows.Next() {
+		var ip FixedIP
+		var gateway sql.NullString
+		var policyRouting sql.NullBool
+		var routeTable sql.NullInt64
+		err := rows.Scan(
+			&ip.PortID,
+			&ip.IPAddress,
+			&ip.SubnetID,
+			&ip.SubnetName,
+			&ip.CIDR,
+			&gateway,
+			&policyRouting,
+			&routeTable,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fixed ip row: %w", err)
+		}
+		ip.GatewayIP = gateway.String
+		if policyRouting.Valid {
+			enabled := policyRouting.Bool
+			ip.PolicyRouting = &enabled
+		}
+		ip.RouteTable = int(routeTable.Int64)
+		fixedIPs[ip.PortID] = append(fixedIPs[ip.PortID], ip)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fixed ip row iteration error: %w", err)
+	}
+
+	return fixedIPs, nil
+}
+
+// ListNodeNames는 node_table의 활성 노드 이름을 정렬해 반환합니다
+func (c *Client) ListNodeNames() ([]string, error) {
+	query := `
+		SELECT attached_node_name
+		FROM node_table
+		WHERE status = 'active'
+		ORDER BY attached_node_name
+	`
+
+	rows, err := c.query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan node row: %w", err)
+		}
+		names = append(names, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("node row iteration error: %w", err)
+	}
+
+	return names, nil
+}
+
+// OrphanInterface는 활성 서브넷을 찾을 수 없는 인터페이스입니다
+// (GetNodeInterfaces의 조인에서 빠지므로 netplan에 나타나지 않음)
+type OrphanInterface struct {
+	PortID     string `db:"port_id" json:"port_id" yaml:"port_id"`
+	NodeName   string `db:"node_name" json:"node_name" yaml:"node_name"`
+	MacAddress string `db:"macaddress" json:"macaddress" yaml:"macaddress"`
+	SubnetID   string `db:"subnet_id" json:"subnet_id" yaml:"subnet_id"`
+}
+
+// GetOrphanInterfaces는 서브넷이 없거나 비활성인 노드의 활성 인터페이스를 조회합니다
+func (c *Client) GetOrphanInterfaces(nodeName string) ([]OrphanInterface, error) {
+	query := `
+		SELECT
+			mi.port_id,
+			n.attached_node_name,
+			mi.macaddress,
+			mi.subnet_id
+		FROM multi_interface mi
+		JOIN node_table n ON mi.attached_node_id = n.attached_node_id
+		LEFT JOIN multi_subnet ms ON mi.subnet_id = ms.subnet_id AND ms.status = 'active'
+		WHERE n.attached_node_name = ?
+		  AND mi.status = 'active'
+		  AND ms.subnet_id IS NULL
+		ORDER BY mi.port_id
+	`
+
+	rows, err := c.query(query, nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orphan interfaces: %w", err)
+	}
+	defer rows.Close()
+
+	var orphans []OrphanInterface
+	for rows.Next() {
+		var orphan OrphanInterface
+		if err := rows.Scan(&orphan.PortID, &orphan.NodeName, &orphan.MacAddress, &orphan.SubnetID); err != nil {
+			return nil, fmt.Errorf("failed to scan orphan interface row: %w", err)
+		}
+		orphans = append(orphans, orphan)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("orphan interface row iteration error: %w", err)
+	}
+
+	return orphans, nil
+}
+
+// UpdateNetplanSuccess는 특정 인터페이스의 netplan 적용 성공 여부를 업데이트합니다
+func (c *Client) UpdateNetplanSuccess(portID string, success bool) error {
+	query := `
+		UPDATE multi_interface 
+		SET netplan_success = ?, modified_at = CURRENT_TIMESTAMP
+		WHERE port_id = ?
+	`
+
+	_, err := c.exec(query, success, portID)
+	if err != nil {
+		return fmt.Errorf("failed to update netplan success: %w", err)
+	}
+
+	c.logger.Debug("Updated netplan success status",
+		zap.String("port_id", portID),
+		zap.Bool("success", success),
+	)
+
+	return nil
+}
+
+// maxNetplanReasonLength는 netplan_reason 컬럼 길이입니다
+const maxNetplanReasonLength = 255
+
+// UpdateNetplanStatus는 netplan 적용 성공 여부와 실패 사유를 업데이트합니다
+// 성공한 경우 reason은 비워서 NULL로 저장됩니다
+func (c *Client) UpdateNetplanStatus(portID string, success bool, reason string) error {
+	reason = TruncateReason(reason)
+
+	query := `
+		UPDATE multi_interface 
+		SET netplan_success = ?, netplan_reason = ?, modified_at = CURRENT_TIMESTAMP
+		WHERE port_id = ?
+	`
+
+	_, err := c.exec(query, success, nullString(reason), portID)
+	if err != nil {
+		return fmt.Errorf("failed to update netplan status: %w", err)
+	}
+
+	c.logger.Debug("Updated netplan status",
+		zap.String("port_id", portID),
+		zap.Bool("success", success),
+		zap.String("reason", reason),
+	)
+
+	return nil
+}
+
+// TruncateReason은 사유를 netplan_reason 컬럼에 저장되는 길이로 자릅니다
+func TruncateReason(reason string) string {
+	if runes := []rune(reason); len(runes) > maxNetplanReasonLength {
+		return string(runes[:maxNetplanReasonLength])
+	}
+	return reason
+}
+
+// applyLogRetention은 노드별로 보관하는 netplan_apply_log 행 수입니다
+const applyLogRetention = 100
+
+// ApplyLog는 한 번의 netplan 적용 결과입니다
+type ApplyLog struct {
+	Method string `db:"method"`
+	// Success는 적용 후 호스트 상태로 확인까지 된 경우에만 true입니다
+	Success   bool   `db:"success"`
+	Confirmed bool   `db:"confirmed"`
+	Reverted  bool   `db:"reverted"`
+	ExitCode  *int   `db:"exit_code"`
+	Duration  int64  `db:"duration_ms"`
+	Error     string `db:"error"`
+	Stdout    string `db:"stdout"`
+	Stderr    string `db:"stderr"`
+	// Attempts는 실행한 모든 명령의 JSON 목록입니다
+	Attempts  string    `db:"attempts"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// RecordApplyResult는 netplan 적용 결과를 netplan_apply_log 테이블에 기록하고
+// 노드별로 최근 applyLogRetention개만 남깁니다
+func (c *Client) RecordApplyResult(nodeName string, log ApplyLog) error {
+	query := `
+		INSERT INTO netplan_apply_log (
+			attached_node_name, method, success, confirmed, reverted, exit_code,
+			duration_ms, error, stdout, stderr, attempts, applied_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`
+
+	var exitCode sql.NullInt64
+	if log.ExitCode != nil {
+		exitCode = sql.NullInt64{Int64: int64(*log.ExitCode), Valid: true}
+	}
+
+	_, err := c.exec(query,
+		nodeName,
+		log.Method,
+		log.Success,
+		log.Confirmed,
+		log.Reverted,
+		exitCode,
+		log.Duration,
+		nullString(log.Error),
+		nullString(log.Stdout),
+		nullString(log.Stderr),
+		nullString(log.Attempts),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record apply result: %w", err)
+	}
+
+	// 오래된 기록 정리 (MySQL은 같은 테이블 서브쿼리를 직접 쓸 수 없어 파생 테이블 사용, PostgreSQL도 동일하게 동작)
+	cleanup := `
+		DELETE FROM netplan_apply_log
+		WHERE attached_node_name = ?
+		  AND id <= (
+			SELECT id FROM (
+				SELECT id FROM netplan_apply_log
+				WHERE attached_node_name = ?
+				ORDER BY id DESC
+				LIMIT 1 OFFSET ?
+			) oldest
+		  )
+	`
+	if _, err := c.exec(cleanup, nodeName, nodeName, applyLogRetention); err != nil {
+		c.logger.Warn("Failed to prune netplan apply log", zap.Error(err))
+	}
+
+	c.logger.Debug("Recorded netplan apply result",
+		zap.String("node_name", nodeName),
+		zap.String("method", log.Method),
+		zap.Bool("success", log.Success),
+	)
+
+	return nil
+}
+
+// GetLatestApplyLog는 노드의 가장 최근 netplan 적용 기록을 조회합니다 (없으면 nil)
+func (c *Client) GetLatestApplyLog(nodeName string) (*ApplyLog, error) {
+	query := `
+		SELECT method, success, confirmed, reverted, exit_code, duration_ms,
+		       error, stdout, stderr, attempts, applied_at
+		FROM netplan_apply_log
+		WHERE attached_node_name = ?
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	var log ApplyLog
+	var exitCode sql.NullInt64
+	var errorText, stdout, stderr, attempts sql.NullString
+	err := c.queryRow(query, nodeName).Scan(
+		&log.Method,
+		&log.Success,
+		&log.Confirmed,
+		&log.Reverted,
+		&exitCode,
+		&log.Duration,
+		&errorText,
+		&stdout,
+		&stderr,
+		&attempts,
+		&log.AppliedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query apply log: %w", err)
+	}
+
+	if exitCode.Valid {
+		code := int(exitCode.Int64)
+		log.ExitCode = &code
+	}
+	log.Error = errorText.String
+	log.Stdout = stdout.String
+	log.Stderr = stderr.String
+	log.Attempts = attempts.String
+
+	return &log, nil
+}
+
+// HostInterface는 노드에서 실제로 발견된 네트워크 링크 정보입니다
+type HostInterface struct {
+	Name         string `db:"interface_name"`
+	MacAddress   string `db:"macaddress"`
+	PermanentMAC string `db:"permanent_macaddress"`
+	Driver       string `db:"driver"`
+	PCIAddress   string `db:"pci_address"`
+	Kind         string `db:"kind"`
+	Physical     bool   `db:"physical"`
+	OperState    string `db:"oper_state"`
+	Carrier      bool   `db:"carrier"`
+	Speed        int    `db:"speed"`
+	MTU          int    `db:"mtu"`
+	Master       string `db:"master"`
+	Addresses    []string
+}
+
+// ReportHostInterfaces는 노드의 호스트 인터페이스 목록을 node_host_interface 테이블에 기록합니다
+// 기존 행을 지우고 새로 삽입하므로 사라진 링크는 테이블에서도 제거됩니다
+func (c *Client) ReportHostInterfaces(nodeName string, interfaces []HostInterface) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(c.dialect.rebind(`DELETE FROM node_host_interface WHERE attached_node_name = ?`), nodeName); err != nil {
+		return fmt.Errorf("failed to delete host interfaces: %w", err)
+	}
+
+	query := `
+		INSERT INTO node_host_interface (
+			attached_node_name, interface_name, macaddress, permanent_macaddress,
+			driver, pci_address, kind, physical, oper_state, carrier,
+			speed, mtu, master, addresses, reported_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`
+	for _, iface := range interfaces {
+		_, err := tx.Exec(c.dialect.rebind(query),
+			nodeName,
+			iface.Name,
+			iface.MacAddress,
+			nullString(iface.PermanentMAC),
+			nullString(iface.Driver),
+			nullString(iface.PCIAddress),
+			nullString(iface.Kind),
+			iface.Physical,
+			iface.OperState,
+			iface.Carrier,
+			iface.Speed,
+			iface.MTU,
+			nullString(iface.Master),
+			strings.Join(iface.Addresses, ","),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert host interface %s: %w", iface.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit host interfaces: %w", err)
+	}
+
+	c.logger.Debug("Reported host interfaces",
+		zap.String("node_name", nodeName),
+		zap.Int("count", len(interfaces)),
+	)
+
+	return nil
+}
+
+// nullString은 빈 문자열을 NULL로 저장합니다
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullBoolPtr는 NULL이면 nil을 반환합니다
+func nullBoolPtr(b sql.NullBool) *bool {
+	if !b.Valid {
+		return nil
+	}
+	value := b.Bool
+	return &value
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+)
+
+// dialect는 데이터베이스 종류별로 다른 부분(연결 문자열, 파라미터 표기, 스키마 조회)을 담당합니다
+// 쿼리는 ? 파라미터와 양쪽에서 동작하는 SQL(CURRENT_TIMESTAMP 등)로 작성하고 rebind로 변환합니다
+type dialect interface {
+	// name은 config의 driver 값이자 마이그레이션 디렉터리 이름입니다
+	name() string
+	driverName() string
+	dsn(cfg *config.DatabaseConfig) string
+	// rebind는 ? 파라미터를 드라이버 표기로 바꿉니다
+	rebind(query string) string
+	// currentSchema는 information_schema에서 현재 스키마를 가리키는 식입니다
+	currentSchema() string
+	// transactionalDDL은 DDL을 트랜잭션으로 묶어 실패 시 되돌릴 수 있는지 여부입니다
+	transactionalDDL() bool
+}
+
+// dialectFor는 설정의 driver에 맞는 dialect를 반환합니다 (비어 있으면 mysql)
+func dialectFor(driver string) (dialect, error) {
+	switch driver {
+	case "", "mysql":
+		return mysqlDialect{}, nil
+	case "postgres":
+		return postgresDialect{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q (mysql or postgres)", driver)
+	}
+}
+
+// mysqlDialect는 MySQL/MariaDB용입니다
+type mysqlDialect struct{}
+
+func (mysqlDialect) name() string       { return "mysql" }
+func (mysqlDialect) driverName() string { return "mysql" }
+
+func (mysqlDialect) dsn(cfg *config.DatabaseConfig) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=%t&loc=%s",
+		cfg.Username,
+		cfg.Password,
+		cfg.Host,
+		cfg.Port,
+		cfg.Database,
+		cfg.Charset,
+		cfg.ParseTime,
+		cfg.Loc,
+	)
+}
+
+func (mysqlDialect) rebind(query string) string { return query }
+func (mysqlDialect) currentSchema() string      { return "DATABASE()" }
+func (mysqlDialect) transactionalDDL() bool     { return false }
+
+// postgresDialect는 PostgreSQL용입니다
+type postgresDialect struct{}
+
+func (postgresDialect) name() string       { return "postgres" }
+func (postgresDialect) driverName() string { return "postgres" }
+
+func (postgresDialect) dsn(cfg *config.DatabaseConfig) string {
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(cfg.Username, cfg.Password),
+		Host:   net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:   "/" + cfg.Database,
+	}
+	if cfg.SSLMode != "" {
+		dsn.RawQuery = url.Values{"sslmode": {cfg.SSLMode}}.Encode()
+	}
+	return dsn.String()
+}
+
+// rebind는 ?를 차례로 $1, $2, ...로 바꿉니다 (쿼리의 문자열 상수에는 ?를 쓰지 않음)
+func (postgresDialect) rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (postgresDialect) currentSchema() string  { return "current_schema()" }
+func (postgresDialect) transactionalDDL() bool { return true }
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReadInterfaceFixture는 NodeInterface 행 목록을 JSON 또는 YAML 파일에서 읽습니다
+// (GetNodeInterfaces 결과를 덤프한 파일로, DB 없이 렌더링할 때 사용)
+func ReadInterfaceFixture(path string) ([]NodeInterface, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML은 JSON의 상위 집합이므로 두 형식 모두 같은 디코더로 읽음
+	var interfaces []NodeInterface
+	if err := yaml.Unmarshal(data, &interfaces); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+
+	return interfaces, nil
+}
+
+// InterfacesByNode는 행을 노드 이름별로 묶습니다 (각 노드 안의 순서는 유지)
+func InterfacesByNode(interfaces []NodeInterface) map[string][]NodeInterface {
+	byNode := make(map[string][]NodeInterface)
+	for _, iface := range interfaces {
+		byNode[iface.NodeName] = append(byNode[iface.NodeName], iface)
+	}
+	return byNode
+}
+
+// NodeNames는 행에 나타난 노드 이름을 정렬해 반환합니다
+func NodeNames(interfaces []NodeInterface) []string {
+	byNode := InterfacesByNode(interfaces)
+	names := make([]string, 0, len(byNode))
+	for name := range byNode {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
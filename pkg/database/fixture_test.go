@@ -0,0 +1,98 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadInterfaceFixture(t *testing.T) {
+	metric := 0
+	linkLocal := ""
+	want := []NodeInterface{
+		{
+			PortID:           "port-1",
+			NodeName:         "node-a",
+			MacAddress:       "fa:16:3e:00:00:01",
+			SubnetName:       "data",
+			CIDR:             "10.0.0.0/24",
+			ModifiedAt:       time.Date(2026, 1, 1, 9, 30, 0, 0, time.UTC),
+			DHCP4RouteMetric: &metric,
+			LinkLocal:        &linkLocal,
+			Group:            &InterfaceGroup{GroupID: "g-1", GroupName: "bond0", GroupType: "bond"},
+			FixedIPs: []FixedIP{
+				{PortID: "port-1", IPAddress: "10.0.0.5", CIDR: "10.0.0.0/24", RouteTable: 120},
+			},
+		},
+		{PortID: "port-2", NodeName: "node-b", MacAddress: "fa:16:3e:00:00:02"},
+	}
+
+	fixtures := map[string]string{
+		"rows.json": `[
+  {
+    "port_id": "port-1",
+    "node_name": "node-a",
+    "macaddress": "fa:16:3e:00:00:01",
+    "subnet_name": "data",
+    "cidr": "10.0.0.0/24",
+    "modified_at": "2026-01-01T09:30:00Z",
+    "dhcp4_route_metric": 0,
+    "link_local": "",
+    "group": {"group_id": "g-1", "group_name": "bond0", "group_type": "bond"},
+    "fixed_ips": [{"port_id": "port-1", "ip_address": "10.0.0.5", "cidr": "10.0.0.0/24", "route_table": 120}]
+  },
+  {"port_id": "port-2", "node_name": "node-b", "macaddress": "fa:16:3e:00:00:02"}
+]`,
+		"rows.yaml": `- port_id: port-1
+  node_name: node-a
+  macaddress: fa:16:3e:00:00:01
+  subnet_name: data
+  cidr: 10.0.0.0/24
+  modified_at: 2026-01-01T09:30:00Z
+  dhcp4_route_metric: 0
+  link_local: ""
+  group:
+    group_id: g-1
+    group_name: bond0
+    group_type: bond
+  fixed_ips:
+    - port_id: port-1
+      ip_address: 10.0.0.5
+      cidr: 10.0.0.0/24
+      route_table: 120
+- port_id: port-2
+  node_name: node-b
+  macaddress: fa:16:3e:00:00:02
+`,
+	}
+	for name, content := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadInterfaceFixture(path)
+			if err != nil {
+				t.Fatalf("ReadInterfaceFixture: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ReadInterfaceFixture =\n%+v\nwant\n%+v", got, want)
+			}
+			if nodes := NodeNames(got); !reflect.DeepEqual(nodes, []string{"node-a", "node-b"}) {
+				t.Errorf("NodeNames = %v", nodes)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rows.yaml")
+		if err := os.WriteFile(path, []byte("port_id: [unterminated"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadInterfaceFixture(path); err == nil {
+			t.Error("ReadInterfaceFixture accepted a malformed file")
+		}
+	})
+}
@@ -0,0 +1,437 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 스키마 마이그레이션은 바이너리에 포함되며, 적용된 버전은 schema_version 테이블에 기록합니다
+// DB 종류별 디렉터리(migrations/mysql, migrations/postgres)는 같은 버전과 이름을 가져야 합니다
+//
+//go:embed migrations/mysql/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+// migrationFileName은 0001_initial_schema.up.sql 형식의 파일 이름입니다
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration은 스키마 변경 한 단계입니다
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus는 마이그레이션과 적용 시각입니다 (적용되지 않았으면 AppliedAt이 nil)
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// migrationSets는 DB 종류별로 버전 순으로 정렬된 내장 마이그레이션입니다
+var migrationSets = mustLoadMigrations(mysqlDialect{}.name(), postgresDialect{}.name())
+
+// SchemaVersion은 이 바이너리의 쿼리가 요구하는 스키마 버전(가장 최신 마이그레이션)입니다
+var SchemaVersion = latestVersion(migrationSets[mysqlDialect{}.name()])
+
+func latestVersion(migrations []Migration) int {
+	return migrations[len(migrations)-1].Version
+}
+
+// mustLoadMigrations는 내장 파일을 읽어 DB 종류별 마이그레이션을 만듭니다
+// 파일이 잘못되었거나 종류별 버전이 다르면 빌드 결과물의 문제이므로 패닉합니다
+func mustLoadMigrations(dialects ...string) map[string][]Migration {
+	sets := make(map[string][]Migration)
+	for _, name := range dialects {
+		loaded, err := loadMigrations(migrationFiles, path.Join("migrations", name))
+		if err != nil {
+			panic(fmt.Sprintf("invalid embedded %s migrations: %v", name, err))
+		}
+		sets[name] = loaded
+	}
+
+	first := sets[dialects[0]]
+	for _, name := range dialects[1:] {
+		if !sameVersions(first, sets[name]) {
+			panic(fmt.Sprintf("embedded %s and %s migrations have different versions", dialects[0], name))
+		}
+	}
+	return sets
+}
+
+// sameVersions는 두 마이그레이션 목록의 버전과 이름이 같은지 확인합니다
+func sameVersions(a, b []Migration) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Version != b[i].Version || a[i].Name != b[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected file name %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("version %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	loaded := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both up and down files", m.Version, m.Name)
+		}
+		loaded = append(loaded, *m)
+	}
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].Version < loaded[j].Version })
+	for i, m := range loaded {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must start at 1 without gaps, found %d at position %d", m.Version, i+1)
+		}
+	}
+	if len(loaded) == 0 {
+		return nil, errors.New("no migrations")
+	}
+	return loaded, nil
+}
+
+// ErrNoSchemaVersion은 schema_version 테이블이 없을 때 반환됩니다
+var ErrNoSchemaVersion = errors.New("database has no schema_version table")
+
+// schemaVersionTable은 적용된 마이그레이션을 버전별로 한 행씩 기록합니다
+const schemaVersionTable = `
+	CREATE TABLE IF NOT EXISTS schema_version (
+		version INT NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+
+// CurrentSchemaVersion은 DB에 적용된 가장 높은 마이그레이션 버전을 반환합니다
+// schema_version 테이블이 없으면 ErrNoSchemaVersion을 반환합니다
+func (c *Client) CurrentSchemaVersion() (int, error) {
+	exists, err := c.hasSchemaVersionTable()
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, ErrNoSchemaVersion
+	}
+
+	var version sql.NullInt64
+	if err := c.queryRow("SELECT MAX(version) FROM schema_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// hasSchemaVersionTable은 schema_version 테이블이 있는지 확인합니다 (읽기만 함)
+func (c *Client) hasSchemaVersionTable() (bool, error) {
+	var exists int
+	err := c.queryRow(`
+		SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema = ` + c.dialect.currentSchema() + ` AND table_name = 'schema_version'
+	`).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up schema_version table: %w", err)
+	}
+	return exists > 0, nil
+}
+
+// CheckSchemaVersion은 DB 스키마가 이 바이너리가 요구하는 버전과 같은지 확인합니다
+func (c *Client) CheckSchemaVersion() error {
+	version, err := c.CurrentSchemaVersion()
+	if errors.Is(err, ErrNoSchemaVersion) {
+		return fmt.Errorf("%w: run `multinic-agent migrate up` on an empty database, "+
+			"or `multinic-agent migrate baseline %d` if the schema was created by an older scripts/create_test_db.sql", err, SchemaVersion)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case version < SchemaVersion:
+		return fmt.Errorf("database schema version %d is older than the required version %d: run `multinic-agent migrate up`",
+			version, SchemaVersion)
+	case version > SchemaVersion:
+		return fmt.Errorf("database schema version %d is newer than this agent supports (%d): upgrade the agent",
+			version, SchemaVersion)
+	}
+	return nil
+}
+
+// MigrationStatuses는 내장 마이그레이션별 적용 여부를 반환합니다
+// DB를 변경하지 않으며, schema_version 테이블이 없으면 모두 적용되지 않은 것으로 봅니다
+func (c *Client) MigrationStatuses() ([]MigrationStatus, error) {
+	migrations := migrationSets[c.dialect.name()]
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	exists, err := c.hasSchemaVersionTable()
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		for _, m := range migrations {
+			statuses = append(statuses, MigrationStatus{Migration: m})
+		}
+		return statuses, nil
+	}
+
+	rows, err := c.query("SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_version: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_version: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range migrations {
+		status := MigrationStatus{Migration: m}
+		if appliedAt, ok := applied[m.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// MigrateTo는 스키마를 target 버전으로 올리거나 내립니다 (0이면 모든 테이블 삭제)
+// PostgreSQL은 마이그레이션마다 트랜잭션으로 실행해 실패하면 되돌립니다
+// MySQL의 DDL은 트랜잭션으로 묶이지 않으므로, 마이그레이션 하나가 중간에 실패하면
+// 해당 버전은 기록되지 않고 오류에 실패한 구문이 포함됩니다
+func (c *Client) MigrateTo(target int) error {
+	if target < 0 || target > SchemaVersion {
+		return fmt.Errorf("target version %d is out of range 0-%d", target, SchemaVersion)
+	}
+	if _, err := c.exec(schemaVersionTable); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+	current, err := c.CurrentSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > SchemaVersion {
+		return fmt.Errorf("database schema version %d is newer than this agent knows (%d)", current, SchemaVersion)
+	}
+
+	// 올릴 때는 낮은 버전부터, 내릴 때는 높은 버전부터 실행
+	migrations := migrationSets[c.dialect.name()]
+	for _, m := range migrations {
+		if m.Version <= current || m.Version > target {
+			continue
+		}
+		if err := c.runMigration(m, "up"); err != nil {
+			return err
+		}
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version > current || m.Version <= target {
+			continue
+		}
+		if err := c.runMigration(m, "down"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Baseline은 마이그레이션을 실행하지 않고 version까지 적용된 것으로 기록합니다
+// 마이그레이션 도입 전에 스크립트로 만든 DB에서 한 번만 사용합니다
+func (c *Client) Baseline(version int) error {
+	if version < 1 || version > SchemaVersion {
+		return fmt.Errorf("baseline version %d is out of range 1-%d", version, SchemaVersion)
+	}
+	if _, err := c.exec(schemaVersionTable); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+	current, err := c.CurrentSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current != 0 {
+		return fmt.Errorf("database already has schema version %d", current)
+	}
+
+	for _, m := range migrationSets[c.dialect.name()][:version] {
+		if _, err := c.exec(recordVersionQuery, m.Version, m.Name); err != nil {
+			return fmt.Errorf("failed to record schema version %d: %w", m.Version, err)
+		}
+	}
+	c.logger.Info("Recorded schema baseline", zap.Int("version", version))
+	return nil
+}
+
+const (
+	recordVersionQuery = "INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, CURRENT_TIMESTAMP)"
+	removeVersionQuery = "DELETE FROM schema_version WHERE version = ?"
+)
+
+// execer는 *sql.DB와 *sql.Tx의 공통 실행 메서드입니다
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// runMigration은 마이그레이션 파일의 구문을 차례로 실행하고 schema_version을 갱신합니다
+func (c *Client) runMigration(m Migration, direction string) error {
+	start := time.Now()
+	script, record, args := m.Up, recordVersionQuery, []any{m.Version, m.Name}
+	if direction == "down" {
+		script, record, args = m.Down, removeVersionQuery, []any{m.Version}
+	}
+
+	var tx *sql.Tx
+	var target execer = c.db
+	if c.dialect.transactionalDDL() {
+		var err error
+		if tx, err = c.db.Begin(); err != nil {
+			return fmt.Errorf("failed to begin migration transaction: %w", err)
+		}
+		defer tx.Rollback()
+		target = tx
+	}
+
+	for _, stmt := range splitStatements(script) {
+		if _, err := target.Exec(stmt); err != nil {
+			return fmt.Errorf("migration %04d_%s (%s) failed: %w\n%s", m.Version, m.Name, direction, err, stmt)
+		}
+	}
+	if _, err := target.Exec(c.dialect.rebind(record), args...); err != nil {
+		return fmt.Errorf("failed to update schema_version for %04d_%s: %w", m.Version, m.Name, err)
+	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+
+	c.logger.Info("Applied schema migration",
+		zap.Int("version", m.Version),
+		zap.String("name", m.Name),
+		zap.String("direction", direction),
+		zap.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// splitStatements는 SQL 스크립트를 세미콜론 단위의 구문으로 나눕니다
+// 따옴표('...', "...", `...`)와 PostgreSQL 달러 인용($$...$$, $tag$...$tag$) 안의 세미콜론은 구문을 끝내지 않으며,
+// "--" 줄 주석과 "/* */" 블록 주석은 제거합니다
+// 따옴표 안에서는 따옴표를 두 번 겹쳐 쓰는 이스케이프만 지원하며, 백슬래시 이스케이프는 지원하지 않습니다
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" {
+			statements = append(statements, stmt)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(script); {
+		rest := script[i:]
+		switch {
+		case strings.HasPrefix(rest, "--"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			i += end
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += 2 + end + 2
+			}
+			current.WriteByte(' ')
+		case rest[0] == '\'' || rest[0] == '"' || rest[0] == '`':
+			n := quotedLength(rest)
+			current.WriteString(rest[:n])
+			i += n
+		case rest[0] == '$' && dollarTag.MatchString(rest):
+			tag := dollarTag.FindString(rest)
+			n := len(rest)
+			if end := strings.Index(rest[len(tag):], tag); end >= 0 {
+				n = len(tag) + end + len(tag)
+			}
+			current.WriteString(rest[:n])
+			i += n
+		case rest[0] == ';':
+			flush()
+			i++
+		default:
+			current.WriteByte(rest[0])
+			i++
+		}
+	}
+	flush()
+	return statements
+}
+
+// dollarTag는 PostgreSQL 달러 인용의 여는 태그입니다 ($$ 또는 $tag$)
+var dollarTag = regexp.MustCompile(`^\$[A-Za-z_][A-Za-z0-9_]*\$|^\$\$`)
+
+// quotedLength는 s의 첫 글자로 시작하는 따옴표 문자열의 길이를 반환합니다
+// 닫는 따옴표가 없으면 s 전체의 길이입니다
+func quotedLength(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		if s[i] != quote {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(s)
+}
@@ -0,0 +1,239 @@
+package database
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var (
+	createdTable  = regexp.MustCompile(`^CREATE TABLE (?:IF NOT EXISTS )?(\w+)`)
+	droppedTable  = regexp.MustCompile(`^DROP TABLE (?:IF EXISTS )?(\w+)`)
+	statementVerb = regexp.MustCompile(`^(CREATE|DROP|ALTER|INSERT|UPDATE|DELETE|COMMENT)\s`)
+)
+
+// 내장 마이그레이션을 DB 종류별로 모두 읽고, 각 파일이 구문 단위로 나뉘는지,
+// down이 up에서 만든 테이블을 모두 삭제하는지 확인합니다
+func TestEmbeddedMigrations(t *testing.T) {
+	for _, d := range []dialect{mysqlDialect{}, postgresDialect{}} {
+		t.Run(d.name(), func(t *testing.T) {
+			migrations, err := loadMigrations(migrationFiles, "migrations/"+d.name())
+			if err != nil {
+				t.Fatalf("loadMigrations: %v", err)
+			}
+			if latestVersion(migrations) != SchemaVersion {
+				t.Errorf("latest version = %d, want SchemaVersion %d", latestVersion(migrations), SchemaVersion)
+			}
+
+			for _, m := range migrations {
+				up, down := splitStatements(m.Up), splitStatements(m.Down)
+				if len(up) == 0 || len(down) == 0 {
+					t.Fatalf("%04d_%s: %d up and %d down statements", m.Version, m.Name, len(up), len(down))
+				}
+
+				var created, dropped []string
+				for _, stmt := range append(slices.Clone(up), down...) {
+					if !statementVerb.MatchString(stmt) {
+						t.Errorf("%04d_%s: statement does not start with a known verb:\n%s", m.Version, m.Name, stmt)
+					}
+					if strings.Contains(stmt, "--") || strings.HasSuffix(stmt, ";") {
+						t.Errorf("%04d_%s: statement keeps a comment or terminator:\n%s", m.Version, m.Name, stmt)
+					}
+					if match := createdTable.FindStringSubmatch(stmt); match != nil {
+						created = append(created, match[1])
+					}
+					if match := droppedTable.FindStringSubmatch(stmt); match != nil {
+						dropped = append(dropped, match[1])
+					}
+				}
+				slices.Sort(created)
+				slices.Sort(dropped)
+				if !slices.Equal(created, dropped) {
+					t.Errorf("%04d_%s: up creates %v but down drops %v", m.Version, m.Name, created, dropped)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "line comments and blank lines",
+			script: "-- 첫 번째\nCREATE TABLE a (id INT);\n\n-- 두 번째\nDROP TABLE b;\n",
+			want:   []string{"CREATE TABLE a (id INT)", "DROP TABLE b"},
+		},
+		{
+			name: "comment string ending in a semicolon",
+			script: "CREATE TABLE a (\n" +
+				"    id INT COMMENT 'primary key;',\n" +
+				"    name VARCHAR(10) COMMENT 'name; -- not a comment'\n" +
+				");\n",
+			want: []string{"CREATE TABLE a (\n    id INT COMMENT 'primary key;',\n    name VARCHAR(10) COMMENT 'name; -- not a comment'\n)"},
+		},
+		{
+			name:   "doubled quotes",
+			script: "INSERT INTO a VALUES ('it''s; fine');INSERT INTO a VALUES ('');",
+			want:   []string{"INSERT INTO a VALUES ('it''s; fine')", "INSERT INTO a VALUES ('')"},
+		},
+		{
+			name:   "quoted identifiers",
+			script: "CREATE TABLE `a;b` (id INT); CREATE TABLE \"c;d\" (id INT);",
+			want:   []string{"CREATE TABLE `a;b` (id INT)", "CREATE TABLE \"c;d\" (id INT)"},
+		},
+		{
+			name:   "several statements on one line",
+			script: "DROP TABLE a; DROP TABLE b;",
+			want:   []string{"DROP TABLE a", "DROP TABLE b"},
+		},
+		{
+			name:   "block comment",
+			script: "/* 시작; */ CREATE TABLE a (id INT /* ; */);",
+			want:   []string{"CREATE TABLE a (id INT  )"},
+		},
+		{
+			name:   "trailing comment after the statement",
+			script: "CREATE TABLE a (id INT); -- 끝;\n",
+			want:   []string{"CREATE TABLE a (id INT)"},
+		},
+		{
+			name: "dollar quoted function body",
+			script: "CREATE FUNCTION touch() RETURNS trigger AS $body$\nBEGIN\n    NEW.modified_at = now();\n    RETURN NEW;\nEND;\n$body$ LANGUAGE plpgsql;\n" +
+				"COMMENT ON TABLE a IS $$x;$$;",
+			want: []string{
+				"CREATE FUNCTION touch() RETURNS trigger AS $body$\nBEGIN\n    NEW.modified_at = now();\n    RETURN NEW;\nEND;\n$body$ LANGUAGE plpgsql",
+				"COMMENT ON TABLE a IS $$x;$$",
+			},
+		},
+		{
+			name:   "last statement without a semicolon",
+			script: "DROP TABLE a;\nDROP TABLE b\n",
+			want:   []string{"DROP TABLE a", "DROP TABLE b"},
+		},
+		{name: "only comments", script: "-- 없음\n/* 없음 */\n;\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.script); !slices.Equal(got, tt.want) {
+				t.Errorf("splitStatements =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(sql string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(sql)} }
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{
+			name: "valid",
+			files: fstest.MapFS{
+				"m/0001_initial.up.sql":   file("CREATE TABLE a (id INT);"),
+				"m/0001_initial.down.sql": file("DROP TABLE a;"),
+				"m/0002_add_b.up.sql":     file("CREATE TABLE b (id INT);"),
+				"m/0002_add_b.down.sql":   file("DROP TABLE b;"),
+			},
+		},
+		{
+			name:    "unexpected file name",
+			files:   fstest.MapFS{"m/initial.sql": file("")},
+			wantErr: "unexpected file name",
+		},
+		{
+			name:    "missing down",
+			files:   fstest.MapFS{"m/0001_initial.up.sql": file("CREATE TABLE a (id INT);")},
+			wantErr: "needs both up and down files",
+		},
+		{
+			name: "two names",
+			files: fstest.MapFS{
+				"m/0001_initial.up.sql": file("CREATE TABLE a (id INT);"),
+				"m/0001_other.down.sql": file("DROP TABLE a;"),
+			},
+			wantErr: "has two names",
+		},
+		{
+			name: "gap",
+			files: fstest.MapFS{
+				"m/0002_add_b.up.sql":   file("CREATE TABLE b (id INT);"),
+				"m/0002_add_b.down.sql": file("DROP TABLE b;"),
+			},
+			wantErr: "without gaps",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(tt.files, "m")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("loadMigrations: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("loadMigrations error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// migrate status는 조회만 하므로 schema_version 테이블이 없어도 만들지 않아야 합니다
+// (sqlmock은 기대하지 않은 CREATE TABLE을 오류로 처리합니다)
+func TestMigrationStatusesReadOnly(t *testing.T) {
+	appliedAt := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	for _, d := range []dialect{mysqlDialect{}, postgresDialect{}} {
+		lookup := "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = " + d.currentSchema() +
+			" AND table_name = 'schema_version'"
+
+		t.Run(d.name()+" without schema_version", func(t *testing.T) {
+			client, mock := newMockClient(t, d)
+			mock.ExpectQuery(lookup).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+			statuses, err := client.MigrationStatuses()
+			if err != nil {
+				t.Fatalf("MigrationStatuses: %v", err)
+			}
+			if len(statuses) != SchemaVersion {
+				t.Fatalf("got %d statuses, want %d", len(statuses), SchemaVersion)
+			}
+			for _, status := range statuses {
+				if status.AppliedAt != nil {
+					t.Errorf("%04d_%s applied at %v, want not applied", status.Version, status.Name, status.AppliedAt)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+
+		t.Run(d.name()+" with schema_version", func(t *testing.T) {
+			client, mock := newMockClient(t, d)
+			mock.ExpectQuery(lookup).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			mock.ExpectQuery("SELECT version, applied_at FROM schema_version").
+				WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, appliedAt))
+
+			statuses, err := client.MigrationStatuses()
+			if err != nil {
+				t.Fatalf("MigrationStatuses: %v", err)
+			}
+			if statuses[0].AppliedAt == nil || !statuses[0].AppliedAt.Equal(appliedAt) {
+				t.Errorf("version 1 applied at %v, want %v", statuses[0].AppliedAt, appliedAt)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
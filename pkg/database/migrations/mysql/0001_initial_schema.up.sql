@@ -0,0 +1,153 @@
+-- 초기 스키마: 서브넷, 노드, 인터페이스(그룹, 고정 IP), 호스트 인터페이스 보고, netplan 적용 기록, CR 상태
+
+-- 서브넷 테이블 생성
+CREATE TABLE multi_subnet (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    subnet_id VARCHAR(36) NOT NULL UNIQUE,
+    subnet_name VARCHAR(255) NOT NULL,
+    cidr VARCHAR(255) NOT NULL,
+    network_id VARCHAR(36) NOT NULL COMMENT 'OpenStack network ID',
+    gateway_ip VARCHAR(45) NULL COMMENT 'Subnet gateway IP',
+    mtu INT NULL COMMENT 'Interface MTU (NULL: agent default)',
+    policy_routing TINYINT(1) NULL COMMENT 'Per-interface policy routing (NULL: agent setting)',
+    route_table INT NULL COMMENT 'Routing table ID for policy routing (NULL: allocated by agent)',
+    optional TINYINT(1) NULL COMMENT 'Do not wait for the interface at boot (NULL: agent setting)',
+    dhcp4_use_routes TINYINT(1) NULL COMMENT 'DHCP: install routes from the lease (NULL: agent setting)',
+    dhcp4_use_dns TINYINT(1) NULL COMMENT 'DHCP: use DNS servers from the lease (NULL: agent setting)',
+    dhcp4_use_hostname TINYINT(1) NULL COMMENT 'DHCP: use the hostname from the lease (NULL: agent setting)',
+    dhcp4_route_metric INT NULL COMMENT 'DHCP: metric of routes from the lease (NULL: agent setting)',
+    link_local VARCHAR(20) NULL COMMENT 'Link-local families, e.g. ipv6 or ipv4,ipv6; empty disables (NULL: netplan default)',
+    status VARCHAR(50) DEFAULT 'active',
+    created_at TIMESTAMP NULL,
+    modified_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL
+);
+
+-- 노드 테이블 생성
+CREATE TABLE node_table (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    attached_node_id VARCHAR(36) NOT NULL UNIQUE,
+    attached_node_name VARCHAR(255) NOT NULL UNIQUE,
+    status VARCHAR(50) DEFAULT 'active',
+    created_at TIMESTAMP NULL,
+    modified_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL
+);
+
+-- 인터페이스 그룹 테이블 생성 (본드/브리지)
+CREATE TABLE multi_interface_group (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    group_id VARCHAR(36) NOT NULL UNIQUE,
+    group_name VARCHAR(15) NOT NULL COMMENT 'Interface name on the node (e.g. bond0, br0)',
+    group_type VARCHAR(16) NOT NULL COMMENT 'bond or bridge',
+    attached_node_id VARCHAR(36) NOT NULL,
+    bond_mode VARCHAR(32) NULL COMMENT 'e.g. 802.3ad, active-backup',
+    lacp_rate VARCHAR(8) NULL COMMENT 'slow or fast',
+    mii_monitor_interval INT NULL COMMENT 'milliseconds',
+    primary_port_id VARCHAR(36) NULL COMMENT 'Primary member port (active-backup)',
+    stp TINYINT(1) NULL COMMENT 'Bridge STP',
+    mtu INT NULL,
+    status VARCHAR(50) DEFAULT 'active',
+    created_at TIMESTAMP NULL,
+    modified_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL,
+    FOREIGN KEY (attached_node_id) REFERENCES node_table(attached_node_id),
+    UNIQUE KEY unique_node_group (attached_node_id, group_name)
+);
+
+-- 인터페이스 테이블 생성
+CREATE TABLE multi_interface (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    port_id VARCHAR(36) NOT NULL UNIQUE,
+    subnet_id VARCHAR(36) NOT NULL,
+    macaddress VARCHAR(17) NOT NULL,
+    attached_node_id VARCHAR(36),
+    attached_node_name VARCHAR(255) NULL,
+    cr_namespace VARCHAR(255) NOT NULL COMMENT 'OpenstackConfig CR namespace',
+    cr_name VARCHAR(255) NOT NULL COMMENT 'OpenstackConfig CR name',
+    status VARCHAR(50) DEFAULT 'active',
+    netplan_success TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Netplan apply success (0: fail/not applied, 1: success)',
+    netplan_reason VARCHAR(255) NULL COMMENT 'Why netplan was not applied (e.g. protected interface, MAC not found)',
+    parent_port_id VARCHAR(36) NULL COMMENT 'Trunk parent port ID (set for VLAN sub-ports)',
+    vlan_id INT NULL COMMENT 'VLAN ID (segmentation ID) of the sub-port',
+    group_id VARCHAR(36) NULL COMMENT 'Bond/bridge this port is a member of',
+    vnic_type VARCHAR(32) NULL COMMENT 'OpenStack binding:vnic_type (normal, direct)',
+    pci_address VARCHAR(16) NULL COMMENT 'SR-IOV VF PCI address (e.g. 0000:3b:02.1)',
+    pf_pci_address VARCHAR(16) NULL COMMENT 'SR-IOV PF PCI address',
+    sriov_vf_count INT NULL COMMENT 'Desired number of VFs on the PF',
+    sriov_switch_mode VARCHAR(16) NULL COMMENT 'PF embedded-switch-mode (legacy, switchdev)',
+    created_at TIMESTAMP NULL,
+    modified_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL,
+    FOREIGN KEY (subnet_id) REFERENCES multi_subnet(subnet_id),
+    FOREIGN KEY (attached_node_id) REFERENCES node_table(attached_node_id),
+    FOREIGN KEY (attached_node_name) REFERENCES node_table(attached_node_name),
+    FOREIGN KEY (parent_port_id) REFERENCES multi_interface(port_id),
+    FOREIGN KEY (group_id) REFERENCES multi_interface_group(group_id),
+    UNIQUE KEY unique_cr_interface (cr_namespace, cr_name, port_id)
+);
+
+-- 포트 고정 IP 테이블 생성 (포트당 여러 서브넷/IP 가능)
+CREATE TABLE multi_interface_ip (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    port_id VARCHAR(36) NOT NULL,
+    subnet_id VARCHAR(36) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL COMMENT 'OpenStack fixed_ips[].ip_address',
+    status VARCHAR(50) DEFAULT 'active',
+    created_at TIMESTAMP NULL,
+    modified_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL,
+    FOREIGN KEY (port_id) REFERENCES multi_interface(port_id),
+    FOREIGN KEY (subnet_id) REFERENCES multi_subnet(subnet_id),
+    UNIQUE KEY unique_port_ip (port_id, ip_address)
+);
+
+-- 호스트 인터페이스 테이블 생성 (에이전트가 보고하는 노드의 실제 NIC 목록)
+CREATE TABLE node_host_interface (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    attached_node_name VARCHAR(255) NOT NULL,
+    interface_name VARCHAR(15) NOT NULL,
+    macaddress VARCHAR(17) NOT NULL,
+    permanent_macaddress VARCHAR(17) NULL COMMENT 'Permanent MAC (differs when enslaved to a bond)',
+    driver VARCHAR(64) NULL,
+    pci_address VARCHAR(16) NULL,
+    kind VARCHAR(32) NULL COMMENT 'Link type (device, vlan, bond, bridge, ...)',
+    physical TINYINT(1) NOT NULL DEFAULT 0,
+    oper_state VARCHAR(16) NOT NULL,
+    carrier TINYINT(1) NOT NULL DEFAULT 0,
+    speed INT NOT NULL DEFAULT -1 COMMENT 'Mbps, -1 when unknown',
+    mtu INT NOT NULL,
+    master VARCHAR(15) NULL COMMENT 'Bond or bridge the link is enslaved to',
+    addresses TEXT NULL COMMENT 'Comma separated CIDR addresses',
+    reported_at TIMESTAMP NULL,
+    UNIQUE KEY unique_node_host_interface (attached_node_name, interface_name),
+    KEY idx_host_interface_mac (macaddress)
+);
+
+-- netplan 적용 기록 테이블 생성 (적용 방법, 종료 코드, 출력, 확인 여부)
+CREATE TABLE netplan_apply_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    attached_node_name VARCHAR(255) NOT NULL,
+    method VARCHAR(64) NOT NULL COMMENT 'netplan-apply, systemd-run, generate+networkctl-reload, ...',
+    success TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Applied and confirmed active on the host',
+    confirmed TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Host state matched the configuration',
+    reverted TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Safe apply restored the previous configuration',
+    exit_code INT NULL COMMENT 'Exit code of the last command run',
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    error TEXT NULL,
+    stdout TEXT NULL COMMENT 'Output of the last command run',
+    stderr TEXT NULL,
+    attempts TEXT NULL COMMENT 'JSON list of every command run',
+    applied_at TIMESTAMP NULL,
+    KEY idx_apply_log_node (attached_node_name, applied_at)
+);
+
+-- CR 상태 테이블 생성
+CREATE TABLE cr_state (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    cr_namespace VARCHAR(255) NOT NULL,
+    cr_name VARCHAR(255) NOT NULL,
+    spec_hash VARCHAR(64) NOT NULL COMMENT 'SHA256 hash of CR spec',
+    last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_cr (cr_namespace, cr_name)
+);
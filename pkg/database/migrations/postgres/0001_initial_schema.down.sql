@@ -0,0 +1,9 @@
+-- 초기 스키마의 모든 테이블 삭제 (외래 키 역순)
+DROP TABLE IF EXISTS cr_state;
+DROP TABLE IF EXISTS netplan_apply_log;
+DROP TABLE IF EXISTS node_host_interface;
+DROP TABLE IF EXISTS multi_interface_ip;
+DROP TABLE IF EXISTS multi_interface;
+DROP TABLE IF EXISTS multi_interface_group;
+DROP TABLE IF EXISTS node_table;
+DROP TABLE IF EXISTS multi_subnet;
@@ -0,0 +1,75 @@
+package hostexec
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Capability is a Linux capability number (see capabilities(7))
+type Capability uint
+
+// Capabilities the agent's operations depend on
+const (
+	CapDACOverride Capability = 1
+	CapNetAdmin    Capability = 12
+	CapSysChroot   Capability = 18
+	CapSysPtrace   Capability = 19
+	CapSysAdmin    Capability = 21
+)
+
+var capabilityNames = map[Capability]string{
+	CapDACOverride: "CAP_DAC_OVERRIDE",
+	CapNetAdmin:    "CAP_NET_ADMIN",
+	CapSysChroot:   "CAP_SYS_CHROOT",
+	CapSysPtrace:   "CAP_SYS_PTRACE",
+	CapSysAdmin:    "CAP_SYS_ADMIN",
+}
+
+func (c Capability) String() string {
+	if name, ok := capabilityNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("CAP_%d", uint(c))
+}
+
+// CapabilitySet is a capability bitmask as shown in /proc/<pid>/status
+type CapabilitySet uint64
+
+// Has reports whether the capability is in the set
+func (s CapabilitySet) Has(c Capability) bool {
+	return c < 64 && s&(1<<c) != 0
+}
+
+// ParseCapabilitySet decodes a hex bitmask such as "000001ffffffffff"
+func ParseCapabilitySet(mask string) (CapabilitySet, error) {
+	value, err := strconv.ParseUint(strings.TrimSpace(mask), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid capability mask %q: %w", mask, err)
+	}
+	return CapabilitySet(value), nil
+}
+
+// ParseEffectiveCapabilities returns the CapEff set from the content of a
+// /proc/<pid>/status file
+func ParseEffectiveCapabilities(status string) (CapabilitySet, error) {
+	scanner := bufio.NewScanner(strings.NewReader(status))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && key == "CapEff" {
+			return ParseCapabilitySet(value)
+		}
+	}
+	return 0, fmt.Errorf("CapEff not found in process status")
+}
+
+// EffectiveCapabilities returns the agent's effective capabilities
+func EffectiveCapabilities() (CapabilitySet, error) {
+	data, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return 0, err
+	}
+	return ParseEffectiveCapabilities(string(data))
+}
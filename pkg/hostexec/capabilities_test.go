@@ -0,0 +1,86 @@
+package hostexec
+
+import "testing"
+
+// statusSample is a /proc/self/status trimmed to the lines around CapEff
+func statusSample(capEff string) string {
+	return `Name:	multinic-agent
+Umask:	0022
+State:	S (sleeping)
+Tgid:	1
+Pid:	1
+PPid:	0
+Uid:	0	0	0	0
+Gid:	0	0	0	0
+CapInh:	0000000000000000
+CapPrm:	` + capEff + `
+CapEff:	` + capEff + `
+CapBnd:	` + capEff + `
+CapAmb:	0000000000000000
+NoNewPrivs:	0
+Seccomp:	0
+`
+}
+
+func TestParseEffectiveCapabilities(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		has     []Capability
+		hasNot  []Capability
+		wantErr bool
+	}{
+		{
+			name:   "privileged container",
+			status: statusSample("000001ffffffffff"),
+			has:    []Capability{CapDACOverride, CapNetAdmin, CapSysChroot, CapSysPtrace, CapSysAdmin},
+		},
+		{
+			name:   "container runtime defaults",
+			status: statusSample("00000000a80425fb"),
+			has:    []Capability{CapDACOverride, CapSysChroot},
+			hasNot: []Capability{CapNetAdmin, CapSysPtrace, CapSysAdmin},
+		},
+		{
+			name:   "NET_ADMIN added",
+			status: statusSample("00000000a80435fb"),
+			has:    []Capability{CapNetAdmin},
+			hasNot: []Capability{CapSysAdmin},
+		},
+		{
+			name:   "unprivileged user",
+			status: statusSample("0000000000000000"),
+			hasNot: []Capability{CapDACOverride, CapNetAdmin, CapSysAdmin},
+		},
+		{name: "no CapEff line", status: "Name:\tmultinic-agent\nPid:\t1\n", wantErr: true},
+		{name: "invalid mask", status: "CapEff:\tnot-hex\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caps, err := ParseEffectiveCapabilities(tt.status)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseEffectiveCapabilities error = %v, want error %v", err, tt.wantErr)
+			}
+			for _, c := range tt.has {
+				if !caps.Has(c) {
+					t.Errorf("%016x lacks %s", uint64(caps), c)
+				}
+			}
+			for _, c := range tt.hasNot {
+				if caps.Has(c) {
+					t.Errorf("%016x has %s", uint64(caps), c)
+				}
+			}
+		})
+	}
+}
+
+func TestCapabilitySetHas(t *testing.T) {
+	all := CapabilitySet(^uint64(0))
+	if !all.Has(63) || all.Has(64) {
+		t.Error("Has must accept capability 63 and reject numbers past the mask")
+	}
+	if got := Capability(40).String(); got != "CAP_40" {
+		t.Errorf("String() = %q, want CAP_40", got)
+	}
+}
@@ -0,0 +1,359 @@
+package hostexec
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+)
+
+// containerCgroupMarkers are cgroup path fragments left by container runtimes
+var containerCgroupMarkers = []string{"docker", "kubepods", "containerd", "crio", "libpod", "lxc"}
+
+// accessWrite is W_OK for access(2)
+const accessWrite = 0x2
+
+// hostProc is where the daemonset mounts the host's /proc. PID 1 seen
+// through it is the host's init even when the pod has its own PID namespace.
+const hostProc = "/host/proc"
+
+// Environment describes where the agent runs
+type Environment struct {
+	// InContainer is true when any container evidence was found
+	InContainer bool
+	// Evidence lists why the agent is believed to run in a container
+	Evidence []string
+	// WeakEvidence lists hints that also occur on hosts, such as an overlay
+	// root on live images and some immutable distros. They are reported but
+	// do not decide InContainer on their own.
+	WeakEvidence []string
+	// Capabilities is the agent's effective capability set
+	Capabilities CapabilitySet
+	// HostPID is true when the agent shares the host init's PID namespace
+	HostPID bool
+	// HostNetwork is true when the agent shares the host init's network
+	// namespace (always true outside a container)
+	HostNetwork bool
+}
+
+// DetectEnvironment inspects /proc for container and privilege information
+func DetectEnvironment() Environment {
+	env := Environment{}
+	env.Evidence, env.WeakEvidence = containerEvidence()
+
+	if caps, err := EffectiveCapabilities(); err == nil {
+		env.Capabilities = caps
+	}
+
+	var evidence []string
+	env.HostPID, env.HostNetwork, evidence = hostNamespaces("/proc", hostProc, os.Getpid())
+	env.Evidence = append(env.Evidence, evidence...)
+	env.InContainer = len(env.Evidence) > 0
+
+	// Outside a container the agent is in the host's namespaces, even when
+	// an unprivileged user cannot read PID 1's
+	if !env.InContainer {
+		env.HostPID = true
+		env.HostNetwork = true
+	}
+
+	return env
+}
+
+// hostNamespaces compares this process' PID and network namespaces with
+// those of the host's PID 1, read through the host /proc mount when there
+// is one. Without it PID 1 is the one in procRoot, which is the host's init
+// unless the agent is itself PID 1 of its own PID namespace. A namespace
+// that cannot be read counts as not shared.
+func hostNamespaces(procRoot, hostProcRoot string, selfPID int) (hostPID, hostNetwork bool, evidence []string) {
+	initProc := procRoot
+	if _, err := os.Stat(filepath.Join(hostProcRoot, "1", "ns")); err == nil {
+		initProc = hostProcRoot
+	} else if selfPID == 1 {
+		evidence = append(evidence, "running as PID 1 without the host /proc")
+		return false, false, evidence
+	}
+
+	shared := func(ns string) bool {
+		self, selfErr := os.Readlink(filepath.Join(procRoot, "self", "ns", ns))
+		initNS, initErr := os.Readlink(filepath.Join(initProc, "1", "ns", ns))
+		if selfErr != nil || initErr != nil {
+			return false
+		}
+		if self != initNS {
+			evidence = append(evidence, fmt.Sprintf("%s namespace differs from PID 1", ns))
+			return false
+		}
+		return true
+	}
+
+	hostPID = shared("pid")
+	hostNetwork = shared("net")
+	return hostPID, hostNetwork, evidence
+}
+
+// containerEvidence collects the container markers present in this process'
+// view of the system, split into evidence and weak hints
+func containerEvidence() (evidence, weak []string) {
+
+	for _, marker := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(marker); err == nil {
+			evidence = append(evidence, marker+" exists")
+		}
+	}
+
+	// systemd's container interface, set by podman, systemd-nspawn and LXC
+	if runtime := os.Getenv("container"); runtime != "" {
+		evidence = append(evidence, "container="+runtime)
+	}
+
+	if data, err := os.ReadFile("/proc/self/cgroup"); err == nil {
+		if marker := cgroupContainerMarker(string(data)); marker != "" {
+			evidence = append(evidence, "cgroup path contains "+marker)
+		}
+	}
+
+	if data, err := os.ReadFile("/proc/self/mountinfo"); err == nil {
+		if fsType := rootFSType(string(data)); fsType == "overlay" {
+			weak = append(weak, "root filesystem is overlay")
+		}
+	}
+
+	return evidence, weak
+}
+
+// cgroupContainerMarker returns the first container runtime marker found in
+// the content of a /proc/<pid>/cgroup file
+func cgroupContainerMarker(cgroup string) string {
+	scanner := bufio.NewScanner(strings.NewReader(cgroup))
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		for _, marker := range containerCgroupMarkers {
+			if strings.Contains(fields[2], marker) {
+				return marker
+			}
+		}
+	}
+	return ""
+}
+
+// rootFSType returns the filesystem type mounted at / in the content of a
+// /proc/<pid>/mountinfo file
+func rootFSType(mountinfo string) string {
+	scanner := bufio.NewScanner(strings.NewReader(mountinfo))
+	for scanner.Scan() {
+		// ID parent major:minor root mount-point options [optional...] - type source super-options
+		pre, post, ok := strings.Cut(scanner.Text(), " - ")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(pre)
+		if len(fields) < 5 || fields[4] != "/" {
+			continue
+		}
+		if postFields := strings.Fields(post); len(postFields) > 0 {
+			return postFields[0]
+		}
+	}
+	return ""
+}
+
+// Operation is one capability the self-check verified
+type Operation struct {
+	Name    string
+	Allowed bool
+	// Reason explains why the operation is not possible
+	Reason string
+}
+
+// Report is the result of the startup self-check
+type Report struct {
+	Environment
+	// Mode is the execution mode the configuration resolves to
+	Mode       string
+	Operations []Operation
+}
+
+// Allowed reports whether the named operation passed the self-check
+func (r *Report) Allowed(name string) bool {
+	for _, op := range r.Operations {
+		if op.Name == name {
+			return op.Allowed
+		}
+	}
+	return false
+}
+
+// Self-check operation names
+const (
+	OpWriteNetplan = "write netplan configuration"
+	OpNetlink      = "manage host links via netlink"
+	OpSRIOV        = "configure SR-IOV via sysfs"
+	OpNsenter      = "enter host namespaces (nsenter)"
+	OpChroot       = "chroot into host root"
+	OpHostCommands = "run netplan on the host"
+)
+
+// SelfCheck determines which operations the agent can perform with the given
+// host exec configuration, netplan directory and sysfs root
+func SelfCheck(cfg *config.HostExecConfig, netplanDir, sysfsRoot string) *Report {
+	env := DetectEnvironment()
+	report := &Report{Environment: env, Mode: resolveMode(cfg, env)}
+
+	add := func(name string, err error) {
+		op := Operation{Name: name, Allowed: err == nil}
+		if err != nil {
+			op.Reason = err.Error()
+		}
+		report.Operations = append(report.Operations, op)
+	}
+
+	add(OpWriteNetplan, checkWritable(netplanDir))
+	add(OpNetlink, checkNetlink(env))
+	add(OpSRIOV, checkSRIOV(env, sysfsRoot))
+	add(OpNsenter, checkNsenter(env, cfg.TargetPID))
+	add(OpChroot, checkChroot(env, cfg.HostRoot))
+
+	switch report.Mode {
+	case ModeDirect:
+		add(OpHostCommands, checkDirect(env))
+	case ModeNsenter:
+		add(OpHostCommands, checkNsenter(env, cfg.TargetPID))
+	case ModeChroot:
+		add(OpHostCommands, checkChroot(env, cfg.HostRoot))
+	default:
+		add(OpHostCommands, fmt.Errorf("host exec mode is %q", report.Mode))
+	}
+
+	return report
+}
+
+// Log writes the report, one line per operation
+func (r *Report) Log(logger *zap.Logger) {
+	logger.Info("Runtime environment",
+		zap.Bool("in_container", r.InContainer),
+		zap.Strings("evidence", r.Evidence),
+		zap.Strings("weak_evidence", r.WeakEvidence),
+		zap.String("cap_eff", fmt.Sprintf("%016x", uint64(r.Capabilities))),
+		zap.Bool("cap_net_admin", r.Capabilities.Has(CapNetAdmin)),
+		zap.Bool("cap_sys_admin", r.Capabilities.Has(CapSysAdmin)),
+		zap.Bool("host_pid", r.HostPID),
+		zap.Bool("host_network", r.HostNetwork),
+		zap.String("host_exec_mode", r.Mode))
+
+	for _, op := range r.Operations {
+		if op.Allowed {
+			logger.Info("Self-check passed", zap.String("operation", op.Name))
+		} else {
+			logger.Warn("Self-check failed",
+				zap.String("operation", op.Name),
+				zap.String("reason", op.Reason))
+		}
+	}
+}
+
+// resolveMode turns ModeAuto into a concrete mode
+func resolveMode(cfg *config.HostExecConfig, env Environment) string {
+	if cfg.Mode != "" && cfg.Mode != ModeAuto {
+		return cfg.Mode
+	}
+	if !env.InContainer {
+		return ModeDirect
+	}
+	if checkNsenter(env, cfg.TargetPID) == nil {
+		return ModeNsenter
+	}
+	if checkChroot(env, cfg.HostRoot) == nil {
+		return ModeChroot
+	}
+	return ModeNone
+}
+
+func requireCapability(env Environment, c Capability) error {
+	if !env.Capabilities.Has(c) {
+		return fmt.Errorf("missing %s", c)
+	}
+	return nil
+}
+
+// checkWritable creates and removes a probe file in dir
+func checkWritable(dir string) error {
+	file, err := os.CreateTemp(dir, ".multinic-selfcheck-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+func checkNetlink(env Environment) error {
+	if err := requireCapability(env, CapNetAdmin); err != nil {
+		return err
+	}
+	if !env.HostNetwork {
+		return errors.New("not in the host network namespace")
+	}
+	return nil
+}
+
+func checkSRIOV(env Environment, sysfsRoot string) error {
+	if err := requireCapability(env, CapSysAdmin); err != nil {
+		return err
+	}
+	if err := syscall.Access(filepath.Join(sysfsRoot, "bus", "pci"), accessWrite); err != nil {
+		return fmt.Errorf("%s is not writable: %w", sysfsRoot, err)
+	}
+	return nil
+}
+
+func checkNsenter(env Environment, pid int) error {
+	if err := requireCapability(env, CapSysAdmin); err != nil {
+		return err
+	}
+	if pid == 1 && !env.HostPID {
+		return errors.New("host PID namespace not shared")
+	}
+	if _, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(pid), "ns", "mnt")); err != nil {
+		return fmt.Errorf("cannot access namespaces of PID %d: %w", pid, err)
+	}
+	if _, err := exec.LookPath("nsenter"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func checkChroot(env Environment, root string) error {
+	if err := requireCapability(env, CapSysChroot); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(root, "etc", "netplan")); err != nil {
+		return fmt.Errorf("host root %s has no /etc/netplan: %w", root, err)
+	}
+	if _, err := exec.LookPath("chroot"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func checkDirect(env Environment) error {
+	if env.InContainer && !env.HostNetwork {
+		return errors.New("commands would run in the container's network namespace")
+	}
+	if _, err := exec.LookPath("netplan"); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package hostexec
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeNamespaces creates <proc>/<pid>/ns links naming the given namespaces
+func writeNamespaces(t *testing.T, proc, pid string, namespaces map[string]string) {
+	t.Helper()
+	dir := filepath.Join(proc, pid, "ns")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for ns, id := range namespaces {
+		if err := os.Symlink(ns+":["+id+"]", filepath.Join(dir, ns)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHostNamespaces(t *testing.T) {
+	host := map[string]string{"pid": "4026531836", "net": "4026531840", "mnt": "4026531841"}
+	pod := map[string]string{"pid": "4026532201", "net": "4026532204", "mnt": "4026532200"}
+	// A hostNetwork pod keeps its own PID and mount namespaces
+	hostNetwork := map[string]string{"pid": pod["pid"], "net": host["net"], "mnt": pod["mnt"]}
+	// A hostPID pod keeps its own network and mount namespaces
+	hostPID := map[string]string{"pid": host["pid"], "net": pod["net"], "mnt": pod["mnt"]}
+
+	tests := []struct {
+		name string
+		self map[string]string
+		// procInit is PID 1 in the agent's own /proc; hostInit, if set, is
+		// PID 1 in the host /proc mount
+		procInit        map[string]string
+		hostInit        map[string]string
+		selfPID         int
+		wantHostPID     bool
+		wantHostNetwork bool
+		wantEvidence    []string
+	}{
+		{
+			name:            "host",
+			self:            host,
+			procInit:        host,
+			selfPID:         1234,
+			wantHostPID:     true,
+			wantHostNetwork: true,
+		},
+		{
+			name:         "isolated pod",
+			self:         pod,
+			procInit:     pod,
+			hostInit:     host,
+			selfPID:      1,
+			wantEvidence: []string{"pid namespace differs from PID 1", "net namespace differs from PID 1"},
+		},
+		{
+			name:            "hostNetwork pod",
+			self:            hostNetwork,
+			procInit:        hostNetwork,
+			hostInit:        host,
+			selfPID:         1,
+			wantHostNetwork: true,
+			wantEvidence:    []string{"pid namespace differs from PID 1"},
+		},
+		{
+			name:         "hostPID pod",
+			self:         hostPID,
+			procInit:     host,
+			hostInit:     host,
+			selfPID:      4321,
+			wantHostPID:  true,
+			wantEvidence: []string{"net namespace differs from PID 1"},
+		},
+		{
+			// A different mount namespace alone says nothing about PID or network
+			name:            "hostPID and hostNetwork pod",
+			self:            map[string]string{"pid": host["pid"], "net": host["net"], "mnt": pod["mnt"]},
+			procInit:        host,
+			selfPID:         4321,
+			wantHostPID:     true,
+			wantHostNetwork: true,
+		},
+		{
+			name:         "own PID 1 without host /proc",
+			self:         hostNetwork,
+			procInit:     hostNetwork,
+			selfPID:      1,
+			wantEvidence: []string{"running as PID 1 without the host /proc"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proc := t.TempDir()
+			hostProc := filepath.Join(t.TempDir(), "host-proc")
+			writeNamespaces(t, proc, "self", tt.self)
+			writeNamespaces(t, proc, "1", tt.procInit)
+			if tt.hostInit != nil {
+				writeNamespaces(t, hostProc, "1", tt.hostInit)
+			}
+
+			gotPID, gotNet, evidence := hostNamespaces(proc, hostProc, tt.selfPID)
+			if gotPID != tt.wantHostPID || gotNet != tt.wantHostNetwork {
+				t.Errorf("HostPID, HostNetwork = %v, %v, want %v, %v", gotPID, gotNet, tt.wantHostPID, tt.wantHostNetwork)
+			}
+			if !slices.Equal(evidence, tt.wantEvidence) {
+				t.Errorf("evidence = %q, want %q", evidence, tt.wantEvidence)
+			}
+		})
+	}
+}
+
+func TestCgroupContainerMarker(t *testing.T) {
+	tests := []struct {
+		name   string
+		cgroup string
+		want   string
+	}{
+		{
+			name: "cgroup v1 docker",
+			cgroup: `12:memory:/docker/3f9a1c2e8b7d
+11:cpu,cpuacct:/docker/3f9a1c2e8b7d
+1:name=systemd:/docker/3f9a1c2e8b7d
+`,
+			want: "docker",
+		},
+		{
+			name: "cgroup v1 kubernetes",
+			cgroup: `11:devices:/kubepods/besteffort/pod5c1b9e4a/8d2f
+1:name=systemd:/kubepods/besteffort/pod5c1b9e4a/8d2f
+`,
+			want: "kubepods",
+		},
+		{
+			name:   "cgroup v2 kubernetes with host cgroup namespace",
+			cgroup: "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod5c1b9e4a.slice/cri-containerd-8d2f.scope\n",
+			want:   "kubepods",
+		},
+		{name: "cgroup v2 private cgroup namespace", cgroup: "0::/\n"},
+		{name: "cgroup v2 host service", cgroup: "0::/system.slice/multinic-agent.service\n"},
+		{
+			name: "cgroup v1 host",
+			cgroup: `12:memory:/system.slice/multinic-agent.service
+1:name=systemd:/system.slice/multinic-agent.service
+`,
+		},
+		{name: "malformed", cgroup: "docker\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cgroupContainerMarker(tt.cgroup); got != tt.want {
+				t.Errorf("cgroupContainerMarker = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRootFSType(t *testing.T) {
+	tests := []struct {
+		name      string
+		mountinfo string
+		want      string
+	}{
+		{
+			name: "container",
+			mountinfo: `1843 1602 0:312 / / rw,relatime master:520 - overlay overlay rw,lowerdir=/var/lib/containerd/l/A:/var/lib/containerd/l/B,upperdir=/var/lib/containerd/1/fs,workdir=/var/lib/containerd/1/work
+1844 1843 0:315 / /proc rw,nosuid,nodev,noexec,relatime - proc proc rw
+1845 1843 0:316 / /dev rw,nosuid - tmpfs tmpfs rw,size=65536k,mode=755
+`,
+			want: "overlay",
+		},
+		{
+			name: "host",
+			mountinfo: `22 1 0:21 / /sys rw,nosuid,nodev,noexec,relatime shared:7 - sysfs sysfs rw
+28 1 253:0 / / rw,relatime shared:1 - ext4 /dev/mapper/vg-root rw,errors=remount-ro
+29 28 0:25 / /run rw,nosuid,nodev shared:11 - tmpfs tmpfs rw,size=3262808k,mode=755
+`,
+			want: "ext4",
+		},
+		{
+			name:      "optional fields",
+			mountinfo: "28 1 0:30 /@ / rw,relatime shared:1 master:2 - btrfs /dev/nvme0n1p2 rw,ssd,subvol=/@\n",
+			want:      "btrfs",
+		},
+		{name: "no root mount", mountinfo: "29 28 0:25 / /run rw - tmpfs tmpfs rw\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rootFSType(tt.mountinfo); got != tt.want {
+				t.Errorf("rootFSType = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
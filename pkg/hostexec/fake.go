@@ -0,0 +1,66 @@
+package hostexec
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Fake is a HostExecutor that records commands and returns canned results,
+// for tests and offline tools
+type Fake struct {
+	mu        sync.Mutex
+	calls     [][]string
+	responses []fakeResponse
+}
+
+type fakeResponse struct {
+	prefix string
+	result Result
+	err    error
+}
+
+// On registers the result for commands whose command line starts with
+// prefix. Later registrations take precedence; unmatched commands succeed
+// with empty output.
+func (f *Fake) On(prefix string, result Result, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses = append(f.responses, fakeResponse{prefix: prefix, result: result, err: err})
+}
+
+// Calls returns the command lines run so far; commands given a done
+// context are not run and not recorded
+func (f *Fake) Calls() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := make([][]string, len(f.calls))
+	copy(calls, f.calls)
+	return calls
+}
+
+func (f *Fake) Run(ctx context.Context, name string, args ...string) (*Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	command := append([]string{name}, args...)
+	line := strings.Join(command, " ")
+
+	// Like the real executors, nothing runs once ctx is done
+	if err := ctx.Err(); err != nil {
+		return &Result{Command: command}, fmt.Errorf("%s: %w", line, err)
+	}
+	f.calls = append(f.calls, command)
+
+	for i := len(f.responses) - 1; i >= 0; i-- {
+		response := f.responses[i]
+		if strings.HasPrefix(line, response.prefix) {
+			result := response.result
+			result.Command = command
+			return &result, response.err
+		}
+	}
+
+	return &Result{Command: command}, nil
+}
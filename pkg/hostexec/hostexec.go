@@ -0,0 +1,167 @@
+package hostexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+)
+
+// Execution modes
+const (
+	// ModeAuto picks direct outside a container, then nsenter or chroot
+	// when the self-check allows them, and none otherwise
+	ModeAuto = "auto"
+	// ModeDirect runs commands as child processes of the agent
+	ModeDirect = "direct"
+	// ModeNsenter enters the namespaces of a host process (PID 1)
+	ModeNsenter = "nsenter"
+	// ModeChroot runs commands with the host root filesystem as root
+	ModeChroot = "chroot"
+	// ModeNone has no host access; every command fails with ErrNoHostAccess
+	ModeNone = "none"
+)
+
+// ErrNoHostAccess is returned when commands cannot reach the host
+var ErrNoHostAccess = errors.New("no host access")
+
+// nsenterFlags maps namespace names to nsenter flags
+var nsenterFlags = map[string]string{
+	"mount":  "-m",
+	"uts":    "-u",
+	"ipc":    "-i",
+	"net":    "-n",
+	"pid":    "-p",
+	"cgroup": "-C",
+	"user":   "-U",
+}
+
+// Result is the outcome of a command run on the host
+type Result struct {
+	// Command is the full command line as executed, including any wrapper
+	Command  []string
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	Duration time.Duration
+}
+
+// HostExecutor runs commands in the host's context
+type HostExecutor interface {
+	// Run executes the command and returns its result. The error is non-nil
+	// when the command could not start, was cancelled or exited non-zero;
+	// the result is returned in every case.
+	Run(ctx context.Context, name string, args ...string) (*Result, error)
+}
+
+// executor prefixes commands with a wrapper (nsenter, chroot) and applies
+// the per-command timeout
+type executor struct {
+	prefix  []string
+	timeout time.Duration
+}
+
+// NewDirect returns an executor that runs commands directly
+func NewDirect(timeout time.Duration) HostExecutor {
+	return &executor{timeout: timeout}
+}
+
+// NewNsenter returns an executor that enters the given namespaces of pid
+func NewNsenter(pid int, namespaces []string, timeout time.Duration) (HostExecutor, error) {
+	prefix := []string{"nsenter", "-t", strconv.Itoa(pid)}
+	for _, ns := range namespaces {
+		flag, ok := nsenterFlags[ns]
+		if !ok {
+			return nil, fmt.Errorf("unknown namespace %q", ns)
+		}
+		prefix = append(prefix, flag)
+	}
+	prefix = append(prefix, "--")
+
+	return &executor{prefix: prefix, timeout: timeout}, nil
+}
+
+// NewChroot returns an executor that runs commands chrooted into root
+func NewChroot(root string, timeout time.Duration) HostExecutor {
+	return &executor{prefix: []string{"chroot", root}, timeout: timeout}
+}
+
+// NewNone returns an executor without host access
+func NewNone() HostExecutor {
+	return none{}
+}
+
+func (e *executor) Run(ctx context.Context, name string, args ...string) (*Result, error) {
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	command := append(append(append([]string{}, e.prefix...), name), args...)
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+
+	result := &Result{
+		Command:  command,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Duration: time.Since(start),
+	}
+	if err == nil {
+		return result, nil
+	}
+
+	result.ExitCode = -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return result, fmt.Errorf("%s: %w", strings.Join(command, " "), ctxErr)
+	}
+	return result, fmt.Errorf("%s: %w", strings.Join(command, " "), err)
+}
+
+type none struct{}
+
+func (none) Run(ctx context.Context, name string, args ...string) (*Result, error) {
+	return &Result{Command: append([]string{name}, args...), ExitCode: -1}, ErrNoHostAccess
+}
+
+// New creates the executor selected by the configuration
+func New(cfg *config.HostExecConfig, logger *zap.Logger) (HostExecutor, error) {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+
+	mode := resolveMode(cfg, DetectEnvironment())
+
+	logger.Info("Host command execution mode",
+		zap.String("configured", cfg.Mode),
+		zap.String("mode", mode))
+
+	switch mode {
+	case ModeDirect:
+		return NewDirect(timeout), nil
+	case ModeNsenter:
+		return NewNsenter(cfg.TargetPID, cfg.Namespaces, timeout)
+	case ModeChroot:
+		return NewChroot(cfg.HostRoot, timeout), nil
+	case ModeNone:
+		return NewNone(), nil
+	default:
+		return nil, fmt.Errorf("unknown host exec mode %q", cfg.Mode)
+	}
+}
@@ -0,0 +1,24 @@
+package inventory
+
+import "context"
+
+// Fake is an Inventory returning fixed links, for tests and offline tools
+type Fake struct {
+	LinkList []Link
+	Err      error
+	// Events is returned by Watch; nil means no events are ever sent
+	Events chan LinkEvent
+}
+
+func (f *Fake) Links() ([]Link, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	links := make([]Link, len(f.LinkList))
+	copy(links, f.LinkList)
+	return links, nil
+}
+
+func (f *Fake) Watch(ctx context.Context) (<-chan LinkEvent, error) {
+	return f.Events, nil
+}
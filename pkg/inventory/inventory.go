@@ -0,0 +1,55 @@
+package inventory
+
+import (
+	"context"
+	"strings"
+)
+
+// Link is a network link on the host with the details needed for MAC
+// matching and status reporting
+type Link struct {
+	Index        int
+	Name         string
+	MACAddress   string
+	PermanentMAC string
+	Driver       string
+	PCIAddress   string
+	// Speed is in Mbps, -1 when unknown (e.g. link down or virtual)
+	Speed     int
+	Carrier   bool
+	OperState string
+	MTU       int
+	// Master is the bond or bridge the link is enslaved to
+	Master     string
+	MasterKind string
+	Addresses  []string
+	// Physical is true when the link is backed by a device (not virtual)
+	Physical bool
+	Kind     string
+	// DefaultRoute is true when a main-table default route leaves through the link
+	DefaultRoute bool
+}
+
+// LinkEvent is a notification that a link was added or changed
+type LinkEvent struct {
+	Name       string
+	MACAddress string
+}
+
+// Inventory lists host network links
+type Inventory interface {
+	Links() ([]Link, error)
+	// Watch streams link events until ctx is cancelled
+	Watch(ctx context.Context) (<-chan LinkEvent, error)
+}
+
+// FindByMAC returns the link whose current or permanent MAC matches mac
+func FindByMAC(links []Link, mac string) (Link, bool) {
+	mac = strings.ToLower(mac)
+	for _, link := range links {
+		if link.MACAddress == mac || (link.PermanentMAC != "" && link.PermanentMAC == mac) {
+			return link, true
+		}
+	}
+	return Link{}, false
+}
@@ -0,0 +1,166 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/ibyeong-geon/multinic-agent/pkg/sysfs"
+)
+
+// netlinkInventory reads links and addresses over netlink and device
+// details (driver, PCI address, speed, carrier) from sysfs
+type netlinkInventory struct {
+	fs sysfs.FS
+}
+
+// NewNetlinkInventory creates an Inventory backed by netlink and sysfs
+func NewNetlinkInventory(fs sysfs.FS) Inventory {
+	return &netlinkInventory{fs: fs}
+}
+
+func (inv *netlinkInventory) Links() ([]Link, error) {
+	nlLinks, err := netlink.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+
+	byIndex := make(map[int]netlink.Link, len(nlLinks))
+	for _, l := range nlLinks {
+		byIndex[l.Attrs().Index] = l
+	}
+
+	defaultRoutes, err := defaultRouteLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]Link, 0, len(nlLinks))
+	for _, l := range nlLinks {
+		attrs := l.Attrs()
+		link := Link{
+			Index:        attrs.Index,
+			Name:         attrs.Name,
+			MACAddress:   strings.ToLower(attrs.HardwareAddr.String()),
+			PermanentMAC: strings.ToLower(attrs.PermHWAddr.String()),
+			OperState:    attrs.OperState.String(),
+			MTU:          attrs.MTU,
+			Kind:         l.Type(),
+			Speed:        -1,
+			DefaultRoute: defaultRoutes[attrs.Index],
+		}
+
+		if master, ok := byIndex[attrs.MasterIndex]; ok && attrs.MasterIndex != 0 {
+			link.Master = master.Attrs().Name
+			link.MasterKind = master.Type()
+		}
+
+		inv.readDevice(&link)
+
+		addrs, err := netlink.AddrList(l, netlink.FAMILY_ALL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list addresses of %s: %w", attrs.Name, err)
+		}
+		for _, addr := range addrs {
+			link.Addresses = append(link.Addresses, addr.IPNet.String())
+		}
+
+		links = append(links, link)
+	}
+
+	return links, nil
+}
+
+func (inv *netlinkInventory) Watch(ctx context.Context) (<-chan LinkEvent, error) {
+	updates := make(chan netlink.LinkUpdate)
+	done := make(chan struct{})
+	if err := netlink.LinkSubscribe(updates, done); err != nil {
+		return nil, fmt.Errorf("failed to subscribe to link updates: %w", err)
+	}
+
+	events := make(chan LinkEvent, 16)
+	go func() {
+		defer close(events)
+		defer close(done)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update, ok := <-updates:
+				if !ok {
+					return
+				}
+				attrs := update.Link.Attrs()
+				event := LinkEvent{
+					Name:       attrs.Name,
+					MACAddress: strings.ToLower(attrs.HardwareAddr.String()),
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// defaultRouteLinks returns the indexes of links that default routes in the
+// main table leave through
+func defaultRouteLinks() (map[int]bool, error) {
+	routes, err := netlink.RouteList(nil, netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+
+	indexes := make(map[int]bool)
+	for _, route := range routes {
+		if route.Dst != nil {
+			if ones, _ := route.Dst.Mask.Size(); ones != 0 {
+				continue
+			}
+		}
+		if route.LinkIndex != 0 {
+			indexes[route.LinkIndex] = true
+		}
+		for _, nexthop := range route.MultiPath {
+			indexes[nexthop.LinkIndex] = true
+		}
+	}
+
+	return indexes, nil
+}
+
+// readDevice fills the sysfs-only attributes of a link
+func (inv *netlinkInventory) readDevice(link *Link) {
+	base := path.Join("class/net", link.Name)
+
+	if carrier, err := inv.fs.ReadFile(path.Join(base, "carrier")); err == nil {
+		link.Carrier = carrier == "1"
+	}
+	if speed, err := inv.fs.ReadFile(path.Join(base, "speed")); err == nil {
+		if v, err := strconv.Atoi(speed); err == nil && v > 0 {
+			link.Speed = v
+		}
+	}
+
+	device, err := inv.fs.Readlink(path.Join(base, "device"))
+	if err != nil {
+		return
+	}
+	link.Physical = true
+
+	if subsystem, err := inv.fs.Readlink(path.Join(base, "device/subsystem")); err == nil && path.Base(subsystem) == "pci" {
+		link.PCIAddress = path.Base(device)
+	}
+	if driver, err := inv.fs.Readlink(path.Join(base, "device/driver")); err == nil {
+		link.Driver = path.Base(driver)
+	}
+}
@@ -31,7 +31,7 @@ func NewLogger(cfg *config.LoggingConfig) (*zap.Logger, error) {
 		encoder = zapcore.NewConsoleEncoder(encoderConfig)
 	}
 
-	// 출력 설정 (stdout 또는 파일)
+	// 출력 설정 (stdout, stderr 또는 파일)
 	var writer zapcore.WriteSyncer
 	if cfg.Output == "file" && cfg.FilePath != "" {
 		file, err := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
@@ -39,6 +39,8 @@ func NewLogger(cfg *config.LoggingConfig) (*zap.Logger, error) {
 			return nil, fmt.Errorf("failed to open log file: %w", err)
 		}
 		writer = zapcore.AddSync(file)
+	} else if cfg.Output == "stderr" {
+		writer = zapcore.AddSync(os.Stderr)
 	} else {
 		writer = zapcore.AddSync(os.Stdout)
 	}
@@ -0,0 +1,272 @@
+package netplan
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/pkg/hostexec"
+	"github.com/ibyeong-geon/multinic-agent/pkg/inventory"
+)
+
+// Apply methods, in the order ApplyNetplan tries them
+const (
+	ApplyMethodNetplanApply  = "netplan-apply"
+	ApplyMethodSystemdRun    = "systemd-run"
+	ApplyMethodNetworkdLoad  = "generate+networkctl-reload"
+	ApplyMethodNetworkdStart = "generate+restart-networkd"
+	// ApplyMethodGenerate only rendered the backend configuration
+	ApplyMethodGenerate = "generate"
+	// ApplyMethodSkipped means no method could run (non-privileged container)
+	ApplyMethodSkipped = "skipped"
+	ApplyMethodDryRun  = "dry-run"
+)
+
+const (
+	// applyVerifyTimeout bounds how long the applied state is polled for,
+	// covering devices that take a moment to come up after the apply
+	applyVerifyTimeout  = 15 * time.Second
+	applyVerifyInterval = time.Second
+	// applyUnitTimeout bounds the wait for a netplan apply queued with
+	// systemd-run --no-block to finish
+	applyUnitTimeout = 2 * time.Minute
+	// maxAttemptOutput caps the stdout/stderr kept per attempt
+	maxAttemptOutput = 4096
+)
+
+// ApplyResult records how a configuration was applied and whether it was
+// confirmed active on the host
+type ApplyResult struct {
+	// Method is the method that completed, or the last one tried on failure
+	Method   string
+	Attempts []ApplyAttempt
+	Duration time.Duration
+	// Confirmed is true once the host state matches the configuration
+	Confirmed bool
+	// Unconfirmed lists what did not match when Confirmed is false
+	Unconfirmed []string
+	// Reverted is set when safe apply restored the previous configuration
+	Reverted bool
+}
+
+// ApplyAttempt is one command run while applying
+type ApplyAttempt struct {
+	Method   string        `json:"method"`
+	Command  []string      `json:"command"`
+	ExitCode int           `json:"exit_code"`
+	Stdout   string        `json:"stdout,omitempty"`
+	Stderr   string        `json:"stderr,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+	Error    string        `json:"error,omitempty"`
+
+	err error
+}
+
+// Succeeded reports whether the attempt's command exited cleanly
+func (a ApplyAttempt) Succeeded() bool {
+	return a.Error == ""
+}
+
+// run executes one command on the host and records it as an attempt
+func (r *ApplyResult) run(ctx context.Context, executor hostexec.HostExecutor, method string, name string, args ...string) ApplyAttempt {
+	result, err := executor.Run(ctx, name, args...)
+
+	attempt := ApplyAttempt{
+		Method:   method,
+		Command:  result.Command,
+		ExitCode: result.ExitCode,
+		Stdout:   truncateOutput(result.Stdout),
+		Stderr:   truncateOutput(result.Stderr),
+		Duration: result.Duration,
+		err:      err,
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	r.Method = method
+	r.Attempts = append(r.Attempts, attempt)
+	return attempt
+}
+
+// LastAttempt returns the most recent attempt, if any
+func (r *ApplyResult) LastAttempt() (ApplyAttempt, bool) {
+	if len(r.Attempts) == 0 {
+		return ApplyAttempt{}, false
+	}
+	return r.Attempts[len(r.Attempts)-1], true
+}
+
+func truncateOutput(output string) string {
+	if len(output) > maxAttemptOutput {
+		return output[:maxAttemptOutput] + "...(truncated)"
+	}
+	return output
+}
+
+// confirmApplied polls the host inventory until it matches the configuration
+// or applyVerifyTimeout passes, and records the outcome on the result
+func (nm *NetplanManager) confirmApplied(ctx context.Context, config *NetplanConfig, result *ApplyResult) {
+	deadline := time.Now().Add(applyVerifyTimeout)
+	for {
+		links, err := nm.inventory.Links()
+		if err != nil {
+			result.Unconfirmed = []string{fmt.Sprintf("failed to read host interfaces: %v", err)}
+		} else {
+			result.Unconfirmed = unappliedDevices(config, links)
+		}
+
+		if len(result.Unconfirmed) == 0 {
+			result.Confirmed = true
+			nm.logger.Info("Confirmed netplan configuration is active",
+				zap.String("method", result.Method))
+			return
+		}
+		if time.Now().After(deadline) {
+			nm.logger.Warn("Netplan configuration not confirmed active",
+				zap.String("method", result.Method),
+				zap.Strings("unconfirmed", result.Unconfirmed))
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(applyVerifyInterval):
+		}
+	}
+}
+
+// waitForUnit polls a oneshot unit queued with systemd-run --no-block until
+// it finishes, then unloads it. The unit keeps its state after exiting
+// (RemainAfterExit), so "active" means the apply completed.
+func (nm *NetplanManager) waitForUnit(ctx context.Context, unit string) error {
+	deadline := time.Now().Add(applyUnitTimeout)
+	for {
+		result, err := nm.exec.Run(ctx, "systemctl", "show", "--property=ActiveState", "--value", unit)
+		if err != nil {
+			return fmt.Errorf("failed to read state of %s: %w", unit, err)
+		}
+
+		switch state := strings.TrimSpace(result.Stdout); state {
+		case "active":
+			nm.logger.Info("Netplan apply unit finished", zap.String("unit", unit))
+			if _, err := nm.exec.Run(ctx, "systemctl", "stop", unit); err != nil {
+				nm.logger.Warn("Failed to unload netplan apply unit", zap.String("unit", unit), zap.Error(err))
+			}
+			return nil
+		case "failed":
+			if _, err := nm.exec.Run(ctx, "systemctl", "reset-failed", unit); err != nil {
+				nm.logger.Warn("Failed to unload netplan apply unit", zap.String("unit", unit), zap.Error(err))
+			}
+			return fmt.Errorf("%s failed, see journalctl -u %s", unit, unit)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s did not finish within %s", unit, applyUnitTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(applyVerifyInterval):
+		}
+	}
+}
+
+// unappliedDevices compares the configuration with the host links: every
+// device must exist, carry its static addresses and be enslaved to its bond
+// or bridge. DHCP addresses are not checked.
+func unappliedDevices(config *NetplanConfig, links []inventory.Link) []string {
+	var problems []string
+
+	masters := make(map[string]string)
+	for name, bond := range config.Network.Bonds {
+		for _, member := range bond.Interfaces {
+			masters[member] = name
+		}
+	}
+	for name, bridge := range config.Network.Bridges {
+		for _, member := range bridge.Interfaces {
+			masters[member] = name
+		}
+	}
+
+	check := func(name string, link inventory.Link, found bool, addresses AddressList) {
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: not present", name))
+			return
+		}
+		have := addressIPs(link.Addresses)
+		for _, ip := range addressIPs(addresses.Strings()) {
+			if !slices.Contains(have, ip) {
+				problems = append(problems, fmt.Sprintf("%s: missing address %s", name, ip))
+			}
+		}
+		if master, ok := masters[name]; ok && link.Master != master {
+			problems = append(problems, fmt.Sprintf("%s: not enslaved to %s", name, master))
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(config.Network.Ethernets)) {
+		ethernet := config.Network.Ethernets[name]
+		var link inventory.Link
+		var found bool
+		if ethernet.Match != nil && ethernet.Match.MACAddress != "" {
+			// Bonds and bridges share a member's MAC, so only devices match
+			link, found = inventory.FindByMAC(physicalLinks(links), ethernet.Match.MACAddress)
+		} else {
+			link, found = findLinkByName(links, name)
+		}
+		check(name, link, found, ethernet.Addresses)
+	}
+	for _, name := range slices.Sorted(maps.Keys(config.Network.VLANs)) {
+		link, found := findLinkByName(links, name)
+		check(name, link, found, config.Network.VLANs[name].Addresses)
+	}
+	for _, name := range slices.Sorted(maps.Keys(config.Network.Bonds)) {
+		link, found := findLinkByName(links, name)
+		check(name, link, found, config.Network.Bonds[name].Addresses)
+	}
+	for _, name := range slices.Sorted(maps.Keys(config.Network.Bridges)) {
+		link, found := findLinkByName(links, name)
+		check(name, link, found, config.Network.Bridges[name].Addresses)
+	}
+
+	return problems
+}
+
+func physicalLinks(links []inventory.Link) []inventory.Link {
+	var physical []inventory.Link
+	for _, link := range links {
+		if link.Physical {
+			physical = append(physical, link)
+		}
+	}
+	return physical
+}
+
+func findLinkByName(links []inventory.Link, name string) (inventory.Link, bool) {
+	for _, link := range links {
+		if link.Name == name {
+			return link, true
+		}
+	}
+	return inventory.Link{}, false
+}
+
+// describeAttempts summarizes the attempts for error messages
+func describeAttempts(result *ApplyResult) string {
+	descriptions := make([]string, 0, len(result.Attempts))
+	for _, attempt := range result.Attempts {
+		status := "ok"
+		if !attempt.Succeeded() {
+			status = fmt.Sprintf("exit %d", attempt.ExitCode)
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%s (%s)", attempt.Method, status))
+	}
+	return strings.Join(descriptions, ", ")
+}
@@ -0,0 +1,242 @@
+package netplan
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+	"github.com/ibyeong-geon/multinic-agent/pkg/hostexec"
+	"github.com/ibyeong-geon/multinic-agent/pkg/inventory"
+)
+
+// failing is the result of a command that exited with status 1
+var failing = hostexec.Result{ExitCode: 1, Stderr: "failed"}
+
+func newApplyManager(cfg *config.NetplanConfig, executor hostexec.HostExecutor) *NetplanManager {
+	return NewNetplanManager(cfg, nil, nil, nil, executor, zap.NewNop())
+}
+
+// applyUnit matches the per-apply systemd-run unit name
+var applyUnit = regexp.MustCompile(`multinic-netplan-apply-\d+`)
+
+// commandLines joins each recorded command for comparison, with the
+// systemd-run unit name made stable
+func commandLines(calls [][]string) []string {
+	lines := make([]string, 0, len(calls))
+	for _, call := range calls {
+		lines = append(lines, applyUnit.ReplaceAllString(strings.Join(call, " "), "multinic-netplan-apply-N"))
+	}
+	return lines
+}
+
+const (
+	queuedApply = "systemd-run --no-block --unit=multinic-netplan-apply-N.service " +
+		"--property=Type=oneshot --property=RemainAfterExit=yes netplan apply"
+	unitState = "systemctl show --property=ActiveState --value multinic-netplan-apply-N.service"
+)
+
+func TestApplyNetplan(t *testing.T) {
+	tests := []struct {
+		name       string
+		dryRun     bool
+		unitState  string
+		failing    []string
+		noAccess   bool
+		wantMethod string
+		wantErr    bool
+		wantCalls  []string
+	}{
+		{
+			name:       "netplan apply",
+			wantMethod: ApplyMethodNetplanApply,
+			wantCalls:  []string{"netplan apply"},
+		},
+		{
+			name:       "falls back to systemd-run",
+			failing:    []string{"netplan apply"},
+			wantMethod: ApplyMethodSystemdRun,
+			unitState:  "active",
+			wantCalls: []string{"netplan apply", queuedApply, unitState,
+				"systemctl stop multinic-netplan-apply-N.service"},
+		},
+		{
+			name:       "systemd-run unit fails",
+			failing:    []string{"netplan apply"},
+			unitState:  "failed",
+			wantMethod: ApplyMethodSystemdRun,
+			wantErr:    true,
+			wantCalls: []string{"netplan apply", queuedApply, unitState,
+				"systemctl reset-failed multinic-netplan-apply-N.service"},
+		},
+		{
+			name:       "falls back to networkctl reload",
+			failing:    []string{"netplan apply", "systemd-run"},
+			wantMethod: ApplyMethodNetworkdLoad,
+			wantCalls: []string{"netplan apply", queuedApply,
+				"netplan generate", "networkctl reload"},
+		},
+		{
+			name:       "falls back to restarting networkd",
+			failing:    []string{"netplan apply", "systemd-run", "networkctl"},
+			wantMethod: ApplyMethodNetworkdStart,
+			wantCalls: []string{"netplan apply", queuedApply,
+				"netplan generate", "networkctl reload", "systemctl restart systemd-networkd"},
+		},
+		{
+			name:       "every method fails",
+			failing:    []string{"netplan apply", "systemd-run", "networkctl", "systemctl"},
+			wantMethod: ApplyMethodNetworkdStart,
+			wantErr:    true,
+			wantCalls: []string{"netplan apply", queuedApply,
+				"netplan generate", "networkctl reload", "systemctl restart systemd-networkd"},
+		},
+		{
+			name:       "generate fails after apply methods",
+			failing:    []string{"netplan apply", "systemd-run", "netplan generate"},
+			wantMethod: ApplyMethodGenerate,
+			wantErr:    true,
+			wantCalls:  []string{"netplan apply", queuedApply, "netplan generate"},
+		},
+		{
+			name:       "no host access",
+			noAccess:   true,
+			wantMethod: ApplyMethodSkipped,
+			wantCalls:  []string{"netplan apply"},
+		},
+		{
+			name:       "dry run",
+			dryRun:     true,
+			wantMethod: ApplyMethodDryRun,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &hostexec.Fake{}
+			executor.On("systemctl show", hostexec.Result{Stdout: tt.unitState + "\n"}, nil)
+			for _, prefix := range tt.failing {
+				executor.On(prefix, failing, errors.New("exit status 1"))
+			}
+			if tt.noAccess {
+				executor.On("", hostexec.Result{}, hostexec.ErrNoHostAccess)
+			}
+			nm := newApplyManager(&config.NetplanConfig{DryRun: tt.dryRun}, executor)
+
+			result, err := nm.ApplyNetplan(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyNetplan error = %v, want error %v", err, tt.wantErr)
+			}
+			if result.Method != tt.wantMethod {
+				t.Errorf("method = %q, want %q", result.Method, tt.wantMethod)
+			}
+			if got := commandLines(executor.Calls()); !slices.Equal(got, tt.wantCalls) {
+				t.Errorf("commands = %q, want %q", got, tt.wantCalls)
+			}
+			for _, attempt := range result.Attempts[:max(len(result.Attempts)-1, 0)] {
+				if attempt.Succeeded() && attempt.Method != ApplyMethodGenerate {
+					t.Errorf("fell back after %s succeeded", attempt.Method)
+				}
+			}
+		})
+	}
+}
+
+// sequenceExecutor answers the unit state query from a list, one per call
+type sequenceExecutor struct {
+	*hostexec.Fake
+	states []string
+}
+
+func (e *sequenceExecutor) Run(ctx context.Context, name string, args ...string) (*hostexec.Result, error) {
+	result, err := e.Fake.Run(ctx, name, args...)
+	if name == "systemctl" && args[0] == "show" && len(e.states) > 0 {
+		result.Stdout, e.states = e.states[0]+"\n", e.states[1:]
+	}
+	return result, err
+}
+
+// A systemd-run apply only completes once its unit has finished
+func TestApplyNetplanWaitsForQueuedUnit(t *testing.T) {
+	executor := &sequenceExecutor{Fake: &hostexec.Fake{}, states: []string{"activating", "active"}}
+	executor.On("netplan apply", failing, errors.New("exit status 1"))
+	nm := newApplyManager(&config.NetplanConfig{}, executor)
+
+	result, err := nm.ApplyNetplan(context.Background())
+	if err != nil {
+		t.Fatalf("ApplyNetplan: %v", err)
+	}
+	if result.Method != ApplyMethodSystemdRun {
+		t.Errorf("method = %q, want %q", result.Method, ApplyMethodSystemdRun)
+	}
+	want := []string{"netplan apply", queuedApply, unitState, unitState,
+		"systemctl stop multinic-netplan-apply-N.service"}
+	if got := commandLines(executor.Calls()); !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestApplyNetplanStopsWithContext(t *testing.T) {
+	executor := &hostexec.Fake{}
+	nm := newApplyManager(&config.NetplanConfig{}, executor)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := nm.ApplyNetplan(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ApplyNetplan error = %v, want context.Canceled", err)
+	}
+	if calls := executor.Calls(); len(calls) != 0 {
+		t.Errorf("ran %q after the context was cancelled", commandLines(calls))
+	}
+}
+
+func TestValidateNetplan(t *testing.T) {
+	tests := []struct {
+		name    string
+		dryRun  bool
+		result  hostexec.Result
+		err     error
+		wantErr bool
+		wantRun bool
+	}{
+		{name: "valid", wantRun: true},
+		{name: "invalid", result: failing, err: errors.New("exit status 1"), wantErr: true, wantRun: true},
+		{name: "no host access", err: hostexec.ErrNoHostAccess, wantRun: true},
+		{name: "dry run", dryRun: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &hostexec.Fake{}
+			executor.On("netplan generate", tt.result, tt.err)
+			nm := newApplyManager(&config.NetplanConfig{DryRun: tt.dryRun}, executor)
+
+			if err := nm.ValidateNetplan(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateNetplan error = %v, want error %v", err, tt.wantErr)
+			}
+			if ran := len(executor.Calls()) > 0; ran != tt.wantRun {
+				t.Errorf("ran netplan generate = %v, want %v", ran, tt.wantRun)
+			}
+		})
+	}
+}
+
+// ProcessInterfaces validates the written file once before applying it
+func TestProcessInterfacesValidatesOnce(t *testing.T) {
+	executor := &hostexec.Fake{}
+	links := &inventory.Fake{LinkList: []inventory.Link{{Name: "ens4", MACAddress: "aa:00:00:00:00:01", Physical: true}}}
+	cfg := &config.NetplanConfig{ConfigPath: t.TempDir(), BackupPath: t.TempDir()}
+	nm := NewNetplanManager(cfg, nil, links, nil, executor, zap.NewNop())
+
+	interfaces := []InterfaceData{{PortID: "p1", MACAddress: "aa:00:00:00:00:01"}}
+	if _, err := nm.ProcessInterfaces(context.Background(), "node-a", interfaces); err != nil {
+		t.Fatalf("ProcessInterfaces: %v", err)
+	}
+	want := []string{"netplan generate", "netplan apply"}
+	if got := commandLines(executor.Calls()); !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+package netplan
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// writeFileAtomic replaces path with data so that readers (and the host after
+// a crash) see either the old or the new content, never a partial file: the
+// data goes to a temporary file in the same directory, is synced, renamed
+// over path, and the directory entry is synced. An existing file's owner is
+// kept; perm is applied to the new file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		if err := chownLike(tmp, info); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// chownLike gives file the owner and group of info when they differ from the
+// agent's, which only succeeds with CAP_CHOWN
+func chownLike(file *os.File, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if int(stat.Uid) == os.Geteuid() && int(stat.Gid) == os.Getegid() {
+		return nil
+	}
+	return file.Chown(int(stat.Uid), int(stat.Gid))
+}
+
+// syncDir flushes a directory so a rename within it is durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// copyFile copies src to dst atomically, keeping the permissions and owner
+// of src so backups of root-only files stay root-only
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(dst, data, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	file, err := os.Open(dst)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return chownLike(file, info)
+}
+
+// verifyNetplanFile checks that the file holds exactly the intended YAML and
+// that it parses back to the same configuration
+func verifyNetplanFile(path string, want []byte) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(data, want) {
+		return fmt.Errorf("content of %s differs from what was written", path)
+	}
+
+	parsed, err := ParseNetplanConfig(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	reencoded, err := parsed.Marshal()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(reencoded, want) {
+		return fmt.Errorf("%s does not parse back to the intended configuration", path)
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+package netplan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+)
+
+// backupIndexFile holds the metadata of every backup in the backup directory
+const backupIndexFile = "index.json"
+
+// Backup reasons
+const (
+	// BackupReasonUpdate precedes the agent replacing its own netplan file
+	BackupReasonUpdate = "update"
+	// BackupReasonAdopt precedes removing adopted definitions from another file
+	BackupReasonAdopt = "adopt"
+	// BackupReasonRestore precedes restoring an older backup over a file
+	BackupReasonRestore = "restore"
+	// BackupReasonUnindexed marks backup files found without index metadata,
+	// such as those written before the index existed
+	BackupReasonUnindexed = "unindexed"
+)
+
+// BackupEntry is the index metadata of one backup
+type BackupEntry struct {
+	ID string `json:"id"`
+	// File is the backup's file name within the backup directory
+	File string `json:"file"`
+	// Source is the path the backup was taken from and restores to
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+	SHA256    string    `json:"sha256"`
+	Size      int64     `json:"size"`
+	Reason    string    `json:"reason"`
+	// Revision identifies the database state the replacing configuration
+	// was generated from
+	Revision string `json:"revision,omitempty"`
+}
+
+// BackupRetention limits the backups kept. Zero values disable a limit; the
+// newest backup of each source is always kept.
+type BackupRetention struct {
+	// MaxCount is the number of backups kept per source file
+	MaxCount int
+	// MaxAge is how long backups are kept
+	MaxAge time.Duration
+	// MaxBytes bounds the total size of all backups
+	MaxBytes int64
+}
+
+// BackupManager stores netplan file backups with an index and prunes them
+// according to the retention policy
+type BackupManager struct {
+	dir string
+	// sourceDir is assumed for unindexed backups, which were only taken of
+	// files in the netplan directory
+	sourceDir string
+	retention BackupRetention
+	logger    *zap.Logger
+	mu        sync.Mutex
+}
+
+// NewBackupManager creates a BackupManager for dir, holding backups of files
+// in sourceDir
+func NewBackupManager(dir, sourceDir string, retention BackupRetention, logger *zap.Logger) *BackupManager {
+	return &BackupManager{
+		dir:       dir,
+		sourceDir: sourceDir,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// BackupManagerFromConfig creates the BackupManager for the netplan
+// configuration's backup directory and retention settings
+func BackupManagerFromConfig(cfg *config.NetplanConfig, logger *zap.Logger) *BackupManager {
+	retention := BackupRetention{
+		MaxCount: cfg.BackupMaxCount,
+		MaxAge:   time.Duration(cfg.BackupMaxAgeDays) * 24 * time.Hour,
+		MaxBytes: int64(cfg.BackupMaxSizeMB) * 1024 * 1024,
+	}
+	return NewBackupManager(cfg.BackupPath, cfg.ConfigPath, retention, logger)
+}
+
+// Dir returns the backup directory
+func (bm *BackupManager) Dir() string {
+	return bm.dir
+}
+
+// Create backs up source and prunes old backups. The entry is nil without
+// error when source does not exist.
+func (bm *BackupManager) Create(source, reason, revision string) (*BackupEntry, error) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	data, err := os.ReadFile(source)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Backups hold addresses and routes, so the directory is root-only
+	if err := os.MkdirAll(bm.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	entries, err := bm.load()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	id := strconv.FormatInt(now.UnixNano(), 10)
+	sum := sha256.Sum256(data)
+	entry := BackupEntry{
+		ID:        id,
+		File:      fmt.Sprintf("%s.%s", filepath.Base(source), id),
+		Source:    source,
+		CreatedAt: now.UTC(),
+		SHA256:    hex.EncodeToString(sum[:]),
+		Size:      int64(len(data)),
+		Reason:    reason,
+		Revision:  revision,
+	}
+	if err := copyFile(source, filepath.Join(bm.dir, entry.File)); err != nil {
+		return nil, err
+	}
+
+	entries = append(entries, entry)
+	entries = bm.prune(entries, now)
+	if err := bm.save(entries); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// List returns the backups, newest first
+func (bm *BackupManager) List() ([]BackupEntry, error) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	entries, err := bm.load()
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(entries)
+	return entries, nil
+}
+
+// Get returns the backup with the given ID
+func (bm *BackupManager) Get(id string) (*BackupEntry, error) {
+	entries, err := bm.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.ID == id {
+			return &entry, nil
+		}
+	}
+	return nil, fmt.Errorf("backup %s not found", id)
+}
+
+// Read returns the content of a backup, checking it against the recorded hash
+func (bm *BackupManager) Read(entry *BackupEntry) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(bm.dir, entry.File))
+	if err != nil {
+		return nil, err
+	}
+	if entry.SHA256 != "" {
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != entry.SHA256 {
+			return nil, fmt.Errorf("backup %s does not match its recorded hash", entry.ID)
+		}
+	}
+	return data, nil
+}
+
+// Restore writes the backup over its source file, backing up the current
+// content first. The restored configuration still has to be applied.
+func (bm *BackupManager) Restore(id string) (*BackupEntry, error) {
+	entry, err := bm.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Source == "" {
+		return nil, fmt.Errorf("backup %s has no recorded source file", id)
+	}
+	data, err := bm.Read(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := bm.Create(entry.Source, BackupReasonRestore, ""); err != nil {
+		return nil, fmt.Errorf("failed to back up %s before restore: %w", entry.Source, err)
+	}
+
+	if err := writeFileAtomic(entry.Source, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to restore %s: %w", entry.Source, err)
+	}
+
+	bm.logger.Info("Restored netplan backup",
+		zap.String("id", entry.ID),
+		zap.String("file", entry.Source))
+	return entry, nil
+}
+
+// load reads the index, oldest first, dropping entries whose file is gone
+// and adding backup files the index does not know
+func (bm *BackupManager) load() ([]BackupEntry, error) {
+	var entries []BackupEntry
+	data, err := os.ReadFile(filepath.Join(bm.dir, backupIndexFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse backup index: %w", err)
+		}
+	}
+
+	files, err := os.ReadDir(bm.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	present := make(map[string]os.DirEntry)
+	for _, file := range files {
+		if !file.Type().IsRegular() || file.Name() == backupIndexFile || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		present[file.Name()] = file
+	}
+
+	var loaded []BackupEntry
+	indexed := make(map[string]bool)
+	for _, entry := range entries {
+		if _, ok := present[entry.File]; ok {
+			loaded = append(loaded, entry)
+			indexed[entry.File] = true
+		}
+	}
+	for name, file := range present {
+		if !indexed[name] {
+			loaded = append(loaded, bm.unindexedEntry(name, file))
+		}
+	}
+
+	slices.SortStableFunc(loaded, func(a, b BackupEntry) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return loaded, nil
+}
+
+// unindexedEntry describes a "<file>.<timestamp>" backup from its name
+func (bm *BackupManager) unindexedEntry(name string, file os.DirEntry) BackupEntry {
+	entry := BackupEntry{ID: name, File: name, Reason: BackupReasonUnindexed}
+	if info, err := file.Info(); err == nil {
+		entry.Size = info.Size()
+		entry.CreatedAt = info.ModTime().UTC()
+	}
+
+	if i := strings.LastIndex(name, "."); i > 0 {
+		if ts, err := strconv.ParseInt(name[i+1:], 10, 64); err == nil {
+			entry.CreatedAt = time.Unix(ts, 0).UTC()
+			entry.Source = filepath.Join(bm.sourceDir, name[:i])
+		}
+	}
+	return entry
+}
+
+func (bm *BackupManager) save(entries []BackupEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(bm.dir, backupIndexFile), data, 0600)
+}
+
+// prune removes backups beyond the retention limits from disk and returns
+// the remaining entries, oldest first
+func (bm *BackupManager) prune(entries []BackupEntry, now time.Time) []BackupEntry {
+	// The newest backup of each source survives every limit
+	newest := make(map[string]int)
+	for i, entry := range entries {
+		newest[entry.Source] = i
+	}
+
+	remove := make([]bool, len(entries))
+	count := make(map[string]int)
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if newest[entry.Source] == i {
+			count[entry.Source]++
+			continue
+		}
+		if bm.retention.MaxAge > 0 && now.Sub(entry.CreatedAt) > bm.retention.MaxAge {
+			remove[i] = true
+			continue
+		}
+		if bm.retention.MaxCount > 0 && count[entry.Source] >= bm.retention.MaxCount {
+			remove[i] = true
+			continue
+		}
+		count[entry.Source]++
+	}
+
+	if bm.retention.MaxBytes > 0 {
+		var total int64
+		for i, entry := range entries {
+			if !remove[i] {
+				total += entry.Size
+			}
+		}
+		for i := 0; i < len(entries) && total > bm.retention.MaxBytes; i++ {
+			if !remove[i] && newest[entries[i].Source] != i {
+				remove[i] = true
+				total -= entries[i].Size
+			}
+		}
+	}
+
+	var kept []BackupEntry
+	for i, entry := range entries {
+		if !remove[i] {
+			kept = append(kept, entry)
+			continue
+		}
+		if err := os.Remove(filepath.Join(bm.dir, entry.File)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			bm.logger.Warn("Failed to remove old netplan backup",
+				zap.String("file", entry.File),
+				zap.Error(err))
+			kept = append(kept, entry)
+			continue
+		}
+		bm.logger.Debug("Removed old netplan backup",
+			zap.String("file", entry.File),
+			zap.String("reason", entry.Reason))
+	}
+	return kept
+}
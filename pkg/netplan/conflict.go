@@ -0,0 +1,419 @@
+package netplan
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// Conflict policies for netplan files that configure the same interfaces
+const (
+	// ConflictPolicyRefuse leaves other files untouched and does not apply
+	ConflictPolicyRefuse = "refuse"
+	// ConflictPolicyAdopt removes the conflicting definitions from the other
+	// file (after a backup) so the agent's file owns the interface
+	ConflictPolicyAdopt = "adopt"
+)
+
+// Conflict kinds
+const (
+	ConflictID      = "id"
+	ConflictName    = "name"
+	ConflictMAC     = "mac"
+	ConflictAddress = "address"
+	// ConflictUnparsed is a file that could not be read or parsed, so it
+	// may configure any interface
+	ConflictUnparsed = "unparsed"
+)
+
+// Conflict is a definition in another netplan file that overlaps with an
+// interface the agent configures
+type Conflict struct {
+	File string
+	// Section and Device identify the definition in the other file
+	Section string
+	Device  string
+	// Interface is the agent's interface it overlaps with
+	Interface string
+	Kind      string
+	Value     string
+}
+
+func (c Conflict) String() string {
+	if c.Kind == ConflictUnparsed {
+		return fmt.Sprintf("%s: cannot be checked: %s", filepath.Base(c.File), c.Value)
+	}
+	return fmt.Sprintf("%s: %s.%s overlaps %s by %s %s",
+		filepath.Base(c.File), c.Section, c.Device, c.Interface, c.Kind, c.Value)
+}
+
+// ConflictError is returned when other netplan files conflict with the
+// agent's configuration and the policy does not allow taking them over
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	descriptions := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		descriptions = append(descriptions, c.String())
+	}
+	return fmt.Sprintf("conflicting netplan definitions: %s", strings.Join(descriptions, "; "))
+}
+
+// foreignDevice holds the fields of another file's device definition that
+// identify the interface it applies to
+type foreignDevice struct {
+	Match     MatchConfig `yaml:"match"`
+	SetName   string      `yaml:"set-name"`
+	Addresses AddressList `yaml:"addresses"`
+}
+
+// ownedDevice is an interface in the agent's configuration
+type ownedDevice struct {
+	id        string
+	name      string
+	mac       string
+	addresses []string
+	physical  bool
+}
+
+// resolveConflicts checks the other netplan files in the config directory
+// against the generated configuration. With the adopt policy, definitions
+// that match an agent interface by ID, name or MAC are removed from the other
+// file; address overlaps on other interfaces and files that cannot be parsed
+// are always refused.
+func (nm *NetplanManager) resolveConflicts(nodeName string, config *NetplanConfig) error {
+	conflicts, err := nm.detectConflicts(nodeName, config)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	for _, c := range conflicts {
+		if c.Kind == ConflictUnparsed {
+			nm.logger.Warn("Failed to parse netplan file - refusing to apply",
+				zap.String("file", c.File),
+				zap.String("error", c.Value))
+			continue
+		}
+		nm.logger.Warn("Netplan definition conflicts with agent interface",
+			zap.String("file", c.File),
+			zap.String("device", c.Section+"."+c.Device),
+			zap.String("interface", c.Interface),
+			zap.String("kind", c.Kind),
+			zap.String("value", c.Value))
+	}
+
+	if nm.conflictPolicy != ConflictPolicyAdopt {
+		return &ConflictError{Conflicts: conflicts}
+	}
+
+	// Group adoptable definitions per file; address-only overlaps and
+	// unparsed files stay
+	adopt := make(map[string][]Conflict)
+	var remaining []Conflict
+	for _, c := range conflicts {
+		if c.Kind == ConflictAddress || c.Kind == ConflictUnparsed {
+			remaining = append(remaining, c)
+			continue
+		}
+		adopt[c.File] = append(adopt[c.File], c)
+	}
+
+	// A device removed for another reason no longer overlaps by address
+	remaining = slices.DeleteFunc(remaining, func(c Conflict) bool {
+		return slices.ContainsFunc(adopt[c.File], func(a Conflict) bool {
+			return a.Section == c.Section && a.Device == c.Device
+		})
+	})
+
+	// Leave every file untouched unless all conflicts can be adopted
+	if len(remaining) > 0 {
+		return &ConflictError{Conflicts: remaining}
+	}
+
+	for _, file := range slices.Sorted(maps.Keys(adopt)) {
+		if err := nm.adoptDefinitions(file, adopt[file]); err != nil {
+			return fmt.Errorf("failed to adopt definitions from %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+// detectConflicts parses every other netplan file in the config directory
+// and reports definitions that overlap with the generated configuration. A
+// file that cannot be parsed is reported as a conflict of its own: netplan
+// may still read it, and it could configure the agent's interfaces.
+func (nm *NetplanManager) detectConflicts(nodeName string, config *NetplanConfig) ([]Conflict, error) {
+	files, err := filepath.Glob(filepath.Join(nm.netplanDir, "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list netplan files: %w", err)
+	}
+
+	owned := ownedDevices(config)
+	ownFile := fmt.Sprintf("99-multinic-%s.yaml", nodeName)
+
+	var conflicts []Conflict
+	for _, file := range files {
+		if filepath.Base(file) == ownFile {
+			continue
+		}
+
+		sections, err := readForeignFile(file)
+		if err != nil {
+			conflicts = append(conflicts, Conflict{File: file, Kind: ConflictUnparsed, Value: err.Error()})
+			continue
+		}
+
+		for _, section := range slices.Sorted(maps.Keys(sections)) {
+			devices := sections[section]
+			for _, id := range slices.Sorted(maps.Keys(devices)) {
+				for _, c := range deviceConflicts(id, devices[id], owned) {
+					c.File = file
+					c.Section = section
+					conflicts = append(conflicts, c)
+				}
+			}
+		}
+	}
+
+	return conflicts, nil
+}
+
+// deviceConflicts compares one foreign definition against the agent's
+// interfaces and reports at most one conflict per interface
+func deviceConflicts(id string, device foreignDevice, owned []ownedDevice) []Conflict {
+	name := device.SetName
+	if name == "" && device.Match.MACAddress == "" && device.Match.Name == "" {
+		name = id
+	}
+	mac := strings.ToLower(device.Match.MACAddress)
+	addresses := addressIPs(device.Addresses.Strings())
+
+	var conflicts []Conflict
+	for _, o := range owned {
+		// Netplan merges definitions with the same ID across files
+		if id == o.id {
+			conflicts = append(conflicts, Conflict{Device: id, Interface: o.name, Kind: ConflictID, Value: id})
+			continue
+		}
+		if name != "" && name == o.name {
+			conflicts = append(conflicts, Conflict{Device: id, Interface: o.name, Kind: ConflictName, Value: name})
+			continue
+		}
+		// match rules only select physical devices
+		if device.Match.Name != "" && o.physical {
+			if matched, _ := filepath.Match(device.Match.Name, o.name); matched {
+				conflicts = append(conflicts, Conflict{Device: id, Interface: o.name, Kind: ConflictName, Value: device.Match.Name})
+				continue
+			}
+		}
+		if mac != "" && o.physical && mac == o.mac {
+			conflicts = append(conflicts, Conflict{Device: id, Interface: o.name, Kind: ConflictMAC, Value: mac})
+			continue
+		}
+		for _, address := range addresses {
+			if slices.Contains(o.addresses, address) {
+				conflicts = append(conflicts, Conflict{Device: id, Interface: o.name, Kind: ConflictAddress, Value: address})
+				break
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// adoptDefinitions backs up the file and removes the conflicting device
+// definitions from it, keeping everything else (including comments)
+func (nm *NetplanManager) adoptDefinitions(file string, conflicts []Conflict) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	network := mappingValue(documentRoot(&doc), "network")
+	var removed []string
+	for _, c := range conflicts {
+		section := mappingValue(network, c.Section)
+		if removeMappingKey(section, c.Device) {
+			removed = append(removed, c.Section+"."+c.Device)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	if nm.dryRun {
+		nm.logger.Info("DRY RUN: Would adopt netplan definitions",
+			zap.String("file", file),
+			zap.Strings("devices", removed))
+		return nil
+	}
+
+	backup, err := nm.backups.Create(file, BackupReasonAdopt, "")
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", file, err)
+	}
+	if backup == nil {
+		return fmt.Errorf("%s disappeared before it could be backed up", file)
+	}
+
+	updated, err := yaml.Marshal(&doc)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(file, updated, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	nm.logger.Info("Adopted netplan definitions from other file",
+		zap.String("file", file),
+		zap.Strings("devices", removed),
+		zap.String("backup", backup.ID))
+
+	return nil
+}
+
+// readForeignFile returns the device definitions of a netplan file keyed by
+// section (ethernets, vlans, ...) and device ID
+func readForeignFile(file string) (map[string]map[string]foreignDevice, error) {
+	config, err := ReadNetplanConfig(file)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := make(map[string]map[string]foreignDevice)
+	add := func(section, id string, device foreignDevice) {
+		if sections[section] == nil {
+			sections[section] = make(map[string]foreignDevice)
+		}
+		sections[section][id] = device
+	}
+	for id, iface := range config.Network.Ethernets {
+		device := foreignDevice{SetName: iface.SetName, Addresses: iface.Addresses}
+		if iface.Match != nil {
+			device.Match = *iface.Match
+		}
+		add("ethernets", id, device)
+	}
+	for id, iface := range config.Network.VLANs {
+		add("vlans", id, foreignDevice{Addresses: iface.Addresses})
+	}
+	for id, iface := range config.Network.Bonds {
+		add("bonds", id, foreignDevice{Addresses: iface.Addresses})
+	}
+	for id, iface := range config.Network.Bridges {
+		add("bridges", id, foreignDevice{Addresses: iface.Addresses})
+	}
+
+	// Sections without a typed model (wifis, tunnels, ...) can still match
+	// interfaces; version and other scalars are not device sections
+	for section, node := range config.Network.Extra {
+		if node.Kind != yaml.MappingNode {
+			continue
+		}
+		devices := make(map[string]foreignDevice)
+		if err := node.Decode(&devices); err != nil {
+			return nil, fmt.Errorf("invalid %s section: %w", section, err)
+		}
+		sections[section] = devices
+	}
+
+	return sections, nil
+}
+
+// ownedDevices lists the interfaces in the agent's configuration
+func ownedDevices(config *NetplanConfig) []ownedDevice {
+	var owned []ownedDevice
+	for id, iface := range config.Network.Ethernets {
+		device := ownedDevice{id: id, name: id, addresses: addressIPs(iface.Addresses.Strings()), physical: true}
+		if iface.SetName != "" {
+			device.name = iface.SetName
+		}
+		if iface.Match != nil {
+			device.mac = strings.ToLower(iface.Match.MACAddress)
+		}
+		owned = append(owned, device)
+	}
+	for id, iface := range config.Network.VLANs {
+		owned = append(owned, ownedDevice{id: id, name: id, addresses: addressIPs(iface.Addresses.Strings())})
+	}
+	for id, iface := range config.Network.Bonds {
+		owned = append(owned, ownedDevice{id: id, name: id, addresses: addressIPs(iface.Addresses.Strings())})
+	}
+	for id, iface := range config.Network.Bridges {
+		owned = append(owned, ownedDevice{id: id, name: id, addresses: addressIPs(iface.Addresses.Strings())})
+	}
+
+	slices.SortFunc(owned, func(a, b ownedDevice) int {
+		return strings.Compare(a.id, b.id)
+	})
+	return owned
+}
+
+// addressIPs strips the prefix length so addresses compare by IP
+func addressIPs(addresses []string) []string {
+	ips := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if ip, _, err := net.ParseCIDR(address); err == nil {
+			ips = append(ips, ip.String())
+		} else if ip := net.ParseIP(address); ip != nil {
+			ips = append(ips, ip.String())
+		}
+	}
+	return ips
+}
+
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// mappingValue returns the value node for key, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// removeMappingKey deletes key and its value from a mapping node
+func removeMappingKey(node *yaml.Node, key string) bool {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = slices.Delete(node.Content, i, i+2)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,298 @@
+package netplan
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+)
+
+func TestDeviceConflicts(t *testing.T) {
+	owned := []ownedDevice{
+		{id: "multinic0", name: "multinic0", mac: "fa:16:3e:00:00:01", addresses: []string{"10.0.0.5"}, physical: true},
+		{id: "multinic0.10", name: "multinic0.10", addresses: []string{"10.1.0.5"}},
+	}
+
+	tests := []struct {
+		name   string
+		id     string
+		device foreignDevice
+		// want lists "interface kind value"
+		want []string
+	}{
+		{name: "unrelated", id: "eth0", device: foreignDevice{Addresses: Addresses("192.168.0.5/24")}},
+		{name: "same ID", id: "multinic0.10", want: []string{"multinic0.10 id multinic0.10"}},
+		{
+			name:   "set-name",
+			id:     "uplink",
+			device: foreignDevice{Match: MatchConfig{MACAddress: "fa:16:3e:00:00:09"}, SetName: "multinic0"},
+			want:   []string{"multinic0 name multinic0"},
+		},
+		{
+			// Without match or set-name the ID is the interface name
+			name: "ID as name",
+			id:   "multinic0",
+			want: []string{"multinic0 id multinic0"},
+		},
+		{
+			name:   "match name glob",
+			id:     "all",
+			device: foreignDevice{Match: MatchConfig{Name: "multinic*"}},
+			want:   []string{"multinic0 name multinic*"},
+		},
+		{
+			name:   "MAC in another case",
+			id:     "nic",
+			device: foreignDevice{Match: MatchConfig{MACAddress: "FA:16:3E:00:00:01"}},
+			want:   []string{"multinic0 mac fa:16:3e:00:00:01"},
+		},
+		{
+			name:   "address",
+			id:     "eth0",
+			device: foreignDevice{Addresses: Addresses("192.168.0.5/24", "10.1.0.5/16")},
+			want:   []string{"multinic0.10 address 10.1.0.5"},
+		},
+		{
+			// A match rule names a physical device; the address still overlaps
+			name:   "MAC and address on different interfaces",
+			id:     "nic",
+			device: foreignDevice{Match: MatchConfig{MACAddress: "fa:16:3e:00:00:01"}, Addresses: Addresses("10.1.0.5/24")},
+			want:   []string{"multinic0 mac fa:16:3e:00:00:01", "multinic0.10 address 10.1.0.5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range deviceConflicts(tt.id, tt.device, owned) {
+				if c.Device != tt.id {
+					t.Errorf("conflict device = %q, want %q", c.Device, tt.id)
+				}
+				got = append(got, c.Interface+" "+c.Kind+" "+c.Value)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("deviceConflicts = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// agentConfig is the agent's configuration the foreign files are checked against
+func agentConfig() *NetplanConfig {
+	return &NetplanConfig{Network: NetworkConfig{
+		Version: 2,
+		Ethernets: map[string]EthernetInterface{
+			"multinic0": {
+				Match:     &MatchConfig{MACAddress: "fa:16:3e:00:00:01"},
+				SetName:   "multinic0",
+				Addresses: Addresses("10.0.0.5/24"),
+			},
+		},
+	}}
+}
+
+const (
+	cloudInit = `# written by cloud-init
+network:
+  version: 2
+  ethernets:
+    # primary interface
+    ens3:
+      dhcp4: true
+    nic1:
+      match:
+        macaddress: fa:16:3e:00:00:01
+      dhcp4: true
+`
+	addressOverlap = `network:
+  version: 2
+  ethernets:
+    ens4:
+      addresses: [10.0.0.5/24]
+`
+	ownFile     = "network:\n  version: 2\n  ethernets:\n    multinic0:\n      dhcp4: true\n"
+	unparseable = "network:\n  ethernets: [\n"
+)
+
+func TestResolveConflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		files  map[string]string
+		// wantConflicts is set when applying is refused
+		wantConflicts []string
+		// wantAdopted lists the files the agent took definitions from
+		wantAdopted []string
+	}{
+		{
+			name:  "no other files",
+			files: map[string]string{"99-multinic-node-a.yaml": ownFile},
+		},
+		{
+			name:          "refuse",
+			policy:        ConflictPolicyRefuse,
+			files:         map[string]string{"50-cloud-init.yaml": cloudInit},
+			wantConflicts: []string{"50-cloud-init.yaml: ethernets.nic1 overlaps multinic0 by mac fa:16:3e:00:00:01"},
+		},
+		{
+			name:        "adopt",
+			policy:      ConflictPolicyAdopt,
+			files:       map[string]string{"50-cloud-init.yaml": cloudInit},
+			wantAdopted: []string{"50-cloud-init.yaml"},
+		},
+		{
+			// Nothing is adopted while any conflict remains
+			name:   "adopt refuses an address overlap",
+			policy: ConflictPolicyAdopt,
+			files: map[string]string{
+				"50-cloud-init.yaml": cloudInit,
+				"60-static.yaml":     addressOverlap,
+			},
+			wantConflicts: []string{"60-static.yaml: ethernets.ens4 overlaps multinic0 by address 10.0.0.5"},
+		},
+		{
+			name:          "refuse unparseable file",
+			policy:        ConflictPolicyRefuse,
+			files:         map[string]string{"60-broken.yaml": unparseable},
+			wantConflicts: []string{"60-broken.yaml: cannot be checked"},
+		},
+		{
+			name:          "adopt refuses unparseable file",
+			policy:        ConflictPolicyAdopt,
+			files:         map[string]string{"50-cloud-init.yaml": cloudInit, "60-broken.yaml": unparseable},
+			wantConflicts: []string{"60-broken.yaml: cannot be checked"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, data := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cfg := &config.NetplanConfig{ConfigPath: dir, BackupPath: t.TempDir(), ConflictPolicy: tt.policy}
+			nm := NewNetplanManager(cfg, nil, nil, nil, nil, zap.NewNop())
+
+			err := nm.resolveConflicts("node-a", agentConfig())
+			var conflictErr *ConflictError
+			if len(tt.wantConflicts) == 0 {
+				if err != nil {
+					t.Fatalf("resolveConflicts: %v", err)
+				}
+			} else if !errors.As(err, &conflictErr) {
+				t.Fatalf("resolveConflicts error = %v, want a ConflictError", err)
+			} else {
+				var got []string
+				for _, c := range conflictErr.Conflicts {
+					got = append(got, c.String())
+				}
+				if len(got) != len(tt.wantConflicts) {
+					t.Fatalf("conflicts = %q, want %q", got, tt.wantConflicts)
+				}
+				for i := range got {
+					if !strings.HasPrefix(got[i], tt.wantConflicts[i]) {
+						t.Errorf("conflict %d = %q, want %q", i, got[i], tt.wantConflicts[i])
+					}
+				}
+			}
+
+			backups, err := nm.backups.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var adopted []string
+			for _, b := range backups {
+				adopted = append(adopted, filepath.Base(b.Source))
+			}
+			if !slices.Equal(adopted, tt.wantAdopted) {
+				t.Errorf("backed up %v, want %v", adopted, tt.wantAdopted)
+			}
+			for name, data := range tt.files {
+				current, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if changed := string(current) != data; changed != slices.Contains(tt.wantAdopted, name) {
+					t.Errorf("%s changed = %v", name, changed)
+				}
+			}
+		})
+	}
+}
+
+// Adopting removes only the conflicting definitions and keeps the rest of
+// the file, comments included
+func TestAdoptDefinitions(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		name := "apply"
+		if dryRun {
+			name = "dry run"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "50-cloud-init.yaml")
+			if err := os.WriteFile(file, []byte(cloudInit), 0o640); err != nil {
+				t.Fatal(err)
+			}
+			cfg := &config.NetplanConfig{ConfigPath: dir, BackupPath: t.TempDir(), DryRun: dryRun}
+			nm := NewNetplanManager(cfg, nil, nil, nil, nil, zap.NewNop())
+
+			conflicts := []Conflict{
+				{File: file, Section: "ethernets", Device: "nic1", Interface: "multinic0", Kind: ConflictMAC},
+				// Already gone: nothing to remove
+				{File: file, Section: "bonds", Device: "bond9", Interface: "multinic0", Kind: ConflictName},
+			}
+			if err := nm.adoptDefinitions(file, conflicts); err != nil {
+				t.Fatalf("adoptDefinitions: %v", err)
+			}
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			backups, err := nm.backups.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dryRun {
+				if string(data) != cloudInit || len(backups) != 0 {
+					t.Errorf("dry run changed the file or took %d backups:\n%s", len(backups), data)
+				}
+				return
+			}
+
+			config, err := ParseNetplanConfig(data)
+			if err != nil {
+				t.Fatalf("adopted file does not parse: %v\n%s", err, data)
+			}
+			if _, ok := config.Network.Ethernets["nic1"]; ok {
+				t.Error("nic1 is still defined")
+			}
+			if eth, ok := config.Network.Ethernets["ens3"]; !ok || eth.DHCP4 == nil || !*eth.DHCP4 {
+				t.Errorf("ens3 = %+v, want it kept with dhcp4", eth)
+			}
+			for _, comment := range []string{"# written by cloud-init", "# primary interface"} {
+				if !strings.Contains(string(data), comment) {
+					t.Errorf("comment %q was dropped:\n%s", comment, data)
+				}
+			}
+			if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0o640 {
+				t.Errorf("file mode = %v, %v, want 0640", info.Mode().Perm(), err)
+			}
+
+			if len(backups) != 1 || backups[0].Reason != BackupReasonAdopt {
+				t.Fatalf("backups = %+v, want one adopt backup", backups)
+			}
+			original, err := nm.backups.Read(&backups[0])
+			if err != nil || string(original) != cloudInit {
+				t.Errorf("backup = %q, %v, want the original file", original, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package netplan
+
+import (
+	"strings"
+
+	"github.com/ibyeong-geon/multinic-agent/pkg/database"
+)
+
+// FromNodeInterfaces converts database rows into the renderer's input. It is
+// shared by the agent and the offline tools so both render the same YAML.
+func FromNodeInterfaces(interfaces []database.NodeInterface) []InterfaceData {
+	netplanInterfaces := make([]InterfaceData, 0, len(interfaces))
+	for _, iface := range interfaces {
+		fixedIPs := make([]FixedIPData, 0, len(iface.FixedIPs))
+		for _, ip := range iface.FixedIPs {
+			fixedIPs = append(fixedIPs, FixedIPData{
+				IPAddress:     ip.IPAddress,
+				SubnetID:      ip.SubnetID,
+				CIDR:          ip.CIDR,
+				GatewayIP:     ip.GatewayIP,
+				PolicyRouting: ip.PolicyRouting,
+				RouteTable:    ip.RouteTable,
+			})
+		}
+
+		var group *GroupData
+		if iface.Group != nil {
+			group = &GroupData{
+				GroupID:            iface.Group.GroupID,
+				Name:               iface.Group.GroupName,
+				Type:               iface.Group.GroupType,
+				BondMode:           iface.Group.BondMode,
+				LACPRate:           iface.Group.LACPRate,
+				MIIMonitorInterval: iface.Group.MIIMonitorInterval,
+				PrimaryPortID:      iface.Group.PrimaryPortID,
+				STP:                iface.Group.STP,
+				MTU:                iface.Group.MTU,
+			}
+		}
+
+		var sriovData *SRIOVData
+		if iface.VNICType == "direct" || iface.PCIAddress != "" {
+			sriovData = &SRIOVData{
+				PCIAddress:   iface.PCIAddress,
+				PFPCIAddress: iface.PFPCIAddress,
+				VFCount:      iface.SRIOVVFCount,
+				SwitchMode:   iface.SRIOVSwitchMode,
+			}
+		}
+
+		netplanInterfaces = append(netplanInterfaces, InterfaceData{
+			PortID:         iface.PortID,
+			MACAddress:     iface.MacAddress,
+			SubnetName:     iface.SubnetName,
+			CIDR:           iface.CIDR,
+			NetworkID:      iface.NetworkID,
+			NetplanSuccess: iface.NetplanSuccess,
+			FixedIPs:       fixedIPs,
+			MTU:            iface.MTU,
+			ParentPortID:   iface.ParentPortID,
+			VLANID:         iface.VLANID,
+			Group:          group,
+			SRIOV:          sriovData,
+			ModifiedAt:     iface.ModifiedAt,
+			Options: SubnetOptions{
+				Optional:         iface.Optional,
+				DHCP4UseRoutes:   iface.DHCP4UseRoutes,
+				DHCP4UseDNS:      iface.DHCP4UseDNS,
+				DHCP4UseHostname: iface.DHCP4UseHostname,
+				DHCP4RouteMetric: iface.DHCP4RouteMetric,
+				LinkLocal:        ParseLinkLocal(iface.LinkLocal),
+			},
+		})
+	}
+
+	return netplanInterfaces
+}
+
+// ParseLinkLocal converts the comma-separated link_local column. NULL leaves
+// the setting unset while an empty string disables link-local addressing.
+func ParseLinkLocal(value *string) LinkLocal {
+	if value == nil {
+		return nil
+	}
+	families := LinkLocal{}
+	for _, family := range strings.Split(*value, ",") {
+		if family = strings.TrimSpace(family); family != "" {
+			families = append(families, family)
+		}
+	}
+	return families
+}
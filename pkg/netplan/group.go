@@ -0,0 +1,175 @@
+package netplan
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// Group types supported for composing multiple ports
+const (
+	GroupTypeBond   = "bond"
+	GroupTypeBridge = "bridge"
+)
+
+// GroupData represents a bond or bridge that a port belongs to
+type GroupData struct {
+	GroupID            string
+	Name               string
+	Type               string
+	BondMode           string
+	LACPRate           string
+	MIIMonitorInterval int
+	PrimaryPortID      string
+	STP                *bool
+	MTU                int
+}
+
+// groupBuilder collects members and addresses per group in port order
+type groupBuilder struct {
+	order   []string
+	groups  map[string]*GroupData
+	members map[string][]string
+	ips     map[string][]FixedIPData
+	// options are taken from the group's first member
+	options map[string]SubnetOptions
+}
+
+func newGroupBuilder() *groupBuilder {
+	return &groupBuilder{
+		groups:  make(map[string]*GroupData),
+		members: make(map[string][]string),
+		ips:     make(map[string][]FixedIPData),
+		options: make(map[string]SubnetOptions),
+	}
+}
+
+// addMember records a member interface and moves its fixed IPs to the group
+func (gb *groupBuilder) addMember(iface InterfaceData, interfaceName string) {
+	name := iface.Group.Name
+	if _, ok := gb.groups[name]; !ok {
+		gb.order = append(gb.order, name)
+		gb.groups[name] = iface.Group
+		gb.options[name] = iface.Options
+	}
+	gb.members[name] = append(gb.members[name], interfaceName)
+
+	seen := make(map[string]bool)
+	for _, ip := range gb.ips[name] {
+		seen[ip.IPAddress] = true
+	}
+	for _, ip := range iface.FixedIPs {
+		if !seen[ip.IPAddress] {
+			gb.ips[name] = append(gb.ips[name], ip)
+			seen[ip.IPAddress] = true
+		}
+	}
+}
+
+// addGroups renders the collected groups into bonds and bridges. Member
+// port IDs are mapped to the group name so VLANs on a member land on the group.
+func (nm *NetplanManager) addGroups(config *NetplanConfig, gb *groupBuilder, portInterfaces map[string]string, policies *policyTargets) error {
+	for _, name := range gb.order {
+		group := gb.groups[name]
+		members := gb.members[name]
+
+		dhcp4 := len(gb.ips[name]) == 0
+		options := nm.options.resolve(gb.options[name], dhcp4)
+		var addresses []string
+		var routes []Route
+		if !dhcp4 {
+			var err error
+			addresses, routes, err = buildStaticAddressing(gb.ips[name])
+			if err != nil {
+				return fmt.Errorf("invalid fixed ips for group %s: %w", name, err)
+			}
+		}
+
+		switch group.Type {
+		case GroupTypeBond:
+			bond := BondInterface{
+				Interfaces:     members,
+				DHCP4:          &dhcp4,
+				MTU:            mtuOrDefault(group.MTU),
+				Addresses:      Addresses(addresses...),
+				Routes:         routes,
+				Optional:       options.Optional,
+				LinkLocal:      options.LinkLocal,
+				DHCP4Overrides: options.DHCP4Overrides,
+				Parameters: &BondParameters{
+					Mode:               group.BondMode,
+					LACPRate:           group.LACPRate,
+					MIIMonitorInterval: milliseconds(group.MIIMonitorInterval),
+				},
+			}
+			if group.PrimaryPortID != "" {
+				primary, ok := portInterfaces[group.PrimaryPortID]
+				if ok && slices.Contains(members, primary) {
+					bond.Parameters.Primary = primary
+				} else {
+					nm.logger.Warn("Bond primary port is not a member - ignoring",
+						zap.String("bond", name),
+						zap.String("primary_port_id", group.PrimaryPortID))
+				}
+			}
+
+			if config.Network.Bonds == nil {
+				config.Network.Bonds = make(map[string]BondInterface)
+			}
+			config.Network.Bonds[name] = bond
+			if !dhcp4 {
+				policies.add(bondKind, name, "bond:"+name, gb.ips[name])
+			}
+		case GroupTypeBridge:
+			bridge := BridgeInterface{
+				Interfaces:     members,
+				DHCP4:          &dhcp4,
+				MTU:            mtuOrDefault(group.MTU),
+				Addresses:      Addresses(addresses...),
+				Routes:         routes,
+				Optional:       options.Optional,
+				DHCP4Overrides: options.DHCP4Overrides,
+				LinkLocal:      options.LinkLocal,
+			}
+			if group.STP != nil {
+				bridge.Parameters = &BridgeParameters{STP: group.STP}
+			}
+
+			if config.Network.Bridges == nil {
+				config.Network.Bridges = make(map[string]BridgeInterface)
+			}
+			config.Network.Bridges[name] = bridge
+			if !dhcp4 {
+				policies.add(bridgeKind, name, "bridge:"+name, gb.ips[name])
+			}
+		default:
+			return fmt.Errorf("unsupported group type %q for group %s", group.Type, name)
+		}
+
+		// VLANs whose parent is a member are attached to the group instead
+		for portID, ifaceName := range portInterfaces {
+			if slices.Contains(members, ifaceName) {
+				portInterfaces[portID] = name
+			}
+		}
+
+		nm.logger.Info("Configured interface group",
+			zap.String("group", name),
+			zap.String("type", group.Type),
+			zap.Strings("members", members),
+			zap.Bool("dhcp4", dhcp4))
+	}
+
+	return nil
+}
+
+// milliseconds renders a millisecond count from the database as a netplan
+// duration, leaving 0 unset
+func milliseconds(ms int) string {
+	if ms == 0 {
+		return ""
+	}
+	return strconv.Itoa(ms)
+}
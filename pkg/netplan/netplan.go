@@ -1,48 +1,23 @@
 package netplan
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"go.uber.org/zap"
-	"gopkg.in/yaml.v3"
-)
-
-// NetplanConfig represents the netplan configuration structure
-type NetplanConfig struct {
-	Network NetworkConfig `yaml:"network"`
-}
 
-type NetworkConfig struct {
-	Version   int                          `yaml:"version"`
-	Renderer  string                       `yaml:"renderer,omitempty"`
-	Ethernets map[string]EthernetInterface `yaml:"ethernets"`
-}
-
-type EthernetInterface struct {
-	Match     *MatchConfig `yaml:"match,omitempty"`
-	SetName   string       `yaml:"set-name,omitempty"`
-	DHCP4     *bool        `yaml:"dhcp4,omitempty"`
-	MTU       int          `yaml:"mtu,omitempty"`
-	Addresses []string     `yaml:"addresses,omitempty"`
-	Routes    []Route      `yaml:"routes,omitempty"`
-}
-
-type MatchConfig struct {
-	MACAddress string `yaml:"macaddress,omitempty"`
-	Driver     string `yaml:"driver,omitempty"`
-}
-
-type Route struct {
-	To     string `yaml:"to"`
-	Via    string `yaml:"via"`
-	Metric int    `yaml:"metric,omitempty"`
-}
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+	"github.com/ibyeong-geon/multinic-agent/pkg/connectivity"
+	"github.com/ibyeong-geon/multinic-agent/pkg/hostexec"
+	"github.com/ibyeong-geon/multinic-agent/pkg/inventory"
+	"github.com/ibyeong-geon/multinic-agent/pkg/sriov"
+)
 
 // InterfaceData represents database interface information
 type InterfaceData struct {
@@ -52,30 +27,104 @@ type InterfaceData struct {
 	CIDR           string
 	NetworkID      string
 	NetplanSuccess bool
+	FixedIPs       []FixedIPData
+	MTU            int
+	// ParentPortID and VLANID are set for trunk sub-ports, which are
+	// rendered as VLAN sub-interfaces on the parent port's interface
+	ParentPortID string
+	VLANID       int
+	// Group is set when the port is a member of a bond or bridge
+	Group *GroupData
+	// SRIOV is set when the port is backed by an SR-IOV virtual function
+	SRIOV *SRIOVData
+	// ModifiedAt is when the port's database row last changed
+	ModifiedAt time.Time
+	// Options are the subnet's optional/DHCP/link-local settings
+	Options SubnetOptions
 }
 
+// FixedIPData represents a fixed IP assigned to a port on a specific subnet
+type FixedIPData struct {
+	IPAddress string
+	SubnetID  string
+	CIDR      string
+	GatewayIP string
+	// PolicyRouting overrides the agent-wide setting for this subnet when set
+	PolicyRouting *bool
+	// RouteTable pins the routing table ID used for this subnet's interface
+	RouteTable int
+}
+
+// defaultMTU is used when the subnet does not define an MTU
+const defaultMTU = 1450
+
 // NetplanManager manages netplan configuration
 type NetplanManager struct {
-	logger      *zap.Logger
-	netplanDir  string
-	backupDir   string
-	dryRun      bool
+	logger         *zap.Logger
+	netplanDir     string
+	backups        *BackupManager
+	dryRun         bool
+	policyRouting  bool
+	routeTableBase int
+	sriovMode      string
+	conflictPolicy string
+	// Deny-list of interfaces the agent must never manage
+	protectedMACs      []string
+	protectedNames     []string
+	protectedAddresses []string
+	// Safe apply reverts the configuration when connectivity checks fail
+	safeApply        bool
+	safeApplyTimeout time.Duration
+	checker          *connectivity.Checker
+	// exec runs netplan and ip commands in the host's context
+	exec        hostexec.HostExecutor
+	sriov       *sriov.Manager
+	inventory   inventory.Inventory
+	pending     *pendingTracker
 	defaultGW   string
 	nameservers []string
+	// offline managers only render: existing netplan files are not read
+	offline bool
+	// options are the defaults for per-subnet link settings
+	options optionDefaults
 }
 
 // NewNetplanManager creates a new NetplanManager
-func NewNetplanManager(logger *zap.Logger, dryRun bool) *NetplanManager {
+func NewNetplanManager(cfg *config.NetplanConfig, sriovManager *sriov.Manager, inv inventory.Inventory, checker *connectivity.Checker, executor hostexec.HostExecutor, logger *zap.Logger) *NetplanManager {
 	return &NetplanManager{
-		logger:      logger,
-		netplanDir:  "/etc/netplan",
-		backupDir:   "/var/backups/netplan",
-		dryRun:      dryRun,
-		defaultGW:   "10.0.0.1",                     // Default gateway - should be configurable
-		nameservers: []string{"8.8.8.8", "8.8.4.4"}, // Default DNS - should be configurable
+		logger:             logger,
+		netplanDir:         cfg.ConfigPath,
+		backups:            BackupManagerFromConfig(cfg, logger),
+		dryRun:             cfg.DryRun,
+		policyRouting:      cfg.PolicyRouting,
+		routeTableBase:     cfg.RouteTableBase,
+		sriovMode:          cfg.SRIOVMode,
+		conflictPolicy:     cfg.ConflictPolicy,
+		protectedMACs:      lowerAll(cfg.ProtectedMACs),
+		protectedNames:     cfg.ProtectedInterfaces,
+		protectedAddresses: cfg.ProtectedAddresses,
+		safeApply:          cfg.SafeApply,
+		safeApplyTimeout:   time.Duration(cfg.SafeApplyTimeout) * time.Second,
+		checker:            checker,
+		exec:               executor,
+		sriov:              sriovManager,
+		inventory:          inv,
+		pending:            newPendingTracker(macWaitTimeout(cfg)),
+		defaultGW:          "10.0.0.1",                     // Default gateway - should be configurable
+		nameservers:        []string{"8.8.8.8", "8.8.4.4"}, // Default DNS - should be configurable
+		options:            optionDefaultsFromConfig(cfg),
 	}
 }
 
+// NewRenderer creates a NetplanManager that only generates configurations.
+// It has no host access and ignores existing netplan files, so the output
+// depends on its input alone.
+func NewRenderer(cfg *config.NetplanConfig, logger *zap.Logger) *NetplanManager {
+	nm := NewNetplanManager(cfg, nil, nil, nil, hostexec.NewNone(), logger)
+	nm.offline = true
+	return nm
+}
+
 // GenerateNetplanConfig generates netplan configuration for given interfaces
 func (nm *NetplanManager) GenerateNetplanConfig(nodeName string, interfaces []InterfaceData) (*NetplanConfig, error) {
 	config := &NetplanConfig{
@@ -85,57 +134,237 @@ func (nm *NetplanManager) GenerateNetplanConfig(nodeName string, interfaces []In
 		},
 	}
 
-	for i, iface := range interfaces {
-		interfaceName := fmt.Sprintf("eth%d", i+1) // eth1, eth2, etc.
+	// Parent interfaces first, so VLANs can reference them by name
+	portInterfaces := make(map[string]string)
+	groups := newGroupBuilder()
+	policies := newPolicyTargets()
+	index := 0
+	for _, iface := range interfaces {
+		if iface.ParentPortID != "" {
+			continue
+		}
+		index++
+		interfaceName := fmt.Sprintf("eth%d", index) // eth1, eth2, etc.
+		portInterfaces[iface.PortID] = interfaceName
+
+		// Bond/bridge members carry no addressing of their own
+		if iface.Group != nil {
+			groups.addMember(iface, interfaceName)
+
+			dhcp4 := false
+			options := nm.options.resolve(iface.Options, dhcp4)
+			config.Network.Ethernets[interfaceName] = EthernetInterface{
+				Match: &MatchConfig{
+					MACAddress: strings.ToLower(iface.MACAddress),
+				},
+				SetName:   interfaceName,
+				DHCP4:     &dhcp4,
+				MTU:       mtuOrDefault(iface.Group.MTU),
+				Optional:  options.Optional,
+				LinkLocal: options.LinkLocal,
+			}
+
+			nm.logger.Info("Configured interface as group member",
+				zap.String("interface", interfaceName),
+				zap.String("mac", iface.MACAddress),
+				zap.String("group", iface.Group.Name))
+			continue
+		}
 
-		dhcp4 := true
+		dhcp4 := len(iface.FixedIPs) == 0
+		options := nm.options.resolve(iface.Options, dhcp4)
 		ethernet := EthernetInterface{
 			Match: &MatchConfig{
 				MACAddress: strings.ToLower(iface.MACAddress),
 			},
-			SetName: interfaceName,
-			DHCP4:   &dhcp4,
-			MTU:     1450,
+			SetName:        interfaceName,
+			DHCP4:          &dhcp4,
+			MTU:            mtuOrDefault(iface.MTU),
+			Optional:       options.Optional,
+			DHCP4Overrides: options.DHCP4Overrides,
+			LinkLocal:      options.LinkLocal,
+		}
+		if err := addSRIOVFunction(config, &ethernet, iface); err != nil {
+			return nil, err
+		}
+
+		if dhcp4 {
+			config.Network.Ethernets[interfaceName] = ethernet
+
+			nm.logger.Info("Configured interface for DHCP",
+				zap.String("interface", interfaceName),
+				zap.String("mac", iface.MACAddress))
+			continue
 		}
 
+		addresses, routes, err := buildStaticAddressing(iface.FixedIPs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixed ips for port %s: %w", iface.PortID, err)
+		}
+		ethernet.Addresses = Addresses(addresses...)
+		ethernet.Routes = routes
+
 		config.Network.Ethernets[interfaceName] = ethernet
+		policies.add(ethernetKind, interfaceName, "mac:"+ethernet.Match.MACAddress, iface.FixedIPs)
 
-		nm.logger.Info("Configured interface for DHCP",
+		nm.logger.Info("Configured interface with static addresses",
 			zap.String("interface", interfaceName),
-			zap.String("mac", iface.MACAddress))
+			zap.String("mac", iface.MACAddress),
+			zap.Strings("addresses", addresses))
+	}
+
+	if err := nm.addGroups(config, groups, portInterfaces, policies); err != nil {
+		return nil, err
+	}
+
+	for _, iface := range interfaces {
+		if iface.ParentPortID == "" {
+			continue
+		}
+
+		parentName, ok := portInterfaces[iface.ParentPortID]
+		if !ok {
+			nm.logger.Warn("Parent port not found on node - skipping VLAN",
+				zap.String("port_id", iface.PortID),
+				zap.String("parent_port_id", iface.ParentPortID),
+				zap.Int("vlan_id", iface.VLANID))
+			continue
+		}
+		if iface.VLANID < 1 || iface.VLANID > 4094 {
+			return nil, fmt.Errorf("invalid vlan id %d for port %s", iface.VLANID, iface.PortID)
+		}
+
+		vlanName := fmt.Sprintf("%s.%d", parentName, iface.VLANID)
+		dhcp4 := len(iface.FixedIPs) == 0
+		options := nm.options.resolve(iface.Options, dhcp4)
+		vlan := VLANInterface{
+			ID:             iface.VLANID,
+			Link:           parentName,
+			MACAddress:     strings.ToLower(iface.MACAddress),
+			DHCP4:          &dhcp4,
+			MTU:            mtuOrDefault(iface.MTU),
+			Optional:       options.Optional,
+			DHCP4Overrides: options.DHCP4Overrides,
+			LinkLocal:      options.LinkLocal,
+		}
+
+		if !dhcp4 {
+			addresses, routes, err := buildStaticAddressing(iface.FixedIPs)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fixed ips for port %s: %w", iface.PortID, err)
+			}
+			vlan.Addresses = Addresses(addresses...)
+			vlan.Routes = routes
+			policies.add(vlanKind, vlanName, "vlan:"+vlanName, iface.FixedIPs)
+		}
+
+		if config.Network.VLANs == nil {
+			config.Network.VLANs = make(map[string]VLANInterface)
+		}
+		config.Network.VLANs[vlanName] = vlan
+
+		nm.logger.Info("Configured VLAN interface",
+			zap.String("interface", vlanName),
+			zap.String("link", parentName),
+			zap.Int("vlan_id", iface.VLANID),
+			zap.Bool("dhcp4", dhcp4))
+	}
+
+	if err := nm.addPolicyRouting(nodeName, config, policies); err != nil {
+		return nil, err
 	}
 
 	return config, nil
 }
 
-// WriteNetplanFile writes the netplan configuration to a file
-func (nm *NetplanManager) WriteNetplanFile(nodeName string, config *NetplanConfig) error {
-	filename := fmt.Sprintf("99-multinic-%s.yaml", nodeName)
-	filePath := filepath.Join(nm.netplanDir, filename)
+// lowerAll returns the values in lower case
+func lowerAll(values []string) []string {
+	lowered := make([]string, 0, len(values))
+	for _, v := range values {
+		lowered = append(lowered, strings.ToLower(v))
+	}
+	return lowered
+}
 
-	// Create backup directory if it doesn't exist
-	if err := os.MkdirAll(nm.backupDir, 0755); err != nil {
-		nm.logger.Error("Failed to create backup directory",
-			zap.String("path", nm.backupDir),
-			zap.Error(err))
+// mtuOrDefault returns the subnet MTU, falling back to defaultMTU
+func mtuOrDefault(mtu int) int {
+	if mtu > 0 {
+		return mtu
 	}
+	return defaultMTU
+}
 
-	// Backup existing file if it exists
-	if _, err := os.Stat(filePath); err == nil {
-		backupPath := filepath.Join(nm.backupDir, fmt.Sprintf("%s.%d", filename, time.Now().Unix()))
-		if err := nm.copyFile(filePath, backupPath); err != nil {
-			nm.logger.Warn("Failed to backup existing netplan file",
-				zap.String("source", filePath),
-				zap.String("backup", backupPath),
-				zap.Error(err))
-		} else {
-			nm.logger.Info("Backed up existing netplan file",
-				zap.String("backup", backupPath))
+// buildStaticAddressing converts fixed IPs into netplan addresses and
+// per-subnet routes. Each subnet gets a link-scoped route whose source is the
+// port's address on that subnet, so traffic leaves with the matching IP.
+func buildStaticAddressing(fixedIPs []FixedIPData) ([]string, []Route, error) {
+	addresses := make([]string, 0, len(fixedIPs))
+	var routes []Route
+	routedSubnets := make(map[string]bool)
+
+	for _, fip := range fixedIPs {
+		ip := net.ParseIP(fip.IPAddress)
+		if ip == nil {
+			return nil, nil, fmt.Errorf("invalid ip address %q", fip.IPAddress)
+		}
+		_, subnet, err := net.ParseCIDR(fip.CIDR)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid cidr %q: %w", fip.CIDR, err)
 		}
+		if !subnet.Contains(ip) {
+			return nil, nil, fmt.Errorf("ip address %s is not in subnet %s", fip.IPAddress, fip.CIDR)
+		}
+
+		prefixLen, _ := subnet.Mask.Size()
+		addresses = append(addresses, fmt.Sprintf("%s/%d", ip.String(), prefixLen))
+
+		if routedSubnets[subnet.String()] {
+			continue
+		}
+		routedSubnets[subnet.String()] = true
+		routes = append(routes, Route{
+			To:    subnet.String(),
+			From:  ip.String(),
+			Scope: "link",
+		})
+	}
+
+	return addresses, routes, nil
+}
+
+// NetplanFilePath returns the path of the agent's netplan file for the node
+func (nm *NetplanManager) NetplanFilePath(nodeName string) string {
+	return filepath.Join(nm.netplanDir, fmt.Sprintf("99-multinic-%s.yaml", nodeName))
+}
+
+// RenderNetplanConfig generates the configuration for the interfaces and
+// returns it as the YAML WriteNetplanFile would write. Nothing on the host
+// is checked or changed.
+func (nm *NetplanManager) RenderNetplanConfig(nodeName string, interfaces []InterfaceData) ([]byte, error) {
+	config, err := nm.GenerateNetplanConfig(nodeName, interfaces)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid netplan config: %w", err)
+	}
+	return config.Marshal()
+}
+
+// WriteNetplanFile backs up the current netplan file and atomically replaces
+// it with the configuration, then verifies the written file. The revision
+// identifies the database state and is recorded with the backup.
+func (nm *NetplanManager) WriteNetplanFile(nodeName string, config *NetplanConfig, revision string) error {
+	filePath := nm.NetplanFilePath(nodeName)
+
+	// Catch mistakes before touching the file; netplan generate still checks
+	// the result together with the rest of the host's configuration
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid netplan config: %w", err)
 	}
 
 	// Marshal config to YAML
-	yamlData, err := yaml.Marshal(config)
+	yamlData, err := config.Marshal()
 	if err != nil {
 		return fmt.Errorf("failed to marshal netplan config: %w", err)
 	}
@@ -147,135 +376,155 @@ func (nm *NetplanManager) WriteNetplanFile(nodeName string, config *NetplanConfi
 		return nil
 	}
 
-	// Write YAML to file with correct permissions (600)
-	if err := os.WriteFile(filePath, yamlData, 0600); err != nil {
+	previous, err := os.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read existing netplan file: %w", err)
+	}
+
+	// Backup existing file if it exists
+	if entry, err := nm.backups.Create(filePath, BackupReasonUpdate, revision); err != nil {
+		nm.logger.Warn("Failed to backup existing netplan file",
+			zap.String("source", filePath),
+			zap.Error(err))
+	} else if entry != nil {
+		nm.logger.Info("Backed up existing netplan file",
+			zap.String("backup", filepath.Join(nm.backups.Dir(), entry.File)))
+	}
+
+	// netplan warns about (and future versions reject) files readable by others
+	if err := writeFileAtomic(filePath, yamlData, 0600); err != nil {
 		return fmt.Errorf("failed to write netplan file: %w", err)
 	}
 
+	if err := verifyNetplanFile(filePath, yamlData); err != nil {
+		if previous != nil {
+			if restoreErr := writeFileAtomic(filePath, previous, 0600); restoreErr != nil {
+				nm.logger.Error("Failed to restore netplan file after verification failure",
+					zap.String("file", filePath),
+					zap.Error(restoreErr))
+			}
+		} else if removeErr := os.Remove(filePath); removeErr != nil {
+			nm.logger.Error("Failed to remove netplan file after verification failure",
+				zap.String("file", filePath),
+				zap.Error(removeErr))
+		}
+		return fmt.Errorf("netplan file verification failed: %w", err)
+	}
+
 	nm.logger.Info("Successfully wrote netplan file",
 		zap.String("file", filePath))
 
 	return nil
 }
 
-// ApplyNetplan applies the netplan configuration
-func (nm *NetplanManager) ApplyNetplan() error {
+// ApplyNetplan applies the netplan configuration and records every method
+// it tried. Callers validate it with ValidateNetplan first. A nil error
+// means a method completed; whether the configuration is actually active is
+// confirmed separately against the host state.
+func (nm *NetplanManager) ApplyNetplan(ctx context.Context) (*ApplyResult, error) {
+	result := &ApplyResult{}
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
 	if nm.dryRun {
 		nm.logger.Info("DRY RUN: Would apply netplan configuration")
-		return nil
-	}
-
-	// Try validation first
-	if err := nm.ValidateNetplan(); err != nil {
-		return fmt.Errorf("netplan validation failed: %w", err)
-	}
-
-	// Check if running in privileged mode with host network
-	if nm.isRunningInContainer() && !nm.isPrivilegedMode() {
-		nm.logger.Info("Running in non-privileged container environment - skipping netplan apply")
-		return nil
+		result.Method = ApplyMethodDryRun
+		return result, nil
 	}
 
 	// Apply netplan configuration
 	nm.logger.Info("Applying netplan configuration...")
 
-	var cmd *exec.Cmd
-
-	// If in container with privileged mode, try nsenter to run in host namespace
-	if nm.isRunningInContainer() && nm.isPrivilegedMode() {
-		nm.logger.Info("Using nsenter to run netplan apply in host namespace")
-		// nsenter -t 1 -m -u -n -i netplan apply
-		cmd = exec.Command("nsenter", "-t", "1", "-m", "-u", "-n", "-i", "netplan", "apply")
-	} else {
-		// Direct execution (for non-container environment)
-		cmd = exec.Command("timeout", "60", "netplan", "apply")
+	attempt := result.run(ctx, nm.exec, ApplyMethodNetplanApply, "netplan", "apply")
+	if errors.Is(attempt.err, hostexec.ErrNoHostAccess) {
+		nm.logger.Info("No host access (non-privileged container) - skipping netplan apply")
+		result.Method = ApplyMethodSkipped
+		result.Attempts = nil
+		return result, nil
+	}
+	if attempt.Succeeded() {
+		nm.logger.Info("Successfully applied netplan configuration",
+			zap.String("output", attempt.Stdout))
+		return result, nil
+	}
+	nm.logApplyAttempt(attempt)
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("netplan apply interrupted: %w", err)
 	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		// If nsenter/netplan apply fails, try alternative approaches
-		nm.logger.Error("Failed to apply netplan configuration with primary method",
-			zap.Error(err),
-			zap.String("stdout", stdout.String()),
-			zap.String("stderr", stderr.String()))
-
-		// Try alternative: use systemd-run if available
-		if nm.isRunningInContainer() && nm.isPrivilegedMode() {
-			nm.logger.Info("Trying alternative method with systemd-run...")
-			altCmd := exec.Command("nsenter", "-t", "1", "-m", "-u", "-n", "-i", "systemd-run", "--no-block", "netplan", "apply")
-			var altStdout, altStderr bytes.Buffer
-			altCmd.Stdout = &altStdout
-			altCmd.Stderr = &altStderr
-
-			if altErr := altCmd.Run(); altErr == nil {
-				nm.logger.Info("Successfully applied netplan with systemd-run",
-					zap.String("output", altStdout.String()))
-				return nil
-			} else {
-				nm.logger.Warn("systemd-run method also failed",
-					zap.Error(altErr),
-					zap.String("stdout", altStdout.String()),
-					zap.String("stderr", altStderr.String()))
-			}
-		}
-
-		// Try fallback: generate only
-		nm.logger.Info("Falling back to netplan generate only...")
-		fallbackCmd := exec.Command("netplan", "generate")
-		var fallbackStdout, fallbackStderr bytes.Buffer
-		fallbackCmd.Stdout = &fallbackStdout
-		fallbackCmd.Stderr = &fallbackStderr
-
-		if fallbackErr := fallbackCmd.Run(); fallbackErr != nil {
-			nm.logger.Error("Fallback netplan generate also failed",
-				zap.Error(fallbackErr),
-				zap.String("stdout", fallbackStdout.String()),
-				zap.String("stderr", fallbackStderr.String()))
-			return fmt.Errorf("all netplan methods failed: primary_err=%w, generate_err=%v", err, fallbackErr)
+	// Try alternative: use systemd-run, which only queues the apply in a
+	// oneshot unit that stays loaded until we have seen how it finished
+	nm.logger.Info("Trying alternative method with systemd-run...")
+	unit := fmt.Sprintf("multinic-netplan-apply-%d.service", start.UnixNano())
+	attempt = result.run(ctx, nm.exec, ApplyMethodSystemdRun, "systemd-run", "--no-block",
+		"--unit="+unit, "--property=Type=oneshot", "--property=RemainAfterExit=yes", "netplan", "apply")
+	if attempt.Succeeded() {
+		nm.logger.Info("Queued netplan apply with systemd-run",
+			zap.String("unit", unit),
+			zap.String("output", attempt.Stdout))
+		if err := nm.waitForUnit(ctx, unit); err != nil {
+			return result, fmt.Errorf("netplan apply queued with systemd-run failed: %w", err)
 		}
+		return result, nil
+	}
+	nm.logApplyAttempt(attempt)
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("netplan apply interrupted: %w", err)
+	}
 
-		nm.logger.Info("Fallback netplan generate succeeded",
-			zap.String("output", fallbackStdout.String()))
+	// Fall back to generating the backend configuration and reloading it
+	nm.logger.Info("Falling back to netplan generate and systemd-networkd reload...")
+	attempt = result.run(ctx, nm.exec, ApplyMethodGenerate, "netplan", "generate")
+	if !attempt.Succeeded() {
+		nm.logApplyAttempt(attempt)
+		return result, fmt.Errorf("all netplan apply methods failed: %s", describeAttempts(result))
+	}
 
-		// Try to manually apply network configuration using ip commands
-		if nm.isRunningInContainer() && nm.isPrivilegedMode() {
-			nm.logger.Info("Attempting manual network configuration using ip commands...")
-			if applyErr := nm.applyNetworkManually(); applyErr != nil {
-				nm.logger.Warn("Manual network configuration failed", zap.Error(applyErr))
-			} else {
-				nm.logger.Info("Successfully applied network configuration manually")
-			}
-		}
+	attempt = result.run(ctx, nm.exec, ApplyMethodNetworkdLoad, "networkctl", "reload")
+	if attempt.Succeeded() {
+		return result, nil
+	}
+	nm.logApplyAttempt(attempt)
 
-		return nil
+	attempt = result.run(ctx, nm.exec, ApplyMethodNetworkdStart, "systemctl", "restart", "systemd-networkd")
+	if attempt.Succeeded() {
+		return result, nil
 	}
+	nm.logApplyAttempt(attempt)
 
-	nm.logger.Info("Successfully applied netplan configuration",
-		zap.String("output", stdout.String()))
+	return result, fmt.Errorf("all netplan apply methods failed: %s", describeAttempts(result))
+}
 
-	return nil
+// logApplyAttempt logs a failed apply attempt
+func (nm *NetplanManager) logApplyAttempt(attempt ApplyAttempt) {
+	nm.logger.Error("Netplan apply method failed",
+		zap.String("method", attempt.Method),
+		zap.Strings("command", attempt.Command),
+		zap.Int("exit_code", attempt.ExitCode),
+		zap.String("error", attempt.Error),
+		zap.String("stdout", attempt.Stdout),
+		zap.String("stderr", attempt.Stderr))
 }
 
-// ValidateNetplan validates the netplan configuration
-func (nm *NetplanManager) ValidateNetplan() error {
+// ValidateNetplan validates the host's whole netplan configuration with
+// netplan generate. Our own file is already checked by NetplanConfig.Validate
+// before it is written.
+func (nm *NetplanManager) ValidateNetplan(ctx context.Context) error {
 	if nm.dryRun {
 		nm.logger.Info("DRY RUN: Would validate netplan configuration")
 		return nil
 	}
 
-	cmd := exec.Command("netplan", "generate")
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
+	result, err := nm.exec.Run(ctx, "netplan", "generate")
+	if errors.Is(err, hostexec.ErrNoHostAccess) {
+		nm.logger.Info("No host access (non-privileged container) - skipping netplan validation")
+		return nil
+	}
+	if err != nil {
 		nm.logger.Error("Netplan validation failed",
 			zap.Error(err),
-			zap.String("stdout", stdout.String()),
-			zap.String("stderr", stderr.String()))
+			zap.String("stdout", result.Stdout),
+			zap.String("stderr", result.Stderr))
 		return fmt.Errorf("netplan validation failed: %w", err)
 	}
 
@@ -283,210 +532,223 @@ func (nm *NetplanManager) ValidateNetplan() error {
 	return nil
 }
 
-// copyFile copies a file from src to dst
-func (nm *NetplanManager) copyFile(src, dst string) error {
-	data, err := os.ReadFile(src)
-	if err != nil {
-		return err
-	}
-
-	return os.WriteFile(dst, data, 0644)
-}
-
 // ProcessInterfaces processes interfaces and applies netplan configuration
-func (nm *NetplanManager) ProcessInterfaces(nodeName string, interfaces []InterfaceData) error {
+func (nm *NetplanManager) ProcessInterfaces(ctx context.Context, nodeName string, interfaces []InterfaceData) (*ProcessResult, error) {
 	nm.logger.Info("Processing interfaces for netplan configuration",
 		zap.String("node", nodeName),
 		zap.Int("interface_count", len(interfaces)))
 
-	// Debug: Check actual host interfaces before generating config
-	if err := nm.debugHostInterfaces(); err != nil {
-		nm.logger.Warn("Failed to debug host interfaces", zap.Error(err))
+	// Configure SR-IOV VFs and resolve them to netdevs
+	interfaces, physicalFunctions := nm.prepareSRIOV(interfaces)
+
+	// Check actual host interfaces and hold back ports whose MAC is missing
+	result := &ProcessResult{}
+	links, err := nm.inventory.Links()
+	if err != nil {
+		// Without the inventory the primary interface cannot be told apart, so do not apply at all
+		return nil, fmt.Errorf("failed to read host interface inventory, refusing to apply without protected interface checks: %w", err)
 	}
+	nm.logHostInterfaces(links, interfaces)
+	interfaces, result = nm.filterMissingMACs(interfaces, links)
+	interfaces = nm.filterProtected(interfaces, links, result)
 
 	// Generate netplan configuration
 	config, err := nm.GenerateNetplanConfig(nodeName, interfaces)
 	if err != nil {
-		return fmt.Errorf("failed to generate netplan config: %w", err)
+		return nil, fmt.Errorf("failed to generate netplan config: %w", err)
 	}
-
-	// Write configuration to file
-	if err := nm.WriteNetplanFile(nodeName, config); err != nil {
-		return fmt.Errorf("failed to write netplan file: %w", err)
+	// In netplan mode the VFs only appear once their PF is applied
+	if err := addPhysicalFunctions(config, physicalFunctions); err != nil {
+		return nil, fmt.Errorf("failed to generate netplan config: %w", err)
 	}
 
-	// Validate configuration
-	if err := nm.ValidateNetplan(); err != nil {
-		return fmt.Errorf("netplan validation failed: %w", err)
+	// Refuse (or take over) other netplan files that configure the same interfaces
+	if err := nm.resolveConflicts(nodeName, config); err != nil {
+		return nil, err
 	}
 
-	// Apply configuration
-	if err := nm.ApplyNetplan(); err != nil {
-		return fmt.Errorf("failed to apply netplan: %w", err)
+	// Keep the previous configuration to clean up removed virtual devices
+	previous, err := nm.readNetplanFile(nodeName)
+	if err != nil {
+		nm.logger.Warn("Failed to read existing netplan file", zap.Error(err))
 	}
 
-	nm.logger.Info("Successfully processed interfaces and applied netplan configuration",
-		zap.String("node", nodeName))
-
-	return nil
-}
-
-// isRunningInContainer detects if we're running in a container
-func (nm *NetplanManager) isRunningInContainer() bool {
-	// Check for container environment indicators
-	if _, err := os.Stat("/.dockerenv"); err == nil {
-		return true
+	// Keep the previous file as is to revert to if connectivity is lost
+	var snapshot *fileSnapshot
+	if nm.safeApply && !nm.dryRun {
+		if snapshot, err = nm.snapshotNetplanFile(nodeName); err != nil {
+			return nil, fmt.Errorf("failed to snapshot netplan file: %w", err)
+		}
 	}
 
-	// Check for Kubernetes environment
-	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
-		return true
+	// Write configuration to file
+	if err := nm.WriteNetplanFile(nodeName, config, interfacesRevision(interfaces)); err != nil {
+		return nil, fmt.Errorf("failed to write netplan file: %w", err)
 	}
 
-	return false
-}
+	// Validate configuration
+	if err := nm.ValidateNetplan(ctx); err != nil {
+		return nil, fmt.Errorf("netplan validation failed: %w", err)
+	}
 
-// isPrivilegedMode checks if running in privileged mode
-func (nm *NetplanManager) isPrivilegedMode() bool {
-	// Check if we can access host /proc/1 (init process)
-	if _, err := os.Stat("/proc/1/root"); err == nil {
-		return true
-	}
-
-	// Check if we have NET_ADMIN capability
-	if _, err := os.Stat("/proc/self/status"); err == nil {
-		if data, err := os.ReadFile("/proc/self/status"); err == nil {
-			content := string(data)
-			// Look for CapEff (effective capabilities)
-			if strings.Contains(content, "CapEff:") {
-				// If we have significant capabilities, likely privileged
-				return strings.Contains(content, "CapEff:\t0000003fffffffff") ||
-					strings.Contains(content, "CapEff:\t000001ffffffffff")
+	// Arm the host-side revert before applying, so an unconfirmed
+	// configuration is rolled back even if the agent itself goes away
+	var timer *revertTimer
+	if snapshot != nil {
+		if timer, err = nm.scheduleRevert(ctx, nodeName, snapshot); err != nil {
+			if restoreErr := snapshot.restore(); restoreErr != nil {
+				nm.logger.Error("Failed to restore netplan file", zap.Error(restoreErr))
 			}
+			return nil, err
 		}
 	}
 
-	// Check environment variable that indicates privileged mode
-	return os.Getenv("PRIVILEGED_MODE") == "true"
-}
-
-// applyNetworkManually applies network configuration using ip commands directly
-func (nm *NetplanManager) applyNetworkManually() error {
-	nm.logger.Info("Attempting to read and parse generated netplan files...")
-
-	// Try to use networkctl reload if available
-	cmd := exec.Command("nsenter", "-t", "1", "-m", "-u", "-n", "-i", "networkctl", "reload")
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// Apply configuration. On failure the host-side revert stays armed.
+	result.Apply, err = nm.ApplyNetplan(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to apply netplan: %w", err)
+	}
 
-	if err := cmd.Run(); err != nil {
-		nm.logger.Warn("networkctl reload failed",
-			zap.Error(err),
-			zap.String("stdout", stdout.String()),
-			zap.String("stderr", stderr.String()))
-
-		// Try alternative: restart systemd-networkd directly
-		restartCmd := exec.Command("nsenter", "-t", "1", "-m", "-u", "-n", "-i", "systemctl", "restart", "systemd-networkd")
-		var restartStdout, restartStderr bytes.Buffer
-		restartCmd.Stdout = &restartStdout
-		restartCmd.Stderr = &restartStderr
-
-		if restartErr := restartCmd.Run(); restartErr != nil {
-			nm.logger.Warn("systemctl restart systemd-networkd also failed",
-				zap.Error(restartErr),
-				zap.String("stdout", restartStdout.String()),
-				zap.String("stderr", restartStderr.String()))
-			return fmt.Errorf("both networkctl reload and systemctl restart failed: %w", err)
-		} else {
-			nm.logger.Info("Successfully restarted systemd-networkd",
-				zap.String("output", restartStdout.String()))
-			return nil
-		}
-	} else {
-		nm.logger.Info("Successfully reloaded network configuration",
-			zap.String("output", stdout.String()))
-		return nil
+	// Revert automatically unless connectivity is confirmed in time
+	if snapshot != nil {
+		if err := nm.confirmConnectivity(ctx); err != nil {
+			nm.logger.Error("Connectivity lost after netplan apply", zap.Error(err))
+			result.Apply.Reverted = true
+			// The revert must finish even when the agent is shutting down;
+			// if it fails the host-side timer still reverts
+			revertCtx := context.WithoutCancel(ctx)
+			if revertErr := nm.revertNetplan(revertCtx, snapshot, config, previous); revertErr != nil {
+				return result, fmt.Errorf("connectivity check failed (%v) and revert failed: %w", err, revertErr)
+			}
+			if cancelErr := nm.cancelRevert(revertCtx, timer); cancelErr != nil {
+				nm.logger.Warn("Host-side revert still armed after reverting", zap.Error(cancelErr))
+			}
+			return result, fmt.Errorf("connectivity check failed, configuration reverted: %w", err)
+		}
+		if err := nm.cancelRevert(context.WithoutCancel(ctx), timer); err != nil {
+			return result, err
+		}
 	}
-}
 
-// debugHostInterfaces checks and logs actual host network interfaces
-func (nm *NetplanManager) debugHostInterfaces() error {
-	nm.logger.Info("=== Debugging Host Network Interfaces ===")
+	// netplan apply does not delete virtual devices dropped from the config
+	nm.removeStaleVirtualDevices(ctx, previous, config)
 
-	// Method 1: Check /sys/class/net
-	if interfaces, err := nm.getSystemInterfaces(); err == nil {
-		for _, iface := range interfaces {
-			nm.logger.Info("Found system interface",
-				zap.String("name", iface.Name),
-				zap.String("mac", iface.MAC),
-				zap.String("state", iface.State))
+	// Only report success once the host state matches the configuration
+	if result.Apply.Method != ApplyMethodDryRun {
+		nm.confirmApplied(ctx, config, result.Apply)
+		if !result.Apply.Confirmed {
+			return result, fmt.Errorf("netplan configuration not confirmed active (method %s): %s",
+				result.Apply.Method, strings.Join(result.Apply.Unconfirmed, "; "))
 		}
-	} else {
-		nm.logger.Warn("Failed to get system interfaces", zap.Error(err))
 	}
 
-	// Method 2: Use ip command if available
-	if nm.isRunningInContainer() && nm.isPrivilegedMode() {
-		nm.logger.Info("Checking interfaces using ip command in host namespace...")
-		cmd := exec.Command("nsenter", "-t", "1", "-n", "ip", "link", "show")
-		var stdout, stderr bytes.Buffer
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
+	nm.logger.Info("Successfully processed interfaces and applied netplan configuration",
+		zap.String("node", nodeName),
+		zap.Int("pending", len(result.Pending)),
+		zap.Int("timed_out", len(result.TimedOut)),
+		zap.String("method", result.Apply.Method))
 
-		if err := cmd.Run(); err != nil {
-			nm.logger.Warn("Failed to run ip link show",
-				zap.Error(err),
-				zap.String("stderr", stderr.String()))
-		} else {
-			nm.logger.Info("Host network interfaces (ip link show)",
-				zap.String("output", stdout.String()))
+	return result, nil
+}
+
+// interfacesRevision identifies the database state of the interfaces by the
+// latest modification time of their rows
+func interfacesRevision(interfaces []InterfaceData) string {
+	var latest time.Time
+	for _, iface := range interfaces {
+		if iface.ModifiedAt.After(latest) {
+			latest = iface.ModifiedAt
 		}
 	}
-
-	return nil
+	if latest.IsZero() {
+		return ""
+	}
+	return latest.UTC().Format(time.RFC3339)
 }
 
-// SystemInterface represents a system network interface
-type SystemInterface struct {
-	Name  string
-	MAC   string
-	State string
+// readNetplanFile reads the agent's netplan file for the node.
+// It returns nil without error if the file does not exist.
+func (nm *NetplanManager) readNetplanFile(nodeName string) (*NetplanConfig, error) {
+	config, err := ReadNetplanConfig(nm.NetplanFilePath(nodeName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return config, err
 }
 
-// getSystemInterfaces reads network interfaces from /sys/class/net
-func (nm *NetplanManager) getSystemInterfaces() ([]SystemInterface, error) {
-	var interfaces []SystemInterface
+// removeStaleVirtualDevices deletes VLAN, bond and bridge links that were in
+// the previous configuration but are no longer desired
+func (nm *NetplanManager) removeStaleVirtualDevices(ctx context.Context, previous, current *NetplanConfig) {
+	if previous == nil {
+		return
+	}
 
-	netDir := "/sys/class/net"
-	entries, err := os.ReadDir(netDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read %s: %w", netDir, err)
+	var stale []string
+	for name := range previous.Network.VLANs {
+		if _, ok := current.Network.VLANs[name]; !ok {
+			stale = append(stale, name)
+		}
+	}
+	for name := range previous.Network.Bonds {
+		if _, ok := current.Network.Bonds[name]; !ok {
+			stale = append(stale, name)
+		}
+	}
+	for name := range previous.Network.Bridges {
+		if _, ok := current.Network.Bridges[name]; !ok {
+			stale = append(stale, name)
+		}
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
+	for _, name := range stale {
+		if nm.dryRun {
+			nm.logger.Info("DRY RUN: Would delete stale virtual interface", zap.String("interface", name))
 			continue
 		}
 
-		name := entry.Name()
-
-		// Skip loopback and virtual interfaces
-		if name == "lo" || strings.HasPrefix(name, "veth") || strings.HasPrefix(name, "docker") {
+		if result, err := nm.exec.Run(ctx, "ip", "link", "delete", name); err != nil {
+			nm.logger.Warn("Failed to delete stale virtual interface",
+				zap.String("interface", name),
+				zap.Error(err),
+				zap.String("stderr", result.Stderr))
 			continue
 		}
 
-		mac, _ := os.ReadFile(filepath.Join(netDir, name, "address"))
-		state, _ := os.ReadFile(filepath.Join(netDir, name, "operstate"))
+		nm.logger.Info("Deleted stale virtual interface", zap.String("interface", name))
+	}
+}
 
-		iface := SystemInterface{
-			Name:  name,
-			MAC:   strings.TrimSpace(string(mac)),
-			State: strings.TrimSpace(string(state)),
+// logHostInterfaces logs the host links and which of them each desired
+// port matches by MAC
+func (nm *NetplanManager) logHostInterfaces(links []inventory.Link, interfaces []InterfaceData) {
+	for _, link := range links {
+		if !link.Physical && link.Kind != "vlan" && link.Kind != "bond" && link.Kind != "bridge" {
+			continue
 		}
-
-		interfaces = append(interfaces, iface)
+		nm.logger.Debug("Found host interface",
+			zap.String("name", link.Name),
+			zap.String("mac", link.MACAddress),
+			zap.String("permanent_mac", link.PermanentMAC),
+			zap.String("driver", link.Driver),
+			zap.String("pci_address", link.PCIAddress),
+			zap.String("state", link.OperState),
+			zap.Bool("carrier", link.Carrier),
+			zap.Int("speed", link.Speed),
+			zap.Int("mtu", link.MTU),
+			zap.String("master", link.Master),
+			zap.Strings("addresses", link.Addresses))
 	}
 
-	return interfaces, nil
+	for _, iface := range interfaces {
+		if iface.MACAddress == "" || iface.ParentPortID != "" {
+			continue
+		}
+		if link, ok := inventory.FindByMAC(links, iface.MACAddress); ok {
+			nm.logger.Info("Matched port to host interface",
+				zap.String("port_id", iface.PortID),
+				zap.String("mac", iface.MACAddress),
+				zap.String("interface", link.Name),
+				zap.String("driver", link.Driver))
+		}
+	}
 }
@@ -0,0 +1,93 @@
+package netplan
+
+import "github.com/ibyeong-geon/multinic-agent/internal/config"
+
+// SubnetOptions holds per-subnet link settings. Unset fields fall back to
+// the agent-wide defaults and then to netplan's own defaults.
+type SubnetOptions struct {
+	// Optional keeps systemd-networkd-wait-online from waiting for the link
+	Optional *bool
+	// DHCP4 overrides, only used when the interface runs DHCP
+	DHCP4UseRoutes   *bool
+	DHCP4UseDNS      *bool
+	DHCP4UseHostname *bool
+	DHCP4RouteMetric *int
+	// LinkLocal lists the families that get link-local addresses; an empty
+	// list disables them
+	LinkLocal LinkLocal
+}
+
+// linkOptions are the resolved settings written on an interface
+type linkOptions struct {
+	Optional       *bool
+	DHCP4Overrides *DHCPOverrides
+	LinkLocal      LinkLocal
+}
+
+// optionDefaults holds the agent-wide defaults for SubnetOptions
+type optionDefaults struct {
+	optional         bool
+	dhcp4UseRoutes   *bool
+	dhcp4UseDNS      *bool
+	dhcp4UseHostname *bool
+	dhcp4RouteMetric int
+}
+
+func optionDefaultsFromConfig(cfg *config.NetplanConfig) optionDefaults {
+	return optionDefaults{
+		// Unset means optional, as the agent's interfaces are secondary
+		optional:         cfg.OptionalInterfaces == nil || *cfg.OptionalInterfaces,
+		dhcp4UseRoutes:   cfg.DHCP4UseRoutes,
+		dhcp4UseDNS:      cfg.DHCP4UseDNS,
+		dhcp4UseHostname: cfg.DHCP4UseHostname,
+		dhcp4RouteMetric: cfg.DHCP4RouteMetric,
+	}
+}
+
+// resolve merges the subnet's options over the defaults. optional is only
+// written when true and dhcp4-overrides only when the interface runs DHCP,
+// so interfaces without options render as before.
+func (d optionDefaults) resolve(opts SubnetOptions, dhcp4 bool) linkOptions {
+	resolved := linkOptions{LinkLocal: opts.LinkLocal}
+
+	optional := d.optional
+	if opts.Optional != nil {
+		optional = *opts.Optional
+	}
+	if optional {
+		resolved.Optional = &optional
+	}
+
+	if !dhcp4 {
+		return resolved
+	}
+	overrides := DHCPOverrides{
+		UseRoutes:   firstBool(opts.DHCP4UseRoutes, d.dhcp4UseRoutes),
+		UseDNS:      firstBool(opts.DHCP4UseDNS, d.dhcp4UseDNS),
+		UseHostname: firstBool(opts.DHCP4UseHostname, d.dhcp4UseHostname),
+		RouteMetric: opts.DHCP4RouteMetric,
+	}
+	if overrides.RouteMetric == nil && d.dhcp4RouteMetric > 0 {
+		overrides.RouteMetric = intPtr(d.dhcp4RouteMetric)
+	}
+	if overrides.UseRoutes != nil || overrides.UseDNS != nil || overrides.UseHostname != nil || overrides.RouteMetric != nil {
+		resolved.DHCP4Overrides = &overrides
+	}
+	return resolved
+}
+
+// intPtr returns a pointer to a copy of v
+func intPtr(v int) *int {
+	return &v
+}
+
+// firstBool returns the first value that is set
+func firstBool(values ...*bool) *bool {
+	for _, v := range values {
+		if v != nil {
+			b := *v
+			return &b
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package netplan
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+	"github.com/ibyeong-geon/multinic-agent/pkg/inventory"
+)
+
+// ProcessResult reports the ports that were left out of the applied
+// configuration because their MAC is not on the host or they are protected,
+// and how the configuration was applied
+type ProcessResult struct {
+	// Pending ports are still within the MAC wait timeout
+	Pending []string
+	// TimedOut ports have waited longer than the MAC wait timeout
+	TimedOut []string
+	// Refused ports resolve to a protected interface, keyed by port ID with
+	// the reason
+	Refused map[string]string
+	// Apply is set once the configuration was applied (or applying failed)
+	Apply *ApplyResult
+}
+
+// Applied reports whether the port made it into the applied configuration
+func (r *ProcessResult) Applied(portID string) bool {
+	_, refused := r.Refused[portID]
+	return !refused && !slices.Contains(r.Pending, portID) && !slices.Contains(r.TimedOut, portID)
+}
+
+// Reason explains why the port was left out, or "" if it was applied
+func (r *ProcessResult) Reason(portID string) string {
+	if reason, ok := r.Refused[portID]; ok {
+		return reason
+	}
+	if slices.Contains(r.TimedOut, portID) {
+		return "MAC not found on host"
+	}
+	if slices.Contains(r.Pending, portID) {
+		return "waiting for MAC to appear on host"
+	}
+	return ""
+}
+
+func (r *ProcessResult) refuse(portID, reason string) {
+	if r.Refused == nil {
+		r.Refused = make(map[string]string)
+	}
+	r.Refused[portID] = reason
+}
+
+// pendingTracker remembers since when each port's MAC has been missing.
+// It lives as long as the NetplanManager so waits span reconciles.
+type pendingTracker struct {
+	// timeout of zero reports missing MACs at once without waiting
+	timeout time.Duration
+	since   map[string]time.Time
+	macs    map[string]string
+	now     func() time.Time
+}
+
+// defaultMACWaitTimeout applies when the configuration leaves the timeout
+// unset, as config.Load does
+const defaultMACWaitTimeout = 120 * time.Second
+
+// macWaitTimeout returns the configured MAC wait timeout; an explicit zero
+// means not to wait
+func macWaitTimeout(cfg *config.NetplanConfig) time.Duration {
+	if cfg.MACWaitTimeout == nil {
+		return defaultMACWaitTimeout
+	}
+	return time.Duration(*cfg.MACWaitTimeout) * time.Second
+}
+
+func newPendingTracker(timeout time.Duration) *pendingTracker {
+	return &pendingTracker{
+		timeout: timeout,
+		since:   make(map[string]time.Time),
+		macs:    make(map[string]string),
+		now:     time.Now,
+	}
+}
+
+// filterMissingMACs drops ports whose MAC is not in the host inventory and
+// records them as pending. VLAN sub-ports follow their parent, since their
+// MAC belongs to a device the agent creates.
+func (nm *NetplanManager) filterMissingMACs(interfaces []InterfaceData, links []inventory.Link) ([]InterfaceData, *ProcessResult) {
+	result := &ProcessResult{}
+	missing := make(map[string]bool)
+	seen := make(map[string]bool)
+
+	for _, iface := range interfaces {
+		if iface.ParentPortID != "" || iface.MACAddress == "" {
+			continue
+		}
+		seen[iface.PortID] = true
+		if _, ok := inventory.FindByMAC(links, iface.MACAddress); !ok {
+			missing[iface.PortID] = true
+		}
+	}
+
+	// Forget ports that appeared or are no longer desired
+	for portID := range nm.pending.since {
+		if !seen[portID] || !missing[portID] {
+			if seen[portID] {
+				nm.logger.Info("Pending interface appeared on host",
+					zap.String("port_id", portID),
+					zap.String("mac", nm.pending.macs[portID]))
+			}
+			delete(nm.pending.since, portID)
+			delete(nm.pending.macs, portID)
+		}
+	}
+
+	now := nm.pending.now()
+	ready := make([]InterfaceData, 0, len(interfaces))
+	for _, iface := range interfaces {
+		portID := iface.PortID
+		if iface.ParentPortID != "" {
+			portID = iface.ParentPortID
+		}
+		if !missing[portID] {
+			ready = append(ready, iface)
+			continue
+		}
+
+		if iface.ParentPortID == "" {
+			if _, ok := nm.pending.since[portID]; !ok {
+				nm.pending.since[portID] = now
+				nm.pending.macs[portID] = strings.ToLower(iface.MACAddress)
+			}
+		}
+
+		waited := now.Sub(nm.pending.since[portID])
+		if nm.pending.timeout == 0 || waited > nm.pending.timeout {
+			result.TimedOut = append(result.TimedOut, iface.PortID)
+			nm.logger.Error("Interface MAC not found on host after timeout",
+				zap.String("port_id", iface.PortID),
+				zap.String("mac", iface.MACAddress),
+				zap.Duration("waited", waited))
+			continue
+		}
+
+		result.Pending = append(result.Pending, iface.PortID)
+		nm.logger.Warn("Interface MAC not found on host - waiting for hotplug",
+			zap.String("port_id", iface.PortID),
+			zap.String("mac", iface.MACAddress),
+			zap.Duration("waited", waited),
+			zap.Duration("timeout", nm.pending.timeout))
+	}
+
+	return ready, result
+}
+
+// IsPendingMAC reports whether a port with this MAC is waiting to appear
+func (nm *NetplanManager) IsPendingMAC(mac string) bool {
+	mac = strings.ToLower(mac)
+	for _, pendingMAC := range nm.pending.macs {
+		if pendingMAC == mac {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPending reports whether any port is waiting to appear
+func (nm *NetplanManager) HasPending() bool {
+	return len(nm.pending.since) > 0
+}
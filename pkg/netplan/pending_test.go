@@ -0,0 +1,106 @@
+package netplan
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+	"github.com/ibyeong-geon/multinic-agent/pkg/hostexec"
+	"github.com/ibyeong-geon/multinic-agent/pkg/inventory"
+)
+
+func TestFilterMissingMACs(t *testing.T) {
+	hostLinks := &inventory.Fake{LinkList: []inventory.Link{
+		{Name: "ens4", MACAddress: "aa:00:00:00:00:01"},
+		// Enslaved to a bond, the current MAC is the bond's; the permanent one still matches
+		{Name: "ens5", MACAddress: "aa:00:00:00:00:ff", PermanentMAC: "aa:00:00:00:00:02"},
+	}}
+
+	tests := []struct {
+		name string
+		// missingSince is how long the port has already been waiting
+		missingSince time.Duration
+		// noWait sets mac_wait_timeout to 0
+		noWait       bool
+		iface        InterfaceData
+		wantReady    bool
+		wantPending  bool
+		wantTimedOut bool
+	}{
+		{name: "present", iface: InterfaceData{PortID: "p1", MACAddress: "AA:00:00:00:00:01"}, wantReady: true},
+		{name: "present by permanent MAC", iface: InterfaceData{PortID: "p2", MACAddress: "aa:00:00:00:00:02"}, wantReady: true},
+		{name: "newly missing", iface: InterfaceData{PortID: "p3", MACAddress: "aa:00:00:00:00:03"}, wantPending: true},
+		{name: "missing within timeout", missingSince: time.Minute, iface: InterfaceData{PortID: "p3", MACAddress: "aa:00:00:00:00:03"}, wantPending: true},
+		{name: "missing past timeout", missingSince: 3 * time.Minute, iface: InterfaceData{PortID: "p3", MACAddress: "aa:00:00:00:00:03"}, wantTimedOut: true},
+		{name: "no MAC", iface: InterfaceData{PortID: "p4"}, wantReady: true},
+		{name: "newly missing without waiting", noWait: true, iface: InterfaceData{PortID: "p3", MACAddress: "aa:00:00:00:00:03"}, wantTimedOut: true},
+		{name: "present without waiting", noWait: true, iface: InterfaceData{PortID: "p1", MACAddress: "aa:00:00:00:00:01"}, wantReady: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeout := 120
+			if tt.noWait {
+				timeout = 0
+			}
+			nm := NewNetplanManager(&config.NetplanConfig{MACWaitTimeout: &timeout}, nil, hostLinks, nil, hostexec.NewNone(), zap.NewNop())
+			now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			nm.pending.now = func() time.Time { return now }
+			if tt.missingSince > 0 {
+				nm.pending.since[tt.iface.PortID] = now.Add(-tt.missingSince)
+				nm.pending.macs[tt.iface.PortID] = tt.iface.MACAddress
+			}
+
+			links, _ := hostLinks.Links()
+			vlan := InterfaceData{PortID: "vlan-" + tt.iface.PortID, ParentPortID: tt.iface.PortID, VLANID: 10}
+			ready, result := nm.filterMissingMACs([]InterfaceData{tt.iface, vlan}, links)
+
+			gotReady := slices.ContainsFunc(ready, func(i InterfaceData) bool { return i.PortID == tt.iface.PortID })
+			if gotReady != tt.wantReady {
+				t.Errorf("ready = %v, want %v", gotReady, tt.wantReady)
+			}
+			if got := slices.ContainsFunc(ready, func(i InterfaceData) bool { return i.PortID == vlan.PortID }); got != tt.wantReady {
+				t.Errorf("VLAN sub-port ready = %v, want it to follow its parent (%v)", got, tt.wantReady)
+			}
+			if got := slices.Contains(result.Pending, tt.iface.PortID); got != tt.wantPending {
+				t.Errorf("pending = %v, want %v", got, tt.wantPending)
+			}
+			if got := slices.Contains(result.TimedOut, tt.iface.PortID); got != tt.wantTimedOut {
+				t.Errorf("timed out = %v, want %v", got, tt.wantTimedOut)
+			}
+			if got := nm.IsPendingMAC(tt.iface.MACAddress); tt.iface.MACAddress != "" && got == tt.wantReady {
+				t.Errorf("IsPendingMAC = %v, want %v", got, !tt.wantReady)
+			}
+		})
+	}
+}
+
+// A port stops waiting once its MAC shows up or it leaves the database
+func TestFilterMissingMACsForgetsResolvedPorts(t *testing.T) {
+	hostLinks := &inventory.Fake{}
+	nm := NewNetplanManager(&config.NetplanConfig{}, nil, hostLinks, nil, hostexec.NewNone(), zap.NewNop())
+
+	interfaces := []InterfaceData{
+		{PortID: "hotplug", MACAddress: "aa:00:00:00:00:01"},
+		{PortID: "removed", MACAddress: "aa:00:00:00:00:02"},
+	}
+	links, _ := hostLinks.Links()
+	if _, result := nm.filterMissingMACs(interfaces, links); len(result.Pending) != 2 {
+		t.Fatalf("pending = %v, want both ports", result.Pending)
+	}
+	if !nm.HasPending() {
+		t.Fatal("HasPending = false with missing ports")
+	}
+
+	hostLinks.LinkList = []inventory.Link{{Name: "ens4", MACAddress: "aa:00:00:00:00:01"}}
+	links, _ = hostLinks.Links()
+	ready, result := nm.filterMissingMACs(interfaces[:1], links)
+	if len(ready) != 1 || len(result.Pending) != 0 {
+		t.Errorf("ready = %v, pending = %v after hotplug", ready, result.Pending)
+	}
+	if nm.HasPending() || nm.IsPendingMAC("aa:00:00:00:00:02") {
+		t.Error("still waiting for a port that appeared or was removed")
+	}
+}
@@ -0,0 +1,297 @@
+package netplan
+
+import (
+	"fmt"
+	"net"
+
+	"go.uber.org/zap"
+)
+
+// Interface kinds that policy routing can be attached to
+const (
+	ethernetKind = "ethernet"
+	vlanKind     = "vlan"
+	bondKind     = "bond"
+	bridgeKind   = "bridge"
+)
+
+// Kernel-reserved routing tables (default, main, local)
+const (
+	reservedTableMin = 253
+	reservedTableMax = 255
+)
+
+// policyTarget is a statically addressed interface that may get its own
+// routing table. The key identifies the interface across reconciles
+// (MAC for ethernets, name for virtual devices) so table IDs stay stable.
+type policyTarget struct {
+	kind     string
+	name     string
+	key      string
+	fixedIPs []FixedIPData
+}
+
+type policyTargets struct {
+	targets []policyTarget
+}
+
+func newPolicyTargets() *policyTargets {
+	return &policyTargets{}
+}
+
+func (pt *policyTargets) add(kind, name, key string, fixedIPs []FixedIPData) {
+	pt.targets = append(pt.targets, policyTarget{
+		kind:     kind,
+		name:     name,
+		key:      key,
+		fixedIPs: fixedIPs,
+	})
+}
+
+// addPolicyRouting gives every eligible interface its own routing table with
+// a subnet route and a default route via the subnet gateway, plus a
+// from-source rule per address, so replies leave through the interface the
+// request arrived on instead of the main default route.
+//
+// Table IDs come from, in order: the subnet's route_table, the table this
+// interface used in the previous netplan file, the lowest free ID from
+// routeTableBase.
+func (nm *NetplanManager) addPolicyRouting(nodeName string, config *NetplanConfig, pt *policyTargets) error {
+	var enabled []policyTarget
+	for _, target := range pt.targets {
+		var fixedIPs []FixedIPData
+		for _, fip := range target.fixedIPs {
+			if nm.policyRoutingEnabled(fip) {
+				fixedIPs = append(fixedIPs, fip)
+			}
+		}
+		if len(fixedIPs) == 0 {
+			continue
+		}
+		target.fixedIPs = fixedIPs
+		enabled = append(enabled, target)
+	}
+
+	if len(enabled) == 0 {
+		return nil
+	}
+
+	previous, err := nm.previousRouteTables(nodeName)
+	if err != nil {
+		nm.logger.Warn("Failed to read previous routing tables - allocating fresh IDs", zap.Error(err))
+	}
+
+	tables := make(map[string]int)
+	owners := make(map[int]string)
+
+	// 1. Tables pinned on the subnet
+	for _, target := range enabled {
+		pinned, err := pinnedRouteTable(target.fixedIPs)
+		if err != nil {
+			return fmt.Errorf("%s: %w", target.name, err)
+		}
+		if pinned == 0 {
+			continue
+		}
+		if !isUsableTable(pinned) {
+			return fmt.Errorf("route table %d for %s is reserved", pinned, target.name)
+		}
+		if owner, ok := owners[pinned]; ok && owner != target.key {
+			return fmt.Errorf("route table %d is configured for more than one interface", pinned)
+		}
+		tables[target.key] = pinned
+		owners[pinned] = target.key
+	}
+
+	// 2. Tables this interface already had
+	for _, target := range enabled {
+		if _, ok := tables[target.key]; ok {
+			continue
+		}
+		table, ok := previous[target.key]
+		if !ok || !isUsableTable(table) {
+			continue
+		}
+		if _, taken := owners[table]; taken {
+			continue
+		}
+		tables[target.key] = table
+		owners[table] = target.key
+	}
+
+	// 3. Lowest free table from the base
+	next := nm.routeTableBase
+	for _, target := range enabled {
+		if _, ok := tables[target.key]; ok {
+			continue
+		}
+		for !isUsableTable(next) || owners[next] != "" {
+			next++
+		}
+		tables[target.key] = next
+		owners[next] = target.key
+	}
+
+	for _, target := range enabled {
+		table := tables[target.key]
+		routes, rules, err := buildPolicyRoutes(target.fixedIPs, table)
+		if err != nil {
+			return fmt.Errorf("failed to build policy routes for %s: %w", target.name, err)
+		}
+
+		switch target.kind {
+		case ethernetKind:
+			iface := config.Network.Ethernets[target.name]
+			iface.Routes = append(iface.Routes, routes...)
+			iface.RoutingPolicy = rules
+			config.Network.Ethernets[target.name] = iface
+		case vlanKind:
+			iface := config.Network.VLANs[target.name]
+			iface.Routes = append(iface.Routes, routes...)
+			iface.RoutingPolicy = rules
+			config.Network.VLANs[target.name] = iface
+		case bondKind:
+			iface := config.Network.Bonds[target.name]
+			iface.Routes = append(iface.Routes, routes...)
+			iface.RoutingPolicy = rules
+			config.Network.Bonds[target.name] = iface
+		case bridgeKind:
+			iface := config.Network.Bridges[target.name]
+			iface.Routes = append(iface.Routes, routes...)
+			iface.RoutingPolicy = rules
+			config.Network.Bridges[target.name] = iface
+		}
+
+		nm.logger.Info("Configured policy routing",
+			zap.String("interface", target.name),
+			zap.Int("table", table),
+			zap.Int("rules", len(rules)))
+	}
+
+	return nil
+}
+
+// policyRoutingEnabled applies the subnet override over the agent setting
+func (nm *NetplanManager) policyRoutingEnabled(fip FixedIPData) bool {
+	if fip.PolicyRouting != nil {
+		return *fip.PolicyRouting
+	}
+	return nm.policyRouting
+}
+
+// previousRouteTables returns the table ID each interface used in the
+// existing netplan file, keyed like policyTarget.key
+func (nm *NetplanManager) previousRouteTables(nodeName string) (map[string]int, error) {
+	tables := make(map[string]int)
+	if nm.offline {
+		return tables, nil
+	}
+
+	previous, err := nm.readNetplanFile(nodeName)
+	if err != nil || previous == nil {
+		return tables, err
+	}
+
+	for _, iface := range previous.Network.Ethernets {
+		if table, ok := policyTable(iface.RoutingPolicy); ok && iface.Match != nil {
+			tables["mac:"+iface.Match.MACAddress] = table
+		}
+	}
+	for name, iface := range previous.Network.VLANs {
+		if table, ok := policyTable(iface.RoutingPolicy); ok {
+			tables["vlan:"+name] = table
+		}
+	}
+	for name, iface := range previous.Network.Bonds {
+		if table, ok := policyTable(iface.RoutingPolicy); ok {
+			tables["bond:"+name] = table
+		}
+	}
+	for name, iface := range previous.Network.Bridges {
+		if table, ok := policyTable(iface.RoutingPolicy); ok {
+			tables["bridge:"+name] = table
+		}
+	}
+
+	return tables, nil
+}
+
+// policyTable returns the table of an interface's first rule, if it has one
+func policyTable(rules []RoutingPolicy) (int, bool) {
+	if len(rules) == 0 || rules[0].Table == nil {
+		return 0, false
+	}
+	return *rules[0].Table, true
+}
+
+// pinnedRouteTable returns the route table configured on the subnets. An
+// interface has a single table, so subnets pinning different ones conflict.
+func pinnedRouteTable(fixedIPs []FixedIPData) (int, error) {
+	pinned := 0
+	for _, fip := range fixedIPs {
+		if fip.RouteTable == 0 {
+			continue
+		}
+		if pinned != 0 && fip.RouteTable != pinned {
+			return 0, fmt.Errorf("subnets pin different route tables %d and %d", pinned, fip.RouteTable)
+		}
+		pinned = fip.RouteTable
+	}
+	return pinned, nil
+}
+
+func isUsableTable(table int) bool {
+	return table > 0 && (table < reservedTableMin || table > reservedTableMax)
+}
+
+// buildPolicyRoutes builds the per-table routes and from-source rules
+func buildPolicyRoutes(fixedIPs []FixedIPData, table int) ([]Route, []RoutingPolicy, error) {
+	var routes []Route
+	var rules []RoutingPolicy
+	routedSubnets := make(map[string]bool)
+	// hasDefault is keyed by address length (32 or 128)
+	hasDefault := make(map[int]bool)
+
+	for _, fip := range fixedIPs {
+		ip := net.ParseIP(fip.IPAddress)
+		if ip == nil {
+			return nil, nil, fmt.Errorf("invalid ip address %q", fip.IPAddress)
+		}
+		_, subnet, err := net.ParseCIDR(fip.CIDR)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid cidr %q: %w", fip.CIDR, err)
+		}
+
+		hostBits := 32
+		if ip.To4() == nil {
+			hostBits = 128
+		}
+		rules = append(rules, RoutingPolicy{
+			From:  fmt.Sprintf("%s/%d", ip.String(), hostBits),
+			Table: intPtr(table),
+		})
+
+		if routedSubnets[subnet.String()] {
+			continue
+		}
+		routedSubnets[subnet.String()] = true
+
+		routes = append(routes, Route{
+			To:    subnet.String(),
+			Scope: "link",
+			Table: intPtr(table),
+		})
+		// A table holds one default route per address family; the first
+		// gateway of each family wins
+		if fip.GatewayIP != "" && !hasDefault[hostBits] {
+			hasDefault[hostBits] = true
+			routes = append(routes, Route{
+				To:    "default",
+				Via:   fip.GatewayIP,
+				Table: intPtr(table),
+			})
+		}
+	}
+
+	return routes, rules, nil
+}
@@ -0,0 +1,68 @@
+package netplan
+
+import (
+	"slices"
+	"testing"
+)
+
+// A dual-stack port needs a default route per family in its table, or
+// traffic of the second family follows the from rule into a dead end
+func TestBuildPolicyRoutesDualStack(t *testing.T) {
+	fixedIPs := []FixedIPData{
+		{IPAddress: "10.0.0.5", CIDR: "10.0.0.0/24", GatewayIP: "10.0.0.1"},
+		{IPAddress: "10.0.1.5", CIDR: "10.0.1.0/24", GatewayIP: "10.0.1.1"},
+		{IPAddress: "2001:db8::5", CIDR: "2001:db8::/64", GatewayIP: "2001:db8::1"},
+	}
+
+	routes, rules, err := buildPolicyRoutes(fixedIPs, 101)
+	if err != nil {
+		t.Fatalf("buildPolicyRoutes: %v", err)
+	}
+
+	var defaults []string
+	for _, route := range routes {
+		if route.To == "default" {
+			defaults = append(defaults, route.Via)
+		}
+	}
+	if want := []string{"10.0.0.1", "2001:db8::1"}; !slices.Equal(defaults, want) {
+		t.Errorf("default routes via %v, want %v", defaults, want)
+	}
+
+	var froms []string
+	for _, rule := range rules {
+		froms = append(froms, rule.From)
+	}
+	if want := []string{"10.0.0.5/32", "10.0.1.5/32", "2001:db8::5/128"}; !slices.Equal(froms, want) {
+		t.Errorf("rules from %v, want %v", froms, want)
+	}
+}
+
+func TestPinnedRouteTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		tables  []int
+		want    int
+		wantErr bool
+	}{
+		{name: "none", tables: []int{0, 0}},
+		{name: "one subnet", tables: []int{0, 120}, want: 120},
+		{name: "same table", tables: []int{120, 120}, want: 120},
+		{name: "conflicting tables", tables: []int{120, 121}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fixedIPs []FixedIPData
+			for _, table := range tt.tables {
+				fixedIPs = append(fixedIPs, FixedIPData{RouteTable: table})
+			}
+			got, err := pinnedRouteTable(fixedIPs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pinnedRouteTable error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("pinnedRouteTable = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
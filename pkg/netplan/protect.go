@@ -0,0 +1,153 @@
+package netplan
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/pkg/inventory"
+)
+
+// protectedLinks returns the host links the agent must never manage, with
+// the reason: links carrying the default route or a protected address (the
+// Kubernetes node IP), links matching the deny-list, and members of a
+// protected bond or bridge. Links whose port explicitly takes routes from
+// DHCP (dhcp4_use_routes in the database) may carry a default route.
+func (nm *NetplanManager) protectedLinks(links []inventory.Link, routesIntended map[string]bool) map[string]string {
+	protected := make(map[string]string)
+
+	for _, link := range links {
+		intended := routesIntended[link.MACAddress] || (link.PermanentMAC != "" && routesIntended[link.PermanentMAC])
+		if reason := nm.linkProtection(link, intended); reason != "" {
+			protected[link.Name] = reason
+		}
+	}
+
+	// Enslaving a member of the primary bond or bridge would also cut the node off
+	for _, link := range links {
+		if _, ok := protected[link.Name]; ok || link.Master == "" {
+			continue
+		}
+		if reason, ok := protected[link.Master]; ok {
+			protected[link.Name] = fmt.Sprintf("interface %s is a member of %s (%s)", link.Name, link.Master, reason)
+		}
+	}
+
+	return protected
+}
+
+// linkProtection returns why the link is protected, or "" if it is not
+func (nm *NetplanManager) linkProtection(link inventory.Link, routesIntended bool) string {
+	if link.DefaultRoute && !routesIntended {
+		return fmt.Sprintf("interface %s carries the default route", link.Name)
+	}
+	if address := nm.holdsProtectedAddress(link); address != "" {
+		return fmt.Sprintf("interface %s holds protected address %s", link.Name, address)
+	}
+	if mac := nm.protectedMAC(link); mac != "" {
+		return fmt.Sprintf("MAC %s is in the protected list", mac)
+	}
+	if pattern := nm.protectedPattern(link.Name); pattern != "" {
+		return fmt.Sprintf("interface %s matches protected pattern %s", link.Name, pattern)
+	}
+	return ""
+}
+
+// filterProtected drops ports that resolve to a protected host interface or
+// whose MAC is on the deny-list. VLAN sub-ports follow their parent.
+func (nm *NetplanManager) filterProtected(interfaces []InterfaceData, links []inventory.Link, result *ProcessResult) []InterfaceData {
+	routesIntended := make(map[string]bool)
+	for _, iface := range interfaces {
+		if iface.ParentPortID == "" && iface.Options.DHCP4UseRoutes != nil && *iface.Options.DHCP4UseRoutes {
+			routesIntended[strings.ToLower(iface.MACAddress)] = true
+		}
+	}
+
+	protected := nm.protectedLinks(links, routesIntended)
+
+	refused := make(map[string]string)
+	for _, iface := range interfaces {
+		if iface.ParentPortID != "" || iface.MACAddress == "" {
+			continue
+		}
+		mac := strings.ToLower(iface.MACAddress)
+		if slices.Contains(nm.protectedMACs, mac) {
+			refused[iface.PortID] = fmt.Sprintf("MAC %s is in the protected list", mac)
+			continue
+		}
+		if link, ok := inventory.FindByMAC(links, mac); ok {
+			if reason, ok := protected[link.Name]; ok {
+				refused[iface.PortID] = reason
+			}
+		}
+	}
+
+	allowed := make([]InterfaceData, 0, len(interfaces))
+	for _, iface := range interfaces {
+		reason, ok := refused[iface.PortID]
+		if !ok && iface.ParentPortID != "" {
+			if parentReason, parentRefused := refused[iface.ParentPortID]; parentRefused {
+				reason, ok = fmt.Sprintf("parent port %s is protected: %s", iface.ParentPortID, parentReason), true
+			}
+		}
+		if !ok {
+			allowed = append(allowed, iface)
+			continue
+		}
+
+		result.refuse(iface.PortID, reason)
+		nm.logger.Error("Refusing to manage protected interface",
+			zap.String("port_id", iface.PortID),
+			zap.String("mac", iface.MACAddress),
+			zap.String("reason", reason))
+	}
+
+	return allowed
+}
+
+// holdsProtectedAddress returns the protected address or network that
+// contains an address assigned to the link
+func (nm *NetplanManager) holdsProtectedAddress(link inventory.Link) string {
+	for _, address := range link.Addresses {
+		ip, _, err := net.ParseCIDR(address)
+		if err != nil {
+			continue
+		}
+		for _, protected := range nm.protectedAddresses {
+			if _, network, err := net.ParseCIDR(protected); err == nil {
+				if network.Contains(ip) {
+					return protected
+				}
+				continue
+			}
+			if protectedIP := net.ParseIP(protected); protectedIP != nil && protectedIP.Equal(ip) {
+				return protected
+			}
+		}
+	}
+	return ""
+}
+
+// protectedMAC returns the link's current or permanent MAC if it is denied
+func (nm *NetplanManager) protectedMAC(link inventory.Link) string {
+	for _, mac := range []string{link.MACAddress, link.PermanentMAC} {
+		if mac != "" && slices.Contains(nm.protectedMACs, mac) {
+			return mac
+		}
+	}
+	return ""
+}
+
+// protectedPattern returns the deny-list pattern matching the interface name
+func (nm *NetplanManager) protectedPattern(name string) string {
+	for _, pattern := range nm.protectedNames {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return pattern
+		}
+	}
+	return ""
+}
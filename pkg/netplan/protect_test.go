@@ -0,0 +1,129 @@
+package netplan
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+	"github.com/ibyeong-geon/multinic-agent/pkg/hostexec"
+	"github.com/ibyeong-geon/multinic-agent/pkg/inventory"
+)
+
+func TestFilterProtected(t *testing.T) {
+	useRoutes := true
+	links := []inventory.Link{
+		{Name: "eno1", MACAddress: "aa:00:00:00:00:01", DefaultRoute: true, Addresses: []string{"192.168.0.10/24"}},
+		{Name: "ens4", MACAddress: "aa:00:00:00:00:02", Addresses: []string{"10.20.30.40/24"}},
+		{Name: "ens5", MACAddress: "aa:00:00:00:00:03", Addresses: []string{"172.16.0.5/16"}},
+		{Name: "ens6", MACAddress: "aa:00:00:00:00:04", DefaultRoute: true},
+		{Name: "bond0", MACAddress: "aa:00:00:00:00:05", DefaultRoute: true},
+		{Name: "ens7", MACAddress: "aa:00:00:00:00:06", Master: "bond0"},
+		{Name: "ens8", MACAddress: "aa:00:00:00:00:07"},
+		{Name: "mgmt0", MACAddress: "aa:00:00:00:00:08"},
+	}
+
+	tests := []struct {
+		name        string
+		cfg         config.NetplanConfig
+		iface       InterfaceData
+		wantRefused bool
+	}{
+		{
+			name:        "default route link",
+			iface:       InterfaceData{PortID: "p1", MACAddress: "AA:00:00:00:00:01"},
+			wantRefused: true,
+		},
+		{
+			name:        "protected address",
+			cfg:         config.NetplanConfig{ProtectedAddresses: []string{"10.20.30.40"}},
+			iface:       InterfaceData{PortID: "p2", MACAddress: "aa:00:00:00:00:02"},
+			wantRefused: true,
+		},
+		{
+			name:        "address inside protected network",
+			cfg:         config.NetplanConfig{ProtectedAddresses: []string{"172.16.0.0/12"}},
+			iface:       InterfaceData{PortID: "p3", MACAddress: "aa:00:00:00:00:03"},
+			wantRefused: true,
+		},
+		{
+			name:  "address outside protected network",
+			cfg:   config.NetplanConfig{ProtectedAddresses: []string{"172.17.0.0/16"}},
+			iface: InterfaceData{PortID: "p3", MACAddress: "aa:00:00:00:00:03"},
+		},
+		{
+			name:  "default route from dhcp4_use_routes",
+			iface: InterfaceData{PortID: "p4", MACAddress: "aa:00:00:00:00:04", Options: SubnetOptions{DHCP4UseRoutes: &useRoutes}},
+		},
+		{
+			name:        "member of protected bond",
+			iface:       InterfaceData{PortID: "p5", MACAddress: "aa:00:00:00:00:06"},
+			wantRefused: true,
+		},
+		{
+			name:        "deny-listed MAC",
+			cfg:         config.NetplanConfig{ProtectedMACs: []string{"AA:00:00:00:00:07"}},
+			iface:       InterfaceData{PortID: "p6", MACAddress: "aa:00:00:00:00:07"},
+			wantRefused: true,
+		},
+		{
+			name:        "deny-listed MAC not on host",
+			cfg:         config.NetplanConfig{ProtectedMACs: []string{"aa:00:00:00:00:99"}},
+			iface:       InterfaceData{PortID: "p7", MACAddress: "aa:00:00:00:00:99"},
+			wantRefused: true,
+		},
+		{
+			name:        "protected name pattern",
+			cfg:         config.NetplanConfig{ProtectedInterfaces: []string{"mgmt*"}},
+			iface:       InterfaceData{PortID: "p8", MACAddress: "aa:00:00:00:00:08"},
+			wantRefused: true,
+		},
+		{
+			name:  "regular port",
+			iface: InterfaceData{PortID: "p9", MACAddress: "aa:00:00:00:00:07"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nm := NewNetplanManager(&tt.cfg, nil, &inventory.Fake{LinkList: links}, nil, hostexec.NewNone(), zap.NewNop())
+			vlan := InterfaceData{PortID: "vlan-" + tt.iface.PortID, ParentPortID: tt.iface.PortID, VLANID: 100}
+
+			result := &ProcessResult{}
+			allowed := nm.filterProtected([]InterfaceData{tt.iface, vlan}, links, result)
+
+			if got := !result.Applied(tt.iface.PortID); got != tt.wantRefused {
+				t.Errorf("refused = %v (%q), want %v", got, result.Reason(tt.iface.PortID), tt.wantRefused)
+			}
+			if got := !result.Applied(vlan.PortID); got != tt.wantRefused {
+				t.Errorf("VLAN sub-port refused = %v, want it to follow its parent (%v)", got, tt.wantRefused)
+			}
+			if want := map[bool]int{true: 0, false: 2}[tt.wantRefused]; len(allowed) != want {
+				t.Errorf("allowed %d ports, want %d", len(allowed), want)
+			}
+		})
+	}
+}
+
+func TestProcessInterfacesFailsClosedWithoutInventory(t *testing.T) {
+	dir := t.TempDir()
+	executor := &hostexec.Fake{}
+	cfg := &config.NetplanConfig{ConfigPath: dir}
+	nm := NewNetplanManager(cfg, nil, &inventory.Fake{Err: errors.New("netlink unavailable")}, nil, executor, zap.NewNop())
+
+	interfaces := []InterfaceData{{PortID: "p1", MACAddress: "aa:00:00:00:00:01"}}
+	if _, err := nm.ProcessInterfaces(context.Background(), "node-a", interfaces); err == nil {
+		t.Fatal("ProcessInterfaces succeeded without the host inventory")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 0 {
+		t.Errorf("wrote %v without the host inventory", files)
+	}
+	if calls := executor.Calls(); slices.ContainsFunc(calls, func(call []string) bool { return call[0] == "netplan" }) {
+		t.Errorf("ran netplan without the host inventory: %v", calls)
+	}
+}
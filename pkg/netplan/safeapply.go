@@ -0,0 +1,169 @@
+package netplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/pkg/hostexec"
+)
+
+// revertTimerGrace is added to the safe apply timeout for the host-side
+// revert timer, so it only fires once the apply and the agent's own
+// confirmation have had their chance
+const revertTimerGrace = applyUnitTimeout + time.Minute
+
+// fileSnapshot is the agent's netplan file as it was before an apply
+type fileSnapshot struct {
+	path   string
+	data   []byte
+	exists bool
+}
+
+// snapshotNetplanFile captures the current netplan file so a failed safe
+// apply can put it back byte for byte
+func (nm *NetplanManager) snapshotNetplanFile(nodeName string) (*fileSnapshot, error) {
+	snapshot := &fileSnapshot{
+		path: filepath.Join(nm.netplanDir, fmt.Sprintf("99-multinic-%s.yaml", nodeName)),
+	}
+
+	data, err := os.ReadFile(snapshot.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshot, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", snapshot.path, err)
+	}
+
+	snapshot.data = data
+	snapshot.exists = true
+	return snapshot, nil
+}
+
+// restore puts the snapshot back in place, removing the file if there was
+// none before
+func (s *fileSnapshot) restore() error {
+	if s.exists {
+		if err := writeFileAtomic(s.path, s.data, 0600); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", s.path, err)
+		}
+	} else if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// revertTimer is a transient systemd timer on the host that restores the
+// snapshot and re-applies it unless the agent confirms the apply in time.
+// It covers the agent crashing, being evicted or losing its own network
+// while the new configuration is unconfirmed.
+type revertTimer struct {
+	unit string
+	// backup is the host copy of the snapshot; netplan ignores it as it
+	// does not end in .yaml
+	backup string
+}
+
+// scheduleRevert arms the host-side revert timer. The netplan directory is
+// mounted at the same path on the host, so the paths are valid there.
+// A nil timer with a nil error means the agent has no host access.
+func (nm *NetplanManager) scheduleRevert(ctx context.Context, nodeName string, snapshot *fileSnapshot) (*revertTimer, error) {
+	timer := &revertTimer{
+		unit:   fmt.Sprintf("multinic-agent-revert-%s", nodeName),
+		backup: filepath.Join(filepath.Dir(snapshot.path), "."+filepath.Base(snapshot.path)+".revert"),
+	}
+
+	script := fmt.Sprintf("rm -f %s && netplan apply", shellQuote(snapshot.path))
+	if snapshot.exists {
+		if err := writeFileAtomic(timer.backup, snapshot.data, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", timer.backup, err)
+		}
+		script = fmt.Sprintf("mv -f %s %s && netplan apply", shellQuote(timer.backup), shellQuote(snapshot.path))
+	}
+
+	delay := nm.safeApplyTimeout + revertTimerGrace
+	result, err := nm.exec.Run(ctx, "systemd-run",
+		"--unit="+timer.unit,
+		fmt.Sprintf("--on-active=%ds", int(delay.Seconds())),
+		"--timer-property=AccuracySec=1s",
+		"/bin/sh", "-c", script)
+	if err != nil {
+		os.Remove(timer.backup)
+		if errors.Is(err, hostexec.ErrNoHostAccess) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to schedule host-side revert: %w (stderr: %s)", err, strings.TrimSpace(result.Stderr))
+	}
+
+	nm.logger.Info("Scheduled host-side netplan revert",
+		zap.String("unit", timer.unit+".timer"),
+		zap.Duration("delay", delay))
+	return timer, nil
+}
+
+// cancelRevert stops the host-side revert timer once the apply is confirmed
+// or already reverted by the agent
+func (nm *NetplanManager) cancelRevert(ctx context.Context, timer *revertTimer) error {
+	if timer == nil {
+		return nil
+	}
+	if _, err := nm.exec.Run(ctx, "systemctl", "stop", timer.unit+".timer"); err != nil {
+		return fmt.Errorf("failed to cancel host-side revert %s.timer: %w", timer.unit, err)
+	}
+	if err := os.Remove(timer.backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		nm.logger.Warn("Failed to remove revert copy", zap.String("file", timer.backup), zap.Error(err))
+	}
+
+	nm.logger.Info("Cancelled host-side netplan revert", zap.String("unit", timer.unit+".timer"))
+	return nil
+}
+
+// shellQuote quotes a path for the revert script
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// confirmConnectivity waits up to the safe apply timeout for the connectivity
+// checks to pass after an apply, like the confirmation step of netplan try
+func (nm *NetplanManager) confirmConnectivity(ctx context.Context) error {
+	if nm.checker == nil || nm.checker.Len() == 0 {
+		nm.logger.Warn("Safe apply enabled without connectivity checks - skipping confirmation")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, nm.safeApplyTimeout)
+	defer cancel()
+
+	nm.logger.Info("Confirming connectivity after netplan apply",
+		zap.Duration("timeout", nm.safeApplyTimeout))
+
+	return nm.checker.WaitReachable(ctx)
+}
+
+// revertNetplan restores the snapshot, re-applies it and removes the virtual
+// devices only the reverted configuration created
+func (nm *NetplanManager) revertNetplan(ctx context.Context, snapshot *fileSnapshot, applied, previous *NetplanConfig) error {
+	nm.logger.Warn("Reverting netplan configuration", zap.String("file", snapshot.path))
+
+	if err := snapshot.restore(); err != nil {
+		return err
+	}
+
+	if _, err := nm.ApplyNetplan(ctx); err != nil {
+		return fmt.Errorf("failed to apply reverted configuration: %w", err)
+	}
+
+	if previous == nil {
+		previous = &NetplanConfig{}
+	}
+	nm.removeStaleVirtualDevices(ctx, applied, previous)
+
+	nm.logger.Info("Reverted netplan configuration", zap.String("file", snapshot.path))
+	return nil
+}
@@ -0,0 +1,137 @@
+package netplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+	"github.com/ibyeong-geon/multinic-agent/pkg/connectivity"
+	"github.com/ibyeong-geon/multinic-agent/pkg/hostexec"
+	"github.com/ibyeong-geon/multinic-agent/pkg/inventory"
+)
+
+// Safe apply arms a host-side revert timer before applying and only
+// cancels it once connectivity is confirmed or the agent has reverted
+func TestProcessInterfacesSafeApply(t *testing.T) {
+	const previous = "network:\n  version: 2\n"
+
+	tests := []struct {
+		name        string
+		previous    bool
+		unreachable bool
+		failing     string
+		unitFailed  bool
+		wantErr     bool
+		// wantRevert is the revert script, wantCalls what runs after
+		// scheduling it; %[1]s is the netplan file, %[2]s its revert copy
+		wantRevert string
+		wantCalls  []string
+		// wantPrevious is set when the previous file must be back in place
+		wantPrevious bool
+	}{
+		{
+			name:       "confirmed",
+			previous:   true,
+			wantRevert: "mv -f '%[2]s' '%[1]s' && netplan apply",
+			wantCalls:  []string{"netplan apply", "systemctl stop multinic-agent-revert-node-a.timer"},
+		},
+		{
+			name:       "confirmed without previous file",
+			wantRevert: "rm -f '%[1]s' && netplan apply",
+			wantCalls:  []string{"netplan apply", "systemctl stop multinic-agent-revert-node-a.timer"},
+		},
+		{
+			name:         "connectivity lost",
+			previous:     true,
+			unreachable:  true,
+			wantErr:      true,
+			wantRevert:   "mv -f '%[2]s' '%[1]s' && netplan apply",
+			wantCalls:    []string{"netplan apply", "netplan apply", "systemctl stop multinic-agent-revert-node-a.timer"},
+			wantPrevious: true,
+		},
+		{
+			name:         "revert cannot be scheduled",
+			previous:     true,
+			failing:      "systemd-run",
+			wantErr:      true,
+			wantRevert:   "mv -f '%[2]s' '%[1]s' && netplan apply",
+			wantPrevious: true,
+		},
+		{
+			// The timer is left to revert what the agent could not confirm
+			name:       "apply fails",
+			previous:   true,
+			failing:    "netplan apply",
+			unitFailed: true,
+			wantErr:    true,
+			wantRevert: "mv -f '%[2]s' '%[1]s' && netplan apply",
+			wantCalls: []string{"netplan apply", queuedApply, unitState,
+				"systemctl reset-failed multinic-netplan-apply-N.service"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "99-multinic-node-a.yaml")
+			backup := filepath.Join(dir, ".99-multinic-node-a.yaml.revert")
+			if tt.previous {
+				if err := os.WriteFile(path, []byte(previous), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			executor := &hostexec.Fake{}
+			if tt.unitFailed {
+				executor.On("systemctl show", hostexec.Result{Stdout: "failed\n"}, nil)
+			}
+			if tt.failing != "" {
+				executor.On(tt.failing, failing, errors.New("exit status 1"))
+			}
+			checker := connectivity.NewChecker(zap.NewNop())
+			checker.Add("gateway", func(ctx context.Context) error {
+				if tt.unreachable {
+					return errors.New("unreachable")
+				}
+				return nil
+			})
+			links := &inventory.Fake{LinkList: []inventory.Link{{Name: "ens4", MACAddress: "aa:00:00:00:00:01", Physical: true}}}
+			cfg := &config.NetplanConfig{ConfigPath: dir, BackupPath: t.TempDir(), SafeApply: true, SafeApplyTimeout: 1}
+			nm := NewNetplanManager(cfg, nil, links, checker, executor, zap.NewNop())
+
+			interfaces := []InterfaceData{{PortID: "p1", MACAddress: "aa:00:00:00:00:01"}}
+			if _, err := nm.ProcessInterfaces(context.Background(), "node-a", interfaces); (err != nil) != tt.wantErr {
+				t.Fatalf("ProcessInterfaces error = %v, want error %v", err, tt.wantErr)
+			}
+
+			want := append([]string{
+				"netplan generate",
+				"systemd-run --unit=multinic-agent-revert-node-a --on-active=181s --timer-property=AccuracySec=1s /bin/sh -c " +
+					fmt.Sprintf(tt.wantRevert, path, backup),
+			}, tt.wantCalls...)
+			if got := commandLines(executor.Calls()); !slices.Equal(got, want) {
+				t.Errorf("commands =\n%q\nwant\n%q", got, want)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading netplan file: %v", err)
+			}
+			if restored := string(data) == previous; restored != tt.wantPrevious {
+				t.Errorf("previous file in place = %v, want %v", restored, tt.wantPrevious)
+			}
+
+			// The revert copy only outlives a failed apply, for the timer
+			_, err = os.Stat(backup)
+			if kept := err == nil; kept != (tt.failing == "netplan apply") {
+				t.Errorf("revert copy kept = %v", kept)
+			}
+		})
+	}
+}
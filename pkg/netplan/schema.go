@@ -0,0 +1,358 @@
+package netplan
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Limits enforced by the kernel and netplan
+const (
+	// maxInterfaceNameLength is IFNAMSIZ minus the trailing NUL
+	maxInterfaceNameLength = 15
+	minMTU                 = 68
+	maxMTU                 = 65535
+	maxVLANID              = 4094
+)
+
+var (
+	validRenderers  = []string{"", "networkd", "NetworkManager"}
+	validScopes     = []string{"", "global", "link", "host"}
+	validBondModes  = []string{"", "balance-rr", "active-backup", "balance-xor", "broadcast", "802.3ad", "balance-tlb", "balance-alb"}
+	validLACPRates  = []string{"", "slow", "fast"}
+	validSwitchMode = []string{"", "switchdev", "legacy"}
+	validLinkLocal  = []string{"ipv4", "ipv6"}
+
+	// bondDuration matches bond intervals: milliseconds, or a number with a
+	// time unit
+	bondDuration = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(us|ms|s|min|m|h)?$`)
+)
+
+// Validate checks the configuration against the rules netplan and the kernel
+// enforce, so a bad configuration is caught before it is written. It only
+// sees this configuration; conflicts with other files on the host are left
+// to netplan generate. All problems are returned together.
+func (c *NetplanConfig) Validate() error {
+	v := &schemaValidator{names: make(map[string]string), members: make(map[string]string)}
+
+	if c.Network.Version != 2 {
+		v.add("network: version must be 2, got %d", c.Network.Version)
+	}
+	if !slices.Contains(validRenderers, c.Network.Renderer) {
+		v.add("network: unknown renderer %q", c.Network.Renderer)
+	}
+
+	setNames := make(map[string]string)
+	matchMACs := make(map[string]string)
+	for _, name := range sortedKeys(c.Network.Ethernets) {
+		eth := c.Network.Ethernets[name]
+		path := "ethernets." + name
+		v.checkName(path, name, "ethernets")
+
+		if eth.SetName != "" {
+			if eth.Match == nil {
+				v.add("%s: set-name requires a match", path)
+			}
+			v.checkNameSyntax(path+".set-name", eth.SetName)
+			if other, ok := setNames[eth.SetName]; ok {
+				v.add("%s: set-name %q is also used by %s", path, eth.SetName, other)
+			} else {
+				setNames[eth.SetName] = path
+			}
+		}
+		if eth.Match != nil {
+			if eth.Match.MACAddress == "" && eth.Match.Driver == "" {
+				v.add("%s: match is empty", path)
+			}
+			if eth.Match.MACAddress != "" {
+				if mac := v.checkMAC(path+".match.macaddress", eth.Match.MACAddress); mac != "" {
+					if other, ok := matchMACs[mac]; ok {
+						v.add("%s: MAC %s is also matched by %s", path, mac, other)
+					} else {
+						matchMACs[mac] = path
+					}
+				}
+			}
+		}
+		if eth.VirtualFunctionCount < 0 {
+			v.add("%s: virtual-function-count must not be negative", path)
+		}
+		if !slices.Contains(validSwitchMode, eth.EmbeddedSwitchMode) {
+			v.add("%s: unknown embedded-switch-mode %q", path, eth.EmbeddedSwitchMode)
+		}
+		v.checkLink(path, eth.MTU, eth.Addresses, eth.Routes, eth.RoutingPolicy)
+		v.checkLinkOptions(path, eth.LinkLocal, eth.DHCP4Overrides, eth.DHCP6Overrides)
+	}
+
+	for _, name := range sortedKeys(c.Network.Bonds) {
+		bond := c.Network.Bonds[name]
+		path := "bonds." + name
+		v.checkName(path, name, "bonds")
+		v.checkMembers(path, bond.Interfaces, c.Network.Ethernets)
+		if params := bond.Parameters; params != nil {
+			if !slices.Contains(validBondModes, params.Mode) {
+				v.add("%s: unknown bond mode %q", path, params.Mode)
+			}
+			if !slices.Contains(validLACPRates, params.LACPRate) {
+				v.add("%s: unknown lacp-rate %q", path, params.LACPRate)
+			}
+			if params.LACPRate != "" && params.Mode != "802.3ad" {
+				v.add("%s: lacp-rate is only valid in 802.3ad mode", path)
+			}
+			for key, value := range map[string]string{
+				"mii-monitor-interval":  params.MIIMonitorInterval,
+				"arp-interval":          params.ARPInterval,
+				"up-delay":              params.UpDelay,
+				"down-delay":            params.DownDelay,
+				"learn-packet-interval": params.LearnPacketInterval,
+			} {
+				if value != "" && !bondDuration.MatchString(value) {
+					v.add("%s: %s %q is not a duration", path, key, value)
+				}
+			}
+			if params.Primary != "" && !slices.Contains(bond.Interfaces, params.Primary) {
+				v.add("%s: primary %q is not a member", path, params.Primary)
+			}
+		}
+		v.checkLink(path, bond.MTU, bond.Addresses, bond.Routes, bond.RoutingPolicy)
+		v.checkLinkOptions(path, bond.LinkLocal, bond.DHCP4Overrides, bond.DHCP6Overrides)
+	}
+
+	for _, name := range sortedKeys(c.Network.Bridges) {
+		bridge := c.Network.Bridges[name]
+		path := "bridges." + name
+		v.checkName(path, name, "bridges")
+		v.checkMembers(path, bridge.Interfaces, c.Network.Ethernets)
+		v.checkLink(path, bridge.MTU, bridge.Addresses, bridge.Routes, bridge.RoutingPolicy)
+		v.checkLinkOptions(path, bridge.LinkLocal, bridge.DHCP4Overrides, bridge.DHCP6Overrides)
+	}
+
+	for _, name := range sortedKeys(c.Network.VLANs) {
+		vlan := c.Network.VLANs[name]
+		path := "vlans." + name
+		v.checkName(path, name, "vlans")
+		if vlan.ID < 0 || vlan.ID > maxVLANID {
+			v.add("%s: id %d is out of range 0-%d", path, vlan.ID, maxVLANID)
+		}
+		if vlan.MACAddress != "" {
+			v.checkMAC(path+".macaddress", vlan.MACAddress)
+		}
+		parentMTU, ok := c.linkMTU(vlan.Link)
+		switch {
+		case vlan.Link == "":
+			v.add("%s: link is required", path)
+		case !ok:
+			v.add("%s: link %q is not defined", path, vlan.Link)
+		case parentMTU > 0 && vlan.MTU > parentMTU:
+			v.add("%s: mtu %d exceeds the mtu %d of link %s", path, vlan.MTU, parentMTU, vlan.Link)
+		}
+		v.checkLink(path, vlan.MTU, vlan.Addresses, vlan.Routes, vlan.RoutingPolicy)
+		v.checkLinkOptions(path, vlan.LinkLocal, vlan.DHCP4Overrides, vlan.DHCP6Overrides)
+	}
+
+	return errors.Join(v.errs...)
+}
+
+// linkMTU returns the MTU of an ethernet, bond or bridge in the configuration
+func (c *NetplanConfig) linkMTU(name string) (int, bool) {
+	if eth, ok := c.Network.Ethernets[name]; ok {
+		return eth.MTU, true
+	}
+	if bond, ok := c.Network.Bonds[name]; ok {
+		return bond.MTU, true
+	}
+	if bridge, ok := c.Network.Bridges[name]; ok {
+		return bridge.MTU, true
+	}
+	return 0, false
+}
+
+// schemaValidator collects problems found while checking a configuration
+type schemaValidator struct {
+	errs []error
+	// names maps every interface ID to its section to catch duplicates
+	names map[string]string
+	// members maps ethernets to the bond or bridge they belong to
+	members map[string]string
+}
+
+func (v *schemaValidator) add(format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+// checkName checks an interface ID, which must be unique across sections
+func (v *schemaValidator) checkName(path, name, section string) {
+	v.checkNameSyntax(path, name)
+	if other, ok := v.names[name]; ok {
+		v.add("%s: name is already defined under %s", path, other)
+		return
+	}
+	v.names[name] = section
+}
+
+// checkNameSyntax checks that the kernel accepts the name for a link
+func (v *schemaValidator) checkNameSyntax(path, name string) {
+	switch {
+	case name == "":
+		v.add("%s: interface name is empty", path)
+	case len(name) > maxInterfaceNameLength:
+		v.add("%s: interface name %q is longer than %d characters", path, name, maxInterfaceNameLength)
+	case name == "." || name == "..":
+		v.add("%s: interface name %q is reserved", path, name)
+	case strings.ContainsAny(name, "/: \t\n"):
+		v.add("%s: interface name %q contains '/', ':' or whitespace", path, name)
+	}
+}
+
+// checkMAC checks an Ethernet MAC address and returns it normalized, or ""
+func (v *schemaValidator) checkMAC(path, value string) string {
+	mac, err := net.ParseMAC(value)
+	if err != nil || len(mac) != 6 {
+		v.add("%s: %q is not an Ethernet MAC address", path, value)
+		return ""
+	}
+	return mac.String()
+}
+
+// checkMembers checks that bond/bridge members are ethernets in this
+// configuration and belong to a single group
+func (v *schemaValidator) checkMembers(path string, members []string, ethernets map[string]EthernetInterface) {
+	if len(members) == 0 {
+		v.add("%s: interfaces must not be empty", path)
+	}
+	for _, member := range members {
+		if _, ok := ethernets[member]; !ok {
+			v.add("%s: member %q is not a defined ethernet", path, member)
+			continue
+		}
+		if other, ok := v.members[member]; ok {
+			v.add("%s: member %q is already a member of %s", path, member, other)
+			continue
+		}
+		v.members[member] = path
+	}
+}
+
+// checkLink checks the settings shared by all interface types
+func (v *schemaValidator) checkLink(path string, mtu int, addresses AddressList, routes []Route, policies []RoutingPolicy) {
+	if mtu != 0 && (mtu < minMTU || mtu > maxMTU) {
+		v.add("%s: mtu %d is out of range %d-%d", path, mtu, minMTU, maxMTU)
+	}
+
+	for _, address := range addresses.Strings() {
+		if _, _, err := net.ParseCIDR(address); err != nil {
+			v.add("%s: address %q is not in address/prefix form", path, address)
+		}
+	}
+
+	for i, route := range routes {
+		routePath := fmt.Sprintf("%s.routes[%d]", path, i)
+		to := v.checkRouteTarget(routePath+".to", route.To, true)
+		if to == nil {
+			continue
+		}
+		// "default" matches either family
+		sameFamily := func(ip net.IP) bool {
+			return route.To == "default" || isIPv4(ip) == isIPv4(to)
+		}
+		if !slices.Contains(validScopes, route.Scope) {
+			v.add("%s: unknown scope %q", routePath, route.Scope)
+		}
+		if route.Table != nil && *route.Table < 0 {
+			v.add("%s: table must not be negative", routePath)
+		}
+		if route.Metric != nil && *route.Metric < 0 {
+			v.add("%s: metric must not be negative", routePath)
+		}
+		if route.From != "" {
+			if from := net.ParseIP(route.From); from == nil {
+				v.add("%s: from %q is not an IP address", routePath, route.From)
+			} else if !sameFamily(from) {
+				v.add("%s: from %s and to %s are different address families", routePath, route.From, route.To)
+			}
+		}
+
+		if route.Via == "" {
+			if route.Scope != "link" && route.Scope != "host" {
+				v.add("%s: route to %s needs via unless its scope is link or host", routePath, route.To)
+			}
+			continue
+		}
+		via := net.ParseIP(route.Via)
+		switch {
+		case via == nil:
+			v.add("%s: via %q is not an IP address", routePath, route.Via)
+		case !sameFamily(via):
+			v.add("%s: via %s and to %s are different address families", routePath, route.Via, route.To)
+		}
+	}
+
+	for i, policy := range policies {
+		policyPath := fmt.Sprintf("%s.routing-policy[%d]", path, i)
+		if policy.From == "" && policy.To == "" {
+			v.add("%s: from or to is required", policyPath)
+		}
+		if policy.From != "" {
+			v.checkRouteTarget(policyPath+".from", policy.From, false)
+		}
+		if policy.To != "" {
+			v.checkRouteTarget(policyPath+".to", policy.To, false)
+		}
+		if policy.Table == nil {
+			v.add("%s: table is required", policyPath)
+		} else if *policy.Table <= 0 {
+			v.add("%s: table must be positive, got %d", policyPath, *policy.Table)
+		}
+		if policy.Priority != nil && *policy.Priority < 0 {
+			v.add("%s: priority must not be negative", policyPath)
+		}
+	}
+}
+
+// checkLinkOptions checks link-local families and DHCP overrides
+func (v *schemaValidator) checkLinkOptions(path string, linkLocal LinkLocal, overrides ...*DHCPOverrides) {
+	for _, family := range linkLocal {
+		if !slices.Contains(validLinkLocal, family) {
+			v.add("%s: unknown link-local family %q", path, family)
+		}
+	}
+	for _, o := range overrides {
+		if o != nil && o.RouteMetric != nil && *o.RouteMetric < 0 {
+			v.add("%s: dhcp route-metric must not be negative", path)
+		}
+	}
+}
+
+// checkRouteTarget parses an address or prefix (or "default" when allowed)
+// and returns its address, or nil if it is invalid
+func (v *schemaValidator) checkRouteTarget(path, value string, allowDefault bool) net.IP {
+	if allowDefault && value == "default" {
+		return net.IPv4zero
+	}
+	if ip, _, err := net.ParseCIDR(value); err == nil {
+		return ip
+	}
+	if ip := net.ParseIP(value); ip != nil {
+		return ip
+	}
+	v.add("%s: %q is not an address or prefix", path, value)
+	return nil
+}
+
+func isIPv4(ip net.IP) bool {
+	return ip.To4() != nil
+}
+
+// sortedKeys returns the map's keys in order so problems are reported
+// deterministically
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,255 @@
+package netplan
+
+import (
+	"strings"
+	"testing"
+)
+
+// validConfig is a configuration that passes every rule: two ethernets in
+// a bond, one with a VLAN on top
+func validConfig() *NetplanConfig {
+	return &NetplanConfig{Network: NetworkConfig{
+		Version: 2,
+		Ethernets: map[string]EthernetInterface{
+			"eth1": {
+				Match:     &MatchConfig{MACAddress: "fa:16:3e:00:00:01"},
+				SetName:   "eth1",
+				MTU:       9000,
+				Addresses: Addresses("10.0.0.5/24"),
+				Routes: []Route{
+					{To: "10.0.0.0/24", Scope: "link"},
+					{To: "default", Via: "10.0.0.1", Table: intPtr(100)},
+				},
+				RoutingPolicy: []RoutingPolicy{{From: "10.0.0.5/32", Table: intPtr(100)}},
+			},
+			"eth2": {Match: &MatchConfig{MACAddress: "fa:16:3e:00:00:02"}, SetName: "eth2"},
+			"eth3": {Match: &MatchConfig{MACAddress: "fa:16:3e:00:00:03"}, SetName: "eth3"},
+		},
+		Bonds: map[string]BondInterface{
+			"bond0": {Interfaces: []string{"eth2", "eth3"}, MTU: 1500, Parameters: &BondParameters{Mode: "active-backup", Primary: "eth2"}},
+		},
+		VLANs: map[string]VLANInterface{
+			"eth1.10": {ID: 10, Link: "eth1", MTU: 1500, Addresses: Addresses("10.1.0.5/24")},
+		},
+	}}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(c *NetplanConfig)
+		// wantErr is a fragment of the expected problem, "" for valid
+		wantErr string
+	}{
+		{name: "valid", mutate: func(c *NetplanConfig) {}},
+		{
+			name:    "version",
+			mutate:  func(c *NetplanConfig) { c.Network.Version = 1 },
+			wantErr: "version must be 2",
+		},
+		{
+			name: "name longer than 15",
+			mutate: func(c *NetplanConfig) {
+				c.Network.Ethernets["interface-name-16"] = EthernetInterface{Match: &MatchConfig{MACAddress: "fa:16:3e:00:00:09"}}
+			},
+			wantErr: "longer than 15 characters",
+		},
+		{
+			name: "set-name longer than 15",
+			mutate: func(c *NetplanConfig) {
+				setEthernet(c, "eth2", func(e *EthernetInterface) { e.SetName = "storage-uplink-0" })
+			},
+			wantErr: `"storage-uplink-0" is longer than 15`,
+		},
+		{
+			name:    "name with slash",
+			mutate:  func(c *NetplanConfig) { setEthernet(c, "eth2", func(e *EthernetInterface) { e.SetName = "eth/2" }) },
+			wantErr: "contains '/'",
+		},
+		{
+			name: "bad MAC",
+			mutate: func(c *NetplanConfig) {
+				setEthernet(c, "eth2", func(e *EthernetInterface) { e.Match.MACAddress = "fa:16:3e:00:00" })
+			},
+			wantErr: "is not an Ethernet MAC address",
+		},
+		{
+			name: "InfiniBand MAC",
+			mutate: func(c *NetplanConfig) {
+				setEthernet(c, "eth2", func(e *EthernetInterface) {
+					e.Match.MACAddress = "00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:01"
+				})
+			},
+			wantErr: "is not an Ethernet MAC address",
+		},
+		{
+			name:    "MTU below minimum",
+			mutate:  func(c *NetplanConfig) { setEthernet(c, "eth2", func(e *EthernetInterface) { e.MTU = 67 }) },
+			wantErr: "mtu 67 is out of range",
+		},
+		{
+			name:    "MTU above maximum",
+			mutate:  func(c *NetplanConfig) { setEthernet(c, "eth2", func(e *EthernetInterface) { e.MTU = 65536 }) },
+			wantErr: "mtu 65536 is out of range",
+		},
+		{
+			name:   "MTU bounds",
+			mutate: func(c *NetplanConfig) { setEthernet(c, "eth2", func(e *EthernetInterface) { e.MTU = 68 }) },
+		},
+		{
+			name: "via of another family",
+			mutate: func(c *NetplanConfig) {
+				setEthernet(c, "eth1", func(e *EthernetInterface) { e.Routes = []Route{{To: "10.2.0.0/16", Via: "2001:db8::1"}} })
+			},
+			wantErr: "different address families",
+		},
+		{
+			name: "from of another family",
+			mutate: func(c *NetplanConfig) {
+				setEthernet(c, "eth1", func(e *EthernetInterface) { e.Routes = []Route{{To: "2001:db8::/64", From: "10.0.0.5", Scope: "link"}} })
+			},
+			wantErr: "different address families",
+		},
+		{
+			name: "default via either family",
+			mutate: func(c *NetplanConfig) {
+				setEthernet(c, "eth1", func(e *EthernetInterface) { e.Routes = []Route{{To: "default", Via: "2001:db8::1"}} })
+			},
+		},
+		{
+			name: "route without via in global scope",
+			mutate: func(c *NetplanConfig) {
+				setEthernet(c, "eth1", func(e *EthernetInterface) { e.Routes = []Route{{To: "10.2.0.0/16"}} })
+			},
+			wantErr: "needs via unless its scope is link or host",
+		},
+		{
+			name: "route without via in host scope",
+			mutate: func(c *NetplanConfig) {
+				setEthernet(c, "eth1", func(e *EthernetInterface) { e.Routes = []Route{{To: "10.0.0.9/32", Scope: "host"}} })
+			},
+		},
+		{
+			name: "negative route metric",
+			mutate: func(c *NetplanConfig) {
+				setEthernet(c, "eth1", func(e *EthernetInterface) { e.Routes[1].Metric = intPtr(-1) })
+			},
+			wantErr: "metric must not be negative",
+		},
+		{
+			name: "routing policy without table",
+			mutate: func(c *NetplanConfig) {
+				setEthernet(c, "eth1", func(e *EthernetInterface) { e.RoutingPolicy[0].Table = nil })
+			},
+			wantErr: "table is required",
+		},
+		{
+			name:    "duplicate set-name",
+			mutate:  func(c *NetplanConfig) { setEthernet(c, "eth3", func(e *EthernetInterface) { e.SetName = "eth2" }) },
+			wantErr: `set-name "eth2" is also used by ethernets.eth2`,
+		},
+		{
+			name: "duplicate MAC",
+			mutate: func(c *NetplanConfig) {
+				setEthernet(c, "eth3", func(e *EthernetInterface) { e.Match.MACAddress = "FA:16:3E:00:00:02" })
+			},
+			wantErr: "MAC fa:16:3e:00:00:02 is also matched by ethernets.eth2",
+		},
+		{
+			name: "name reused across sections",
+			mutate: func(c *NetplanConfig) {
+				c.Network.Bridges = map[string]BridgeInterface{"eth1.10": {Interfaces: []string{"eth1"}}}
+			},
+			wantErr: "name is already defined under bridges",
+		},
+		{
+			name: "bond member reuse",
+			mutate: func(c *NetplanConfig) {
+				c.Network.Bridges = map[string]BridgeInterface{"br0": {Interfaces: []string{"eth3"}}}
+			},
+			wantErr: `member "eth3" is already a member of bonds.bond0`,
+		},
+		{
+			name: "bond member not defined",
+			mutate: func(c *NetplanConfig) {
+				bond := c.Network.Bonds["bond0"]
+				bond.Interfaces = append(bond.Interfaces, "eth9")
+				c.Network.Bonds["bond0"] = bond
+			},
+			wantErr: `member "eth9" is not a defined ethernet`,
+		},
+		{
+			name: "bond primary not a member",
+			mutate: func(c *NetplanConfig) {
+				c.Network.Bonds["bond0"].Parameters.Primary = "eth1"
+			},
+			wantErr: `primary "eth1" is not a member`,
+		},
+		{
+			name: "lacp-rate outside 802.3ad",
+			mutate: func(c *NetplanConfig) {
+				c.Network.Bonds["bond0"].Parameters.LACPRate = "fast"
+			},
+			wantErr: "lacp-rate is only valid in 802.3ad mode",
+		},
+		{
+			name: "VLAN MTU above link MTU",
+			mutate: func(c *NetplanConfig) {
+				setVLAN(c, "eth1.10", func(v *VLANInterface) { v.Link = "bond0"; v.MTU = 9000 })
+			},
+			wantErr: "mtu 9000 exceeds the mtu 1500 of link bond0",
+		},
+		{
+			name:    "VLAN link not defined",
+			mutate:  func(c *NetplanConfig) { setVLAN(c, "eth1.10", func(v *VLANInterface) { v.Link = "eth9" }) },
+			wantErr: `link "eth9" is not defined`,
+		},
+		{
+			name:    "VLAN ID out of range",
+			mutate:  func(c *NetplanConfig) { setVLAN(c, "eth1.10", func(v *VLANInterface) { v.ID = 4095 }) },
+			wantErr: "id 4095 is out of range",
+		},
+		{
+			name: "address without prefix",
+			mutate: func(c *NetplanConfig) {
+				setVLAN(c, "eth1.10", func(v *VLANInterface) { v.Addresses = Addresses("10.1.0.5") })
+			},
+			wantErr: "is not in address/prefix form",
+		},
+		{
+			name: "unknown link-local family",
+			mutate: func(c *NetplanConfig) {
+				setEthernet(c, "eth2", func(e *EthernetInterface) { e.LinkLocal = LinkLocal{"ipv5"} })
+			},
+			wantErr: `unknown link-local family "ipv5"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			tt.mutate(config)
+			err := config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want valid", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() = %v, want a problem containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func setEthernet(c *NetplanConfig, name string, change func(*EthernetInterface)) {
+	eth := c.Network.Ethernets[name]
+	change(&eth)
+	c.Network.Ethernets[name] = eth
+}
+
+func setVLAN(c *NetplanConfig, name string, change func(*VLANInterface)) {
+	vlan := c.Network.VLANs[name]
+	change(&vlan)
+	c.Network.VLANs[name] = vlan
+}
@@ -0,0 +1,207 @@
+package netplan
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/pkg/sriov"
+)
+
+// SR-IOV configuration modes
+const (
+	// SRIOVModeSysfs sets the VF count through sriov_numvfs before rendering
+	SRIOVModeSysfs = "sysfs"
+	// SRIOVModeNetplan renders the PF with virtual-function-count and lets
+	// netplan create the VFs
+	SRIOVModeNetplan = "netplan"
+)
+
+// SRIOVData is the desired SR-IOV state stored with a port
+type SRIOVData struct {
+	// PCIAddress is the VF backing the port
+	PCIAddress string
+	// PFPCIAddress is the physical function the VF belongs to
+	PFPCIAddress string
+	// VFCount is the number of VFs the PF should expose
+	VFCount int
+	// SwitchMode is the PF embedded-switch-mode (legacy or switchdev)
+	SwitchMode string
+	// PFName and PFMAC are resolved from sysfs in netplan mode
+	PFName string
+	PFMAC  string
+}
+
+// prepareSRIOV applies the desired VF counts and resolves SR-IOV ports
+// through sysfs, preferring the VF's PCI address over the stored MAC. Ports
+// whose VF cannot be matched are dropped until the VF shows up. In netplan
+// mode it also returns the resolved PFs, which must be rendered even while
+// their VFs are missing since netplan is what creates them.
+func (nm *NetplanManager) prepareSRIOV(interfaces []InterfaceData) ([]InterfaceData, []SRIOVData) {
+	if nm.sriov == nil {
+		return interfaces, nil
+	}
+
+	prepared := make([]InterfaceData, 0, len(interfaces))
+	pfCounts := make(map[string]int)
+	pfModes := make(map[string]string)
+	// VFs backing ports, by PCI address or MAC, must survive VF count changes
+	pfInUse := make(map[string][]string)
+	for _, iface := range interfaces {
+		if iface.SRIOV != nil {
+			// Copy so resolved fields never leak into the caller's slice
+			data := *iface.SRIOV
+			iface.SRIOV = &data
+
+			if data.PFPCIAddress != "" {
+				pfCounts[data.PFPCIAddress] = max(pfCounts[data.PFPCIAddress], data.VFCount)
+				if data.SwitchMode != "" {
+					pfModes[data.PFPCIAddress] = data.SwitchMode
+				}
+				for _, id := range []string{data.PCIAddress, iface.MACAddress} {
+					if id != "" {
+						pfInUse[data.PFPCIAddress] = append(pfInUse[data.PFPCIAddress], id)
+					}
+				}
+			}
+		}
+		prepared = append(prepared, iface)
+	}
+
+	pfs := slices.Sorted(maps.Keys(pfCounts))
+	pfDevices := make(map[string]*sriov.Device)
+
+	for _, pf := range pfs {
+		switch nm.sriovMode {
+		case SRIOVModeNetplan:
+			device, err := nm.sriov.Resolve(pf)
+			if err != nil {
+				nm.logger.Warn("Failed to resolve SR-IOV physical function",
+					zap.String("pf", pf),
+					zap.Error(err))
+				continue
+			}
+			pfDevices[pf] = device
+		default:
+			if pfCounts[pf] == 0 {
+				continue
+			}
+			if nm.dryRun {
+				nm.logger.Info("DRY RUN: Would set SR-IOV VF count",
+					zap.String("pf", pf),
+					zap.Int("vf_count", pfCounts[pf]))
+				continue
+			}
+			if err := nm.sriov.EnsureVFCount(pf, pfCounts[pf], pfInUse[pf]); err != nil {
+				nm.logger.Error("Failed to configure SR-IOV VF count",
+					zap.String("pf", pf),
+					zap.Int("vf_count", pfCounts[pf]),
+					zap.Error(err))
+			}
+		}
+	}
+
+	result := make([]InterfaceData, 0, len(prepared))
+	for _, iface := range prepared {
+		if iface.SRIOV == nil {
+			result = append(result, iface)
+			continue
+		}
+
+		if iface.SRIOV.PCIAddress != "" {
+			device, err := nm.sriov.Resolve(iface.SRIOV.PCIAddress)
+			if err == nil {
+				if iface.MACAddress != "" && !strings.EqualFold(iface.MACAddress, device.MACAddress) {
+					nm.logger.Info("SR-IOV VF MAC differs from the port's MAC - matching the VF by PCI address",
+						zap.String("port_id", iface.PortID),
+						zap.String("pci_address", iface.SRIOV.PCIAddress),
+						zap.String("port_mac", iface.MACAddress),
+						zap.String("vf_mac", device.MACAddress))
+				}
+				iface.MACAddress = device.MACAddress
+				if iface.SRIOV.PFPCIAddress == "" {
+					iface.SRIOV.PFPCIAddress = device.PFPCIAddress
+				}
+			} else if iface.MACAddress == "" {
+				nm.logger.Warn("SR-IOV VF not found and port has no MAC - skipping",
+					zap.String("port_id", iface.PortID),
+					zap.String("pci_address", iface.SRIOV.PCIAddress),
+					zap.Error(err))
+				continue
+			}
+		}
+
+		if device, ok := pfDevices[iface.SRIOV.PFPCIAddress]; ok {
+			iface.SRIOV.PFName = device.Name
+			iface.SRIOV.PFMAC = device.MACAddress
+			if mode, ok := pfModes[iface.SRIOV.PFPCIAddress]; ok {
+				iface.SRIOV.SwitchMode = mode
+			}
+			iface.SRIOV.VFCount = pfCounts[iface.SRIOV.PFPCIAddress]
+		}
+
+		result = append(result, iface)
+	}
+
+	var physicalFunctions []SRIOVData
+	for _, pf := range pfs {
+		if device, ok := pfDevices[pf]; ok {
+			physicalFunctions = append(physicalFunctions, SRIOVData{
+				PFPCIAddress: pf,
+				PFName:       device.Name,
+				PFMAC:        device.MACAddress,
+				VFCount:      pfCounts[pf],
+				SwitchMode:   pfModes[pf],
+			})
+		}
+	}
+
+	return result, physicalFunctions
+}
+
+// addSRIOVFunction links a VF to its PF and renders the PF entry carrying
+// the VF count, when the PF was resolved for netplan mode
+func addSRIOVFunction(config *NetplanConfig, ethernet *EthernetInterface, iface InterfaceData) error {
+	if iface.SRIOV == nil || iface.SRIOV.PFName == "" {
+		return nil
+	}
+	if err := addPhysicalFunction(config, *iface.SRIOV); err != nil {
+		return err
+	}
+
+	ethernet.Link = iface.SRIOV.PFName
+	return nil
+}
+
+// addPhysicalFunctions renders the PF entries, including those whose VF
+// ports are not rendered yet
+func addPhysicalFunctions(config *NetplanConfig, physicalFunctions []SRIOVData) error {
+	for _, pf := range physicalFunctions {
+		if err := addPhysicalFunction(config, pf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addPhysicalFunction renders the PF entry, or raises the VF count of an
+// existing one
+func addPhysicalFunction(config *NetplanConfig, data SRIOVData) error {
+	pfName := data.PFName
+	pf, exists := config.Network.Ethernets[pfName]
+	if exists && (pf.Match == nil || pf.Match.MACAddress != data.PFMAC) {
+		return fmt.Errorf("physical function %s conflicts with interface of the same name", pfName)
+	}
+
+	pf.Match = &MatchConfig{MACAddress: data.PFMAC}
+	pf.SetName = pfName
+	pf.VirtualFunctionCount = max(pf.VirtualFunctionCount, data.VFCount)
+	if data.SwitchMode != "" {
+		pf.EmbeddedSwitchMode = data.SwitchMode
+	}
+	config.Network.Ethernets[pfName] = pf
+	return nil
+}
@@ -0,0 +1,149 @@
+package netplan
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/internal/config"
+	"github.com/ibyeong-geon/multinic-agent/pkg/hostexec"
+	"github.com/ibyeong-geon/multinic-agent/pkg/inventory"
+	"github.com/ibyeong-geon/multinic-agent/pkg/sriov"
+	"github.com/ibyeong-geon/multinic-agent/pkg/sysfs"
+)
+
+// writeSysfsPF creates a PF with the given netdev in a fake sysfs tree
+// and, optionally, one VF
+func writeSysfsPF(t *testing.T, root, pf, name, mac string, vf *sriov.Device) {
+	t.Helper()
+	devices := filepath.Join(root, "bus/pci/devices")
+	files := map[string]string{
+		filepath.Join(devices, pf, "sriov_totalvfs"):       "8",
+		filepath.Join(devices, pf, "sriov_numvfs"):         "0",
+		filepath.Join(devices, pf, "net", name, "address"): mac,
+	}
+	if vf != nil {
+		files[filepath.Join(devices, pf, "sriov_numvfs")] = "1"
+		files[filepath.Join(devices, vf.PCIAddress, "net", vf.Name, "address")] = vf.MACAddress
+	}
+	for path, value := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(value+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if vf != nil {
+		if err := os.Symlink("../"+pf, filepath.Join(devices, vf.PCIAddress, "physfn")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink("../"+vf.PCIAddress, filepath.Join(devices, pf, "virtfn0")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// In netplan mode the VFs only exist once the PF entry is applied, so the
+// PF must be rendered while its VF ports wait for their MACs
+func TestProcessInterfacesRendersPFWhileVFsArePending(t *testing.T) {
+	const pf = "0000:3b:00.0"
+	root := t.TempDir()
+	writeSysfsPF(t, root, pf, "ens1f0", "3c:fd:fe:00:00:01", nil)
+
+	dir := t.TempDir()
+	cfg := &config.NetplanConfig{ConfigPath: dir, BackupPath: t.TempDir(), SRIOVMode: SRIOVModeNetplan}
+	links := &inventory.Fake{LinkList: []inventory.Link{{Name: "ens1f0", MACAddress: "3c:fd:fe:00:00:01", Physical: true}}}
+	nm := NewNetplanManager(cfg, sriov.NewManager(sysfs.New(root), zap.NewNop()), links, nil, &hostexec.Fake{}, zap.NewNop())
+
+	interfaces := []InterfaceData{
+		{
+			PortID:     "vf-port",
+			MACAddress: "fa:16:3e:00:00:01",
+			SRIOV:      &SRIOVData{PCIAddress: "0000:3b:02.0", PFPCIAddress: pf, VFCount: 4, SwitchMode: "legacy"},
+		},
+		{
+			PortID: "pci-only-port",
+			SRIOV:  &SRIOVData{PCIAddress: "0000:3b:02.1", PFPCIAddress: pf, VFCount: 2},
+		},
+	}
+	result, err := nm.ProcessInterfaces(context.Background(), "node-a", interfaces)
+	if err != nil {
+		t.Fatalf("ProcessInterfaces: %v", err)
+	}
+	if result.Applied("vf-port") {
+		t.Error("VF port applied before its VF exists")
+	}
+
+	written, err := ReadNetplanConfig(nm.NetplanFilePath("node-a"))
+	if err != nil {
+		t.Fatalf("reading written config: %v", err)
+	}
+	entry, ok := written.Network.Ethernets["ens1f0"]
+	if !ok {
+		t.Fatalf("PF not rendered: %+v", written.Network.Ethernets)
+	}
+	if entry.VirtualFunctionCount != 4 || entry.EmbeddedSwitchMode != "legacy" {
+		t.Errorf("PF entry = %+v, want 4 VFs in legacy mode", entry)
+	}
+}
+
+func TestPrepareSRIOVPrefersPCIAddress(t *testing.T) {
+	const pf = "0000:3b:00.0"
+	root := t.TempDir()
+	vf := &sriov.Device{PCIAddress: "0000:3b:02.0", Name: "ens1f0v0", MACAddress: "02:00:00:00:00:07"}
+	writeSysfsPF(t, root, pf, "ens1f0", "3c:fd:fe:00:00:01", vf)
+
+	tests := []struct {
+		name    string
+		iface   InterfaceData
+		wantMAC string
+		wantOK  bool
+	}{
+		{
+			name:    "PCI address over stale MAC",
+			iface:   InterfaceData{PortID: "p1", MACAddress: "FA:16:3E:00:00:01", SRIOV: &SRIOVData{PCIAddress: vf.PCIAddress}},
+			wantMAC: vf.MACAddress,
+			wantOK:  true,
+		},
+		{
+			name:    "PCI address without MAC",
+			iface:   InterfaceData{PortID: "p2", SRIOV: &SRIOVData{PCIAddress: vf.PCIAddress}},
+			wantMAC: vf.MACAddress,
+			wantOK:  true,
+		},
+		{
+			name:    "missing VF falls back to MAC",
+			iface:   InterfaceData{PortID: "p3", MACAddress: "fa:16:3e:00:00:03", SRIOV: &SRIOVData{PCIAddress: "0000:3b:02.5"}},
+			wantMAC: "fa:16:3e:00:00:03",
+			wantOK:  true,
+		},
+		{
+			name:  "missing VF without MAC",
+			iface: InterfaceData{PortID: "p4", SRIOV: &SRIOVData{PCIAddress: "0000:3b:02.5"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.NetplanConfig{SRIOVMode: SRIOVModeNetplan}
+			nm := NewNetplanManager(cfg, sriov.NewManager(sysfs.New(root), zap.NewNop()), nil, nil, hostexec.NewNone(), zap.NewNop())
+
+			prepared, _ := nm.prepareSRIOV([]InterfaceData{tt.iface})
+			if len(prepared) == 1 != tt.wantOK {
+				t.Fatalf("prepared %d ports, want kept = %v", len(prepared), tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			if prepared[0].MACAddress != tt.wantMAC {
+				t.Errorf("MAC = %s, want %s", prepared[0].MACAddress, tt.wantMAC)
+			}
+			if tt.iface.SRIOV.PFPCIAddress != "" {
+				t.Error("prepareSRIOV changed the caller's SRIOVData")
+			}
+		})
+	}
+}
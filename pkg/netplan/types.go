@@ -0,0 +1,377 @@
+package netplan
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The types below follow netplan's YAML reference. Fields the agent
+// generates come first in each struct (so its output keeps its layout);
+// the rest are there to read and rewrite files written by others. Keys
+// this model does not know end up in Extra and are written back unchanged.
+
+// NetplanConfig represents the netplan configuration structure
+type NetplanConfig struct {
+	Network NetworkConfig        `yaml:"network"`
+	Extra   map[string]yaml.Node `yaml:",inline"`
+}
+
+type NetworkConfig struct {
+	Version   int                          `yaml:"version"`
+	Renderer  string                       `yaml:"renderer,omitempty"`
+	Ethernets map[string]EthernetInterface `yaml:"ethernets,omitempty"`
+	VLANs     map[string]VLANInterface     `yaml:"vlans,omitempty"`
+	Bonds     map[string]BondInterface     `yaml:"bonds,omitempty"`
+	Bridges   map[string]BridgeInterface   `yaml:"bridges,omitempty"`
+	// Other sections (wifis, tunnels, vrfs, ...) are kept as is
+	Extra map[string]yaml.Node `yaml:",inline"`
+}
+
+type EthernetInterface struct {
+	Match         *MatchConfig    `yaml:"match,omitempty"`
+	SetName       string          `yaml:"set-name,omitempty"`
+	Link          string          `yaml:"link,omitempty"`
+	DHCP4         *bool           `yaml:"dhcp4,omitempty"`
+	MTU           int             `yaml:"mtu,omitempty"`
+	Addresses     AddressList     `yaml:"addresses,omitempty"`
+	Routes        []Route         `yaml:"routes,omitempty"`
+	RoutingPolicy []RoutingPolicy `yaml:"routing-policy,omitempty"`
+	// SR-IOV physical function settings
+	VirtualFunctionCount int    `yaml:"virtual-function-count,omitempty"`
+	EmbeddedSwitchMode   string `yaml:"embedded-switch-mode,omitempty"`
+
+	Renderer          string         `yaml:"renderer,omitempty"`
+	Optional          *bool          `yaml:"optional,omitempty"`
+	OptionalAddresses []string       `yaml:"optional-addresses,omitempty"`
+	ActivationMode    string         `yaml:"activation-mode,omitempty"`
+	Critical          *bool          `yaml:"critical,omitempty"`
+	DHCP6             *bool          `yaml:"dhcp6,omitempty"`
+	DHCPIdentifier    string         `yaml:"dhcp-identifier,omitempty"`
+	DHCP4Overrides    *DHCPOverrides `yaml:"dhcp4-overrides,omitempty"`
+	DHCP6Overrides    *DHCPOverrides `yaml:"dhcp6-overrides,omitempty"`
+	LinkLocal         LinkLocal      `yaml:"link-local,omitempty"`
+	AcceptRA          *bool          `yaml:"accept-ra,omitempty"`
+	IPv6Privacy       *bool          `yaml:"ipv6-privacy,omitempty"`
+	IPv6MTU           int            `yaml:"ipv6-mtu,omitempty"`
+	IgnoreCarrier     *bool          `yaml:"ignore-carrier,omitempty"`
+	Gateway4          string         `yaml:"gateway4,omitempty"`
+	Gateway6          string         `yaml:"gateway6,omitempty"`
+	Nameservers       *Nameservers   `yaml:"nameservers,omitempty"`
+	MACAddress        string         `yaml:"macaddress,omitempty"`
+	WakeOnLAN         *bool          `yaml:"wakeonlan,omitempty"`
+	EmitLLDP          *bool          `yaml:"emit-lldp,omitempty"`
+	// Offloads
+	ReceiveChecksumOffload      *bool `yaml:"receive-checksum-offload,omitempty"`
+	TransmitChecksumOffload     *bool `yaml:"transmit-checksum-offload,omitempty"`
+	TCPSegmentationOffload      *bool `yaml:"tcp-segmentation-offload,omitempty"`
+	TCP6SegmentationOffload     *bool `yaml:"tcp6-segmentation-offload,omitempty"`
+	GenericSegmentationOffload  *bool `yaml:"generic-segmentation-offload,omitempty"`
+	GenericReceiveOffload       *bool `yaml:"generic-receive-offload,omitempty"`
+	LargeReceiveOffload         *bool `yaml:"large-receive-offload,omitempty"`
+	DelayVirtualFunctionsRebind *bool `yaml:"delay-virtual-functions-rebind,omitempty"`
+
+	Extra map[string]yaml.Node `yaml:",inline"`
+}
+
+// VLANInterface is a VLAN sub-interface on top of a parent ethernet
+type VLANInterface struct {
+	ID            int             `yaml:"id"`
+	Link          string          `yaml:"link"`
+	MACAddress    string          `yaml:"macaddress,omitempty"`
+	DHCP4         *bool           `yaml:"dhcp4,omitempty"`
+	MTU           int             `yaml:"mtu,omitempty"`
+	Addresses     AddressList     `yaml:"addresses,omitempty"`
+	Routes        []Route         `yaml:"routes,omitempty"`
+	RoutingPolicy []RoutingPolicy `yaml:"routing-policy,omitempty"`
+
+	Renderer          string         `yaml:"renderer,omitempty"`
+	Optional          *bool          `yaml:"optional,omitempty"`
+	OptionalAddresses []string       `yaml:"optional-addresses,omitempty"`
+	ActivationMode    string         `yaml:"activation-mode,omitempty"`
+	Critical          *bool          `yaml:"critical,omitempty"`
+	DHCP6             *bool          `yaml:"dhcp6,omitempty"`
+	DHCPIdentifier    string         `yaml:"dhcp-identifier,omitempty"`
+	DHCP4Overrides    *DHCPOverrides `yaml:"dhcp4-overrides,omitempty"`
+	DHCP6Overrides    *DHCPOverrides `yaml:"dhcp6-overrides,omitempty"`
+	LinkLocal         LinkLocal      `yaml:"link-local,omitempty"`
+	AcceptRA          *bool          `yaml:"accept-ra,omitempty"`
+	IPv6Privacy       *bool          `yaml:"ipv6-privacy,omitempty"`
+	IPv6MTU           int            `yaml:"ipv6-mtu,omitempty"`
+	IgnoreCarrier     *bool          `yaml:"ignore-carrier,omitempty"`
+	Gateway4          string         `yaml:"gateway4,omitempty"`
+	Gateway6          string         `yaml:"gateway6,omitempty"`
+	Nameservers       *Nameservers   `yaml:"nameservers,omitempty"`
+
+	Extra map[string]yaml.Node `yaml:",inline"`
+}
+
+// BondInterface is a bond composed of member ethernets
+type BondInterface struct {
+	Interfaces    []string        `yaml:"interfaces"`
+	DHCP4         *bool           `yaml:"dhcp4,omitempty"`
+	MTU           int             `yaml:"mtu,omitempty"`
+	Addresses     AddressList     `yaml:"addresses,omitempty"`
+	Routes        []Route         `yaml:"routes,omitempty"`
+	RoutingPolicy []RoutingPolicy `yaml:"routing-policy,omitempty"`
+	Parameters    *BondParameters `yaml:"parameters,omitempty"`
+
+	Renderer          string         `yaml:"renderer,omitempty"`
+	Optional          *bool          `yaml:"optional,omitempty"`
+	OptionalAddresses []string       `yaml:"optional-addresses,omitempty"`
+	ActivationMode    string         `yaml:"activation-mode,omitempty"`
+	Critical          *bool          `yaml:"critical,omitempty"`
+	DHCP6             *bool          `yaml:"dhcp6,omitempty"`
+	DHCPIdentifier    string         `yaml:"dhcp-identifier,omitempty"`
+	DHCP4Overrides    *DHCPOverrides `yaml:"dhcp4-overrides,omitempty"`
+	DHCP6Overrides    *DHCPOverrides `yaml:"dhcp6-overrides,omitempty"`
+	LinkLocal         LinkLocal      `yaml:"link-local,omitempty"`
+	AcceptRA          *bool          `yaml:"accept-ra,omitempty"`
+	IPv6Privacy       *bool          `yaml:"ipv6-privacy,omitempty"`
+	IPv6MTU           int            `yaml:"ipv6-mtu,omitempty"`
+	IgnoreCarrier     *bool          `yaml:"ignore-carrier,omitempty"`
+	Gateway4          string         `yaml:"gateway4,omitempty"`
+	Gateway6          string         `yaml:"gateway6,omitempty"`
+	Nameservers       *Nameservers   `yaml:"nameservers,omitempty"`
+	MACAddress        string         `yaml:"macaddress,omitempty"`
+
+	Extra map[string]yaml.Node `yaml:",inline"`
+}
+
+// BondParameters keeps intervals and delays as written, since netplan takes
+// plain milliseconds or durations such as "100ms"
+type BondParameters struct {
+	Mode               string `yaml:"mode,omitempty"`
+	LACPRate           string `yaml:"lacp-rate,omitempty"`
+	MIIMonitorInterval string `yaml:"mii-monitor-interval,omitempty"`
+	Primary            string `yaml:"primary,omitempty"`
+
+	MinLinks              int      `yaml:"min-links,omitempty"`
+	TransmitHashPolicy    string   `yaml:"transmit-hash-policy,omitempty"`
+	ADSelect              string   `yaml:"ad-select,omitempty"`
+	AllMembersActive      *bool    `yaml:"all-members-active,omitempty"`
+	ARPInterval           string   `yaml:"arp-interval,omitempty"`
+	ARPIPTargets          []string `yaml:"arp-ip-targets,omitempty"`
+	ARPValidate           string   `yaml:"arp-validate,omitempty"`
+	ARPAllTargets         string   `yaml:"arp-all-targets,omitempty"`
+	UpDelay               string   `yaml:"up-delay,omitempty"`
+	DownDelay             string   `yaml:"down-delay,omitempty"`
+	FailOverMACPolicy     string   `yaml:"fail-over-mac-policy,omitempty"`
+	GratuitousARP         int      `yaml:"gratuitous-arp,omitempty"`
+	PacketsPerMember      *int     `yaml:"packets-per-member,omitempty"`
+	PrimaryReselectPolicy string   `yaml:"primary-reselect-policy,omitempty"`
+	ResendIGMP            *int     `yaml:"resend-igmp,omitempty"`
+	LearnPacketInterval   string   `yaml:"learn-packet-interval,omitempty"`
+
+	Extra map[string]yaml.Node `yaml:",inline"`
+}
+
+// BridgeInterface is a Linux bridge composed of member ethernets
+type BridgeInterface struct {
+	Interfaces    []string          `yaml:"interfaces"`
+	DHCP4         *bool             `yaml:"dhcp4,omitempty"`
+	MTU           int               `yaml:"mtu,omitempty"`
+	Addresses     AddressList       `yaml:"addresses,omitempty"`
+	Routes        []Route           `yaml:"routes,omitempty"`
+	RoutingPolicy []RoutingPolicy   `yaml:"routing-policy,omitempty"`
+	Parameters    *BridgeParameters `yaml:"parameters,omitempty"`
+
+	Renderer          string         `yaml:"renderer,omitempty"`
+	Optional          *bool          `yaml:"optional,omitempty"`
+	OptionalAddresses []string       `yaml:"optional-addresses,omitempty"`
+	ActivationMode    string         `yaml:"activation-mode,omitempty"`
+	Critical          *bool          `yaml:"critical,omitempty"`
+	DHCP6             *bool          `yaml:"dhcp6,omitempty"`
+	DHCPIdentifier    string         `yaml:"dhcp-identifier,omitempty"`
+	DHCP4Overrides    *DHCPOverrides `yaml:"dhcp4-overrides,omitempty"`
+	DHCP6Overrides    *DHCPOverrides `yaml:"dhcp6-overrides,omitempty"`
+	LinkLocal         LinkLocal      `yaml:"link-local,omitempty"`
+	AcceptRA          *bool          `yaml:"accept-ra,omitempty"`
+	IPv6Privacy       *bool          `yaml:"ipv6-privacy,omitempty"`
+	IPv6MTU           int            `yaml:"ipv6-mtu,omitempty"`
+	IgnoreCarrier     *bool          `yaml:"ignore-carrier,omitempty"`
+	Gateway4          string         `yaml:"gateway4,omitempty"`
+	Gateway6          string         `yaml:"gateway6,omitempty"`
+	Nameservers       *Nameservers   `yaml:"nameservers,omitempty"`
+	MACAddress        string         `yaml:"macaddress,omitempty"`
+
+	Extra map[string]yaml.Node `yaml:",inline"`
+}
+
+type BridgeParameters struct {
+	STP *bool `yaml:"stp,omitempty"`
+
+	AgeingTime   string         `yaml:"ageing-time,omitempty"`
+	Priority     *int           `yaml:"priority,omitempty"`
+	PortPriority map[string]int `yaml:"port-priority,omitempty"`
+	ForwardDelay string         `yaml:"forward-delay,omitempty"`
+	HelloTime    string         `yaml:"hello-time,omitempty"`
+	MaxAge       string         `yaml:"max-age,omitempty"`
+	PathCost     map[string]int `yaml:"path-cost,omitempty"`
+
+	Extra map[string]yaml.Node `yaml:",inline"`
+}
+
+type MatchConfig struct {
+	MACAddress string `yaml:"macaddress,omitempty"`
+	Driver     string `yaml:"driver,omitempty"`
+	Name       string `yaml:"name,omitempty"`
+
+	Extra map[string]yaml.Node `yaml:",inline"`
+}
+
+type Route struct {
+	To     string `yaml:"to"`
+	Via    string `yaml:"via,omitempty"`
+	From   string `yaml:"from,omitempty"`
+	Scope  string `yaml:"scope,omitempty"`
+	Table  *int   `yaml:"table,omitempty"`
+	Metric *int   `yaml:"metric,omitempty"`
+
+	OnLink                  *bool  `yaml:"on-link,omitempty"`
+	Type                    string `yaml:"type,omitempty"`
+	MTU                     int    `yaml:"mtu,omitempty"`
+	CongestionWindow        int    `yaml:"congestion-window,omitempty"`
+	AdvertisedReceiveWindow int    `yaml:"advertised-receive-window,omitempty"`
+
+	Extra map[string]yaml.Node `yaml:",inline"`
+}
+
+// RoutingPolicy is a netplan routing-policy rule
+type RoutingPolicy struct {
+	From     string `yaml:"from,omitempty"`
+	To       string `yaml:"to,omitempty"`
+	Table    *int   `yaml:"table,omitempty"`
+	Priority *int   `yaml:"priority,omitempty"`
+
+	Mark          *int `yaml:"mark,omitempty"`
+	TypeOfService *int `yaml:"type-of-service,omitempty"`
+
+	Extra map[string]yaml.Node `yaml:",inline"`
+}
+
+// Nameservers are the static DNS settings of an interface
+type Nameservers struct {
+	Addresses []string `yaml:"addresses,omitempty"`
+	Search    []string `yaml:"search,omitempty"`
+
+	Extra map[string]yaml.Node `yaml:",inline"`
+}
+
+// DHCPOverrides tune which settings are taken from a DHCP lease
+type DHCPOverrides struct {
+	UseDNS       *bool  `yaml:"use-dns,omitempty"`
+	UseNTP       *bool  `yaml:"use-ntp,omitempty"`
+	SendHostname *bool  `yaml:"send-hostname,omitempty"`
+	UseHostname  *bool  `yaml:"use-hostname,omitempty"`
+	UseMTU       *bool  `yaml:"use-mtu,omitempty"`
+	Hostname     string `yaml:"hostname,omitempty"`
+	UseRoutes    *bool  `yaml:"use-routes,omitempty"`
+	RouteMetric  *int   `yaml:"route-metric,omitempty"`
+	UseDomains   string `yaml:"use-domains,omitempty"`
+
+	Extra map[string]yaml.Node `yaml:",inline"`
+}
+
+// LinkLocal lists the families (ipv4, ipv6) that get link-local addresses.
+// An empty list disables them and is written as [], unlike an unset one.
+type LinkLocal []string
+
+// IsZero makes omitempty drop only an unset list
+func (l LinkLocal) IsZero() bool {
+	return l == nil
+}
+
+// Address is an entry of an addresses list. netplan accepts a plain
+// "address/prefix" or a single-key map that adds options to it.
+type Address struct {
+	Address  string
+	Lifetime string
+	Label    string
+}
+
+type addressOptions struct {
+	Lifetime string `yaml:"lifetime,omitempty"`
+	Label    string `yaml:"label,omitempty"`
+}
+
+// MarshalYAML writes the plain form unless there are options
+func (a Address) MarshalYAML() (any, error) {
+	if a.Lifetime == "" && a.Label == "" {
+		return a.Address, nil
+	}
+	return map[string]addressOptions{a.Address: {Lifetime: a.Lifetime, Label: a.Label}}, nil
+}
+
+// UnmarshalYAML reads either form
+func (a *Address) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*a = Address{Address: node.Value}
+		return nil
+	case yaml.MappingNode:
+		if len(node.Content) != 2 {
+			return fmt.Errorf("line %d: address with options must have a single key", node.Line)
+		}
+		var options addressOptions
+		if err := node.Content[1].Decode(&options); err != nil {
+			return err
+		}
+		*a = Address{Address: node.Content[0].Value, Lifetime: options.Lifetime, Label: options.Label}
+		return nil
+	default:
+		return fmt.Errorf("line %d: invalid address", node.Line)
+	}
+}
+
+// AddressList is the addresses of an interface
+type AddressList []Address
+
+// Addresses wraps plain "address/prefix" strings
+func Addresses(addresses ...string) AddressList {
+	if len(addresses) == 0 {
+		return nil
+	}
+	list := make(AddressList, 0, len(addresses))
+	for _, address := range addresses {
+		list = append(list, Address{Address: address})
+	}
+	return list
+}
+
+// Strings returns the addresses without their options
+func (l AddressList) Strings() []string {
+	addresses := make([]string, 0, len(l))
+	for _, address := range l {
+		addresses = append(addresses, address.Address)
+	}
+	return addresses
+}
+
+// ParseNetplanConfig parses a netplan YAML document
+func ParseNetplanConfig(data []byte) (*NetplanConfig, error) {
+	config := &NetplanConfig{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ReadNetplanConfig reads and parses a netplan file
+func ReadNetplanConfig(path string) (*NetplanConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseNetplanConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Marshal encodes the configuration as netplan YAML
+func (c *NetplanConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
@@ -0,0 +1,206 @@
+package netplan
+
+import "testing"
+
+func TestNetplanConfigRoundTrip(t *testing.T) {
+	const input = `network:
+  version: 2
+  ethernets:
+    eth1:
+      match:
+        macaddress: aa:00:00:00:00:01
+      set-name: eth1
+      dhcp4: false
+    eth2:
+      match:
+        macaddress: aa:00:00:00:00:02
+      set-name: eth2
+      dhcp4: false
+  bonds:
+    bond0:
+      interfaces: [eth1, eth2]
+      addresses: [10.0.0.5/24]
+      routes:
+        - to: 10.1.0.0/16
+          via: 10.0.0.1
+          metric: 0
+        - to: 10.2.0.0/16
+          via: 10.0.0.1
+      parameters:
+        mode: active-backup
+        mii-monitor-interval: 100ms
+        arp-interval: "200"
+        up-delay: 1s
+        down-delay: 500ms
+        learn-packet-interval: 1min
+`
+	config, err := ParseNetplanConfig([]byte(input))
+	if err != nil {
+		t.Fatalf("ParseNetplanConfig: %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	data, err := config.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	again, err := ParseNetplanConfig(data)
+	if err != nil {
+		t.Fatalf("parsing marshalled config: %v\n%s", err, data)
+	}
+
+	bond := again.Network.Bonds["bond0"]
+	params := bond.Parameters
+	got := map[string]string{
+		"mii-monitor-interval":  params.MIIMonitorInterval,
+		"arp-interval":          params.ARPInterval,
+		"up-delay":              params.UpDelay,
+		"down-delay":            params.DownDelay,
+		"learn-packet-interval": params.LearnPacketInterval,
+	}
+	want := map[string]string{
+		"mii-monitor-interval":  "100ms",
+		"arp-interval":          "200",
+		"up-delay":              "1s",
+		"down-delay":            "500ms",
+		"learn-packet-interval": "1min",
+	}
+	for key := range want {
+		if got[key] != want[key] {
+			t.Errorf("%s = %q, want %q", key, got[key], want[key])
+		}
+	}
+
+	if len(bond.Routes) != 2 {
+		t.Fatalf("routes = %+v", bond.Routes)
+	}
+	if metric := bond.Routes[0].Metric; metric == nil || *metric != 0 {
+		t.Errorf("explicit metric 0 lost: %v", metric)
+	}
+	if metric := bond.Routes[1].Metric; metric != nil {
+		t.Errorf("unset metric became %d", *metric)
+	}
+}
+
+func TestValidateBondDurations(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{"100", true},
+		{"100ms", true},
+		{"1.5s", true},
+		{"2min", true},
+		{"-100", false},
+		{"fast", false},
+		{"100 ms", false},
+	}
+	for _, tt := range tests {
+		config := &NetplanConfig{Network: NetworkConfig{
+			Version: 2,
+			Ethernets: map[string]EthernetInterface{
+				"eth1": {Match: &MatchConfig{MACAddress: "aa:00:00:00:00:01"}, SetName: "eth1"},
+			},
+			Bonds: map[string]BondInterface{
+				"bond0": {Interfaces: []string{"eth1"}, Parameters: &BondParameters{Mode: "active-backup", UpDelay: tt.value}},
+			},
+		}}
+		if err := config.Validate(); (err == nil) != tt.valid {
+			t.Errorf("up-delay %q: Validate() = %v, want valid = %v", tt.value, err, tt.valid)
+		}
+	}
+}
+
+func TestMilliseconds(t *testing.T) {
+	for ms, want := range map[int]string{0: "", 100: "100"} {
+		if got := milliseconds(ms); got != want {
+			t.Errorf("milliseconds(%d) = %q, want %q", ms, got, want)
+		}
+	}
+}
+
+// Keys the model does not know, and explicit zeros, must survive a parse
+// and marshal unchanged. The fixture is in the marshaller's layout so the
+// output can be compared byte for byte.
+func TestNetplanConfigKeepsUnknownKeys(t *testing.T) {
+	const input = `network:
+    version: 2
+    renderer: networkd
+    ethernets:
+        eno1:
+            match:
+                macaddress: aa:00:00:00:00:01
+            set-name: eno1
+            dhcp4: true
+            dhcp4-overrides:
+                route-metric: 0
+                x-lease-hint: short
+            wakeonlan: true
+            x-vendor-tuning:
+                ring-size: 4096
+    vlans:
+        vlan10:
+            id: 10
+            link: eno1
+            routes:
+                - to: default
+                  via: 10.0.10.1
+                  table: 0
+            routing-policy:
+                - from: 10.0.10.5
+                  table: 100
+                  priority: 0
+                  mark: 0
+    bonds:
+        bond0:
+            interfaces:
+                - eno2
+            parameters:
+                mode: balance-rr
+                packets-per-member: 0
+                resend-igmp: 0
+    bridges:
+        br0:
+            interfaces:
+                - eno3
+            parameters:
+                priority: 0
+    tunnels:
+        gre1:
+            mode: gre
+            local: 10.0.0.1
+            remote: 10.0.0.2
+    wifis:
+        wlan0:
+            access-points:
+                corp:
+                    password: secret
+x-custom:
+    owner: ops
+`
+	config, err := ParseNetplanConfig([]byte(input))
+	if err != nil {
+		t.Fatalf("ParseNetplanConfig: %v", err)
+	}
+	for _, key := range []string{"tunnels", "wifis"} {
+		if _, ok := config.Network.Extra[key]; !ok {
+			t.Errorf("network.%s not kept in Extra", key)
+		}
+	}
+	if _, ok := config.Extra["x-custom"]; !ok {
+		t.Error("top-level x-custom not kept in Extra")
+	}
+	if _, ok := config.Network.Ethernets["eno1"].Extra["x-vendor-tuning"]; !ok {
+		t.Error("ethernets.eno1.x-vendor-tuning not kept in Extra")
+	}
+
+	data, err := config.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(data) != input {
+		t.Errorf("round trip changed the file:\n%s\nwant\n%s", data, input)
+	}
+}
@@ -0,0 +1,219 @@
+package sriov
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/pkg/sysfs"
+)
+
+// Manager configures SR-IOV physical functions and resolves virtual
+// functions through sysfs
+type Manager struct {
+	fs     sysfs.FS
+	logger *zap.Logger
+}
+
+// Device is a PCI network function and its netdev
+type Device struct {
+	PCIAddress string
+	Name       string
+	MACAddress string
+	// PFPCIAddress is set for virtual functions
+	PFPCIAddress string
+}
+
+// NewManager creates a new SR-IOV Manager
+func NewManager(fs sysfs.FS, logger *zap.Logger) *Manager {
+	return &Manager{
+		fs:     fs,
+		logger: logger,
+	}
+}
+
+func devicePath(pciAddress string, attr ...string) string {
+	return path.Join(append([]string{"bus/pci/devices", pciAddress}, attr...)...)
+}
+
+// TotalVFs returns the maximum number of VFs the PF supports
+func (m *Manager) TotalVFs(pfPCI string) (int, error) {
+	return m.readInt(devicePath(pfPCI, "sriov_totalvfs"))
+}
+
+// NumVFs returns the number of VFs currently enabled on the PF
+func (m *Manager) NumVFs(pfPCI string) (int, error) {
+	return m.readInt(devicePath(pfPCI, "sriov_numvfs"))
+}
+
+// EnsureVFCount sets the number of VFs on the PF. inUse holds the PCI
+// addresses or MACs of VFs that back ports; removing any of them is
+// refused. Some drivers refuse to change a non-zero count directly (EBUSY),
+// in which case the count is reset to 0 first, tearing down every VF; that
+// reset is refused as well while any VF backs a port.
+func (m *Manager) EnsureVFCount(pfPCI string, count int, inUse []string) error {
+	total, err := m.TotalVFs(pfPCI)
+	if err != nil {
+		return fmt.Errorf("PF %s does not support SR-IOV: %w", pfPCI, err)
+	}
+	if count > total {
+		return fmt.Errorf("PF %s supports at most %d VFs, %d requested", pfPCI, total, count)
+	}
+
+	current, err := m.NumVFs(pfPCI)
+	if err != nil {
+		return fmt.Errorf("failed to read VF count of %s: %w", pfPCI, err)
+	}
+	if current == count {
+		return nil
+	}
+
+	vfs, err := m.VFs(pfPCI)
+	if err != nil {
+		return fmt.Errorf("failed to list VFs of %s: %w", pfPCI, err)
+	}
+	removed := vfs[min(count, len(vfs)):]
+	if busy := usedVFs(removed, inUse); len(busy) > 0 {
+		return fmt.Errorf("refusing to reduce VFs of %s from %d to %d: VFs %s back ports",
+			pfPCI, current, count, strings.Join(busy, ", "))
+	}
+
+	numVFsPath := devicePath(pfPCI, "sriov_numvfs")
+	if len(removed) > 0 {
+		m.logger.Warn("Removing SR-IOV VFs",
+			zap.String("pf", pfPCI),
+			zap.Strings("vfs", describeVFs(removed)))
+	}
+	err = m.fs.WriteFile(numVFsPath, strconv.Itoa(count))
+	if errors.Is(err, syscall.EBUSY) && current != 0 {
+		if busy := usedVFs(vfs, inUse); len(busy) > 0 {
+			return fmt.Errorf("PF %s refuses to change its VF count from %d to %d directly, "+
+				"refusing to reset it: VFs %s back ports", pfPCI, current, count, strings.Join(busy, ", "))
+		}
+		m.logger.Warn("PF refuses to change a non-zero VF count, resetting it and tearing down all VFs",
+			zap.String("pf", pfPCI),
+			zap.Strings("vfs", describeVFs(vfs)))
+		if err := m.fs.WriteFile(numVFsPath, "0"); err != nil {
+			return fmt.Errorf("failed to reset VF count of %s: %w", pfPCI, err)
+		}
+		err = m.fs.WriteFile(numVFsPath, strconv.Itoa(count))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set VF count of %s: %w", pfPCI, err)
+	}
+
+	m.logger.Info("Configured SR-IOV VF count",
+		zap.String("pf", pfPCI),
+		zap.Int("previous", current),
+		zap.Int("vf_count", count))
+
+	return nil
+}
+
+// VFs returns the PF's enabled VFs ordered by VF index, resolved to their
+// netdev where they have one
+func (m *Manager) VFs(pfPCI string) ([]Device, error) {
+	entries, err := m.fs.ReadDir(devicePath(pfPCI))
+	if err != nil {
+		return nil, err
+	}
+
+	byIndex := make(map[int]string)
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry, "virtfn") {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimPrefix(entry, "virtfn"))
+		if err != nil {
+			continue
+		}
+		target, err := m.fs.Readlink(devicePath(pfPCI, entry))
+		if err != nil {
+			return nil, err
+		}
+		byIndex[index] = path.Base(target)
+	}
+
+	vfs := make([]Device, 0, len(byIndex))
+	for _, index := range slices.Sorted(maps.Keys(byIndex)) {
+		vf := Device{PCIAddress: byIndex[index], PFPCIAddress: pfPCI}
+		// VFs bound to vfio or without a driver have no netdev
+		if device, err := m.Resolve(vf.PCIAddress); err == nil {
+			vf.Name = device.Name
+			vf.MACAddress = device.MACAddress
+		}
+		vfs = append(vfs, vf)
+	}
+	return vfs, nil
+}
+
+// usedVFs returns the VFs matching an inUse PCI address or MAC
+func usedVFs(vfs []Device, inUse []string) []string {
+	var used []string
+	for _, vf := range vfs {
+		for _, id := range inUse {
+			if strings.EqualFold(id, vf.PCIAddress) || (vf.MACAddress != "" && strings.EqualFold(id, vf.MACAddress)) {
+				used = append(used, vf.PCIAddress)
+				break
+			}
+		}
+	}
+	return used
+}
+
+// describeVFs formats VFs as "pci (netdev)" for logs
+func describeVFs(vfs []Device) []string {
+	described := make([]string, 0, len(vfs))
+	for _, vf := range vfs {
+		if vf.Name != "" {
+			described = append(described, fmt.Sprintf("%s (%s)", vf.PCIAddress, vf.Name))
+		} else {
+			described = append(described, vf.PCIAddress)
+		}
+	}
+	return described
+}
+
+// Resolve returns the netdev of a PCI network function. For a VF the
+// parent PF address is resolved through the physfn link.
+func (m *Manager) Resolve(pciAddress string) (*Device, error) {
+	names, err := m.fs.ReadDir(devicePath(pciAddress, "net"))
+	if err != nil {
+		return nil, fmt.Errorf("no netdev for PCI device %s: %w", pciAddress, err)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no netdev for PCI device %s", pciAddress)
+	}
+
+	name := names[0]
+	mac, err := m.fs.ReadFile(devicePath(pciAddress, "net", name, "address"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MAC of %s: %w", name, err)
+	}
+
+	device := &Device{
+		PCIAddress: pciAddress,
+		Name:       name,
+		MACAddress: strings.ToLower(mac),
+	}
+	if target, err := m.fs.Readlink(devicePath(pciAddress, "physfn")); err == nil {
+		device.PFPCIAddress = path.Base(target)
+	}
+
+	return device, nil
+}
+
+func (m *Manager) readInt(attr string) (int, error) {
+	value, err := m.fs.ReadFile(attr)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
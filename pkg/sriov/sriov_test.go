@@ -0,0 +1,201 @@
+package sriov
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/ibyeong-geon/multinic-agent/pkg/sysfs"
+)
+
+const testPF = "0000:3b:00.0"
+
+// fakeVF is a VF in the fake sysfs tree; an empty netdev means no driver
+type fakeVF struct {
+	pci    string
+	netdev string
+	mac    string
+}
+
+// newFakeSysfs builds a sysfs tree with one PF exposing the given VFs
+func newFakeSysfs(t *testing.T, totalVFs int, vfs ...fakeVF) string {
+	t.Helper()
+	root := t.TempDir()
+	devices := filepath.Join(root, "bus/pci/devices")
+
+	write := func(path, value string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(value+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	symlink := func(target, path string) {
+		t.Helper()
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pf := filepath.Join(devices, testPF)
+	write(filepath.Join(pf, "sriov_totalvfs"), strconv.Itoa(totalVFs))
+	write(filepath.Join(pf, "sriov_numvfs"), strconv.Itoa(len(vfs)))
+	write(filepath.Join(pf, "net/ens1f0/address"), "3C:FD:FE:00:00:01")
+
+	for i, vf := range vfs {
+		dir := filepath.Join(devices, vf.pci)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if vf.netdev != "" {
+			write(filepath.Join(dir, "net", vf.netdev, "address"), vf.mac)
+		}
+		symlink("../"+testPF, filepath.Join(dir, "physfn"))
+		symlink("../"+vf.pci, filepath.Join(pf, "virtfn"+strconv.Itoa(i)))
+	}
+	return root
+}
+
+// recordingFS records the values written. With busy set it refuses to
+// change a non-zero sriov_numvfs directly, like drivers that return EBUSY.
+type recordingFS struct {
+	sysfs.FS
+	busy   bool
+	writes []string
+}
+
+func (fs *recordingFS) WriteFile(path, value string) error {
+	if fs.busy && strings.HasSuffix(path, "sriov_numvfs") {
+		current, _ := fs.ReadFile(path)
+		if current != "0" && value != "0" {
+			return &os.PathError{Op: "write", Path: path, Err: syscall.EBUSY}
+		}
+	}
+	fs.writes = append(fs.writes, value)
+	return fs.FS.WriteFile(path, value)
+}
+
+func TestEnsureVFCount(t *testing.T) {
+	vfs := []fakeVF{
+		{pci: "0000:3b:02.0", netdev: "ens1f0v0", mac: "02:00:00:00:00:00"},
+		{pci: "0000:3b:02.1", netdev: "ens1f0v1", mac: "02:00:00:00:00:01"},
+		{pci: "0000:3b:02.2"},
+		{pci: "0000:3b:02.3", netdev: "ens1f0v3", mac: "02:00:00:00:00:03"},
+	}
+
+	tests := []struct {
+		name       string
+		existing   []fakeVF
+		count      int
+		inUse      []string
+		busy       bool
+		wantErr    string
+		wantWrites []string
+	}{
+		{name: "unchanged", existing: vfs, count: 4},
+		{name: "enable from zero", count: 4, wantWrites: []string{"4"}},
+		{name: "grow directly", existing: vfs[:2], count: 4, wantWrites: []string{"4"}},
+		{name: "grow resets on EBUSY", existing: vfs[:2], count: 4, busy: true, wantWrites: []string{"0", "4"}},
+		{name: "grow refuses reset on EBUSY with VF in use", existing: vfs[:2], count: 4, busy: true, inUse: []string{"02:00:00:00:00:00"}, wantErr: "refusing to reset"},
+		{name: "shrink past unused VFs", existing: vfs, count: 2, inUse: []string{"0000:3b:02.0", "02:00:00:00:00:01"}, wantWrites: []string{"2"}},
+		{name: "shrink below VF in use by PCI", existing: vfs, count: 3, inUse: []string{"0000:3b:02.3"}, wantErr: "0000:3b:02.3"},
+		{name: "shrink below VF in use by MAC", existing: vfs, count: 1, inUse: []string{"02:00:00:00:00:01"}, wantErr: "0000:3b:02.1"},
+		{name: "more than total", existing: vfs, count: 9, wantErr: "at most 8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := newFakeSysfs(t, 8, tt.existing...)
+			fs := &recordingFS{FS: sysfs.New(root), busy: tt.busy}
+			m := NewManager(fs, zap.NewNop())
+
+			err := m.EnsureVFCount(testPF, tt.count, tt.inUse)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("EnsureVFCount error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				if len(fs.writes) != 0 {
+					t.Errorf("wrote %v after refusing", fs.writes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EnsureVFCount: %v", err)
+			}
+			if strings.Join(fs.writes, ",") != strings.Join(tt.wantWrites, ",") {
+				t.Errorf("writes = %v, want %v", fs.writes, tt.wantWrites)
+			}
+			if got, _ := m.NumVFs(testPF); got != tt.count {
+				t.Errorf("sriov_numvfs = %d, want %d", got, tt.count)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	root := newFakeSysfs(t, 8,
+		fakeVF{pci: "0000:3b:02.0", netdev: "ens1f0v0", mac: "02:AA:BB:CC:DD:00"},
+		fakeVF{pci: "0000:3b:02.1"},
+	)
+	m := NewManager(sysfs.New(root), zap.NewNop())
+
+	tests := []struct {
+		name    string
+		pci     string
+		want    Device
+		wantErr bool
+	}{
+		{
+			name: "VF",
+			pci:  "0000:3b:02.0",
+			want: Device{PCIAddress: "0000:3b:02.0", Name: "ens1f0v0", MACAddress: "02:aa:bb:cc:dd:00", PFPCIAddress: testPF},
+		},
+		{
+			name: "PF",
+			pci:  testPF,
+			want: Device{PCIAddress: testPF, Name: "ens1f0", MACAddress: "3c:fd:fe:00:00:01"},
+		},
+		{name: "VF without netdev", pci: "0000:3b:02.1", wantErr: true},
+		{name: "missing device", pci: "0000:af:00.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Resolve(tt.pci)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Resolve(%s) = %+v, want error", tt.pci, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%s): %v", tt.pci, err)
+			}
+			if *got != tt.want {
+				t.Errorf("Resolve(%s) = %+v, want %+v", tt.pci, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVFs(t *testing.T) {
+	root := newFakeSysfs(t, 8,
+		fakeVF{pci: "0000:3b:02.0", netdev: "ens1f0v0", mac: "02:00:00:00:00:00"},
+		fakeVF{pci: "0000:3b:02.1"},
+	)
+	m := NewManager(sysfs.New(root), zap.NewNop())
+
+	vfs, err := m.VFs(testPF)
+	if err != nil {
+		t.Fatalf("VFs: %v", err)
+	}
+	want := []string{"0000:3b:02.0 (ens1f0v0)", "0000:3b:02.1"}
+	if got := describeVFs(vfs); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("VFs = %v, want %v", got, want)
+	}
+}
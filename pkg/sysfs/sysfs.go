@@ -0,0 +1,83 @@
+package sysfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultRoot is the mount point of sysfs on the host
+const DefaultRoot = "/sys"
+
+// FS provides access to sysfs attributes relative to a root directory.
+// Pointing the root at a directory tree that mirrors /sys gives a fake
+// sysfs for tests and offline tools.
+type FS interface {
+	// ReadFile returns the trimmed content of an attribute
+	ReadFile(path string) (string, error)
+	// WriteFile writes a value to an attribute
+	WriteFile(path, value string) error
+	// ReadDir returns the entry names of a directory
+	ReadDir(path string) ([]string, error)
+	// Readlink returns the target of a symlink
+	Readlink(path string) (string, error)
+	// Exists reports whether the path exists
+	Exists(path string) bool
+}
+
+type dirFS struct {
+	root string
+}
+
+// New returns an FS rooted at root
+func New(root string) FS {
+	return &dirFS{root: root}
+}
+
+func (fs *dirFS) abs(path string) string {
+	return filepath.Join(fs.root, filepath.Clean("/"+path))
+}
+
+func (fs *dirFS) ReadFile(path string) (string, error) {
+	data, err := os.ReadFile(fs.abs(path))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (fs *dirFS) WriteFile(path, value string) error {
+	// sysfs attributes must be written in a single write without truncation
+	f, err := os.OpenFile(fs.abs(path), os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(value); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+func (fs *dirFS) ReadDir(path string) ([]string, error) {
+	entries, err := os.ReadDir(fs.abs(path))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names, nil
+}
+
+func (fs *dirFS) Readlink(path string) (string, error) {
+	return os.Readlink(fs.abs(path))
+}
+
+func (fs *dirFS) Exists(path string) bool {
+	_, err := os.Stat(fs.abs(path))
+	return err == nil
+}
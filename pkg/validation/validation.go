@@ -0,0 +1,196 @@
+// Package validation checks database rows before they are rendered into
+// netplan. A bad row is rejected on its own with a reason so the rest of the
+// node's ports can still be configured.
+package validation
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/ibyeong-geon/multinic-agent/pkg/database"
+	"github.com/ibyeong-geon/multinic-agent/pkg/netplan"
+)
+
+// Problem kinds
+const (
+	KindInvalidMAC      = "invalid-mac"
+	KindInvalidCIDR     = "invalid-cidr"
+	KindInvalidIP       = "invalid-ip"
+	KindInvalidVLAN     = "invalid-vlan"
+	KindDuplicateMAC    = "duplicate-mac"
+	KindDuplicateSubnet = "duplicate-subnet"
+	KindParentRejected  = "parent-rejected"
+	KindInvalidOption   = "invalid-option"
+)
+
+// Problem is one reason a port was rejected
+type Problem struct {
+	PortID  string `json:"port_id"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// Result splits a node's rows into the ones safe to render and the problems
+// found in the others
+type Result struct {
+	Valid    []database.NodeInterface
+	Problems []Problem
+}
+
+// Rejected reports whether the port has at least one problem
+func (r *Result) Rejected(portID string) bool {
+	for _, problem := range r.Problems {
+		if problem.PortID == portID {
+			return true
+		}
+	}
+	return false
+}
+
+// Reason joins the port's problems into one line, or "" if it is valid
+func (r *Result) Reason(portID string) string {
+	var messages []string
+	for _, problem := range r.Problems {
+		if problem.PortID == portID {
+			messages = append(messages, problem.Message)
+		}
+	}
+	if len(messages) == 0 {
+		return ""
+	}
+	return "invalid port data: " + strings.Join(messages, "; ")
+}
+
+// ValidateInterfaces checks one node's rows. Rows keep their order in Valid.
+//
+// Ports sharing a MAC or a subnet are all rejected since there is no way to
+// tell which one is right. Trunk subports are exempt from both checks (they
+// may share the parent's MAC) and so are bond/bridge members, which share the
+// group's subnet by design; a subport whose parent is rejected is rejected too.
+func ValidateInterfaces(interfaces []database.NodeInterface) *Result {
+	result := &Result{}
+	add := func(portID, kind, format string, args ...any) {
+		result.Problems = append(result.Problems, Problem{
+			PortID:  portID,
+			Kind:    kind,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	macPorts := make(map[string][]string)
+	subnetPorts := make(map[string][]string)
+	for _, iface := range interfaces {
+		checkRow(iface, add)
+
+		if iface.ParentPortID != "" {
+			continue
+		}
+		if mac, err := net.ParseMAC(iface.MacAddress); err == nil {
+			macPorts[mac.String()] = append(macPorts[mac.String()], iface.PortID)
+		}
+		if iface.Group == nil && iface.SubnetID != "" {
+			subnetPorts[iface.SubnetID] = append(subnetPorts[iface.SubnetID], iface.PortID)
+		}
+	}
+
+	for _, mac := range sortedKeys(macPorts) {
+		ports := macPorts[mac]
+		if len(ports) < 2 {
+			continue
+		}
+		for _, portID := range ports {
+			add(portID, KindDuplicateMAC, "MAC %s is shared by ports %s", mac, strings.Join(ports, ", "))
+		}
+	}
+	subnetNames := make(map[string]string)
+	for _, iface := range interfaces {
+		subnetNames[iface.SubnetID] = iface.SubnetName
+	}
+	for _, subnetID := range sortedKeys(subnetPorts) {
+		ports := subnetPorts[subnetID]
+		if len(ports) < 2 {
+			continue
+		}
+		for _, portID := range ports {
+			add(portID, KindDuplicateSubnet, "subnet %s is attached to ports %s", subnetNames[subnetID], strings.Join(ports, ", "))
+		}
+	}
+
+	for _, iface := range interfaces {
+		if iface.ParentPortID != "" && result.Rejected(iface.ParentPortID) {
+			add(iface.PortID, KindParentRejected, "parent port %s was rejected", iface.ParentPortID)
+		}
+	}
+
+	for _, iface := range interfaces {
+		if !result.Rejected(iface.PortID) {
+			result.Valid = append(result.Valid, iface)
+		}
+	}
+	return result
+}
+
+// checkRow checks the fields of a single row
+func checkRow(iface database.NodeInterface, add func(portID, kind, format string, args ...any)) {
+	if mac, err := net.ParseMAC(iface.MacAddress); err != nil || len(mac) != 6 {
+		add(iface.PortID, KindInvalidMAC, "%q is not an Ethernet MAC address", iface.MacAddress)
+	}
+	if _, _, err := net.ParseCIDR(iface.CIDR); err != nil {
+		add(iface.PortID, KindInvalidCIDR, "subnet %s has invalid CIDR %q", iface.SubnetName, iface.CIDR)
+	}
+	if iface.ParentPortID != "" && (iface.VLANID < 1 || iface.VLANID > 4094) {
+		add(iface.PortID, KindInvalidVLAN, "VLAN ID %d is out of range 1-4094", iface.VLANID)
+	}
+
+	if iface.DHCP4RouteMetric != nil && *iface.DHCP4RouteMetric < 0 {
+		add(iface.PortID, KindInvalidOption, "subnet %s has negative DHCP route metric %d", iface.SubnetName, *iface.DHCP4RouteMetric)
+	}
+	for _, family := range netplan.ParseLinkLocal(iface.LinkLocal) {
+		if family != "ipv4" && family != "ipv6" {
+			add(iface.PortID, KindInvalidOption, "subnet %s has unknown link-local family %q (ipv4 or ipv6)", iface.SubnetName, family)
+		}
+	}
+
+	// The port gets a single routing table
+	pinned := database.FixedIP{}
+	for _, ip := range iface.FixedIPs {
+		if ip.RouteTable == 0 {
+			continue
+		}
+		if pinned.RouteTable != 0 && ip.RouteTable != pinned.RouteTable {
+			add(iface.PortID, KindInvalidOption, "subnets %s and %s pin different route tables %d and %d",
+				pinned.SubnetName, ip.SubnetName, pinned.RouteTable, ip.RouteTable)
+			break
+		}
+		pinned = ip
+	}
+
+	for _, ip := range iface.FixedIPs {
+		_, network, err := net.ParseCIDR(ip.CIDR)
+		if err != nil {
+			// Already reported above when it is the port's own subnet
+			if ip.SubnetID != iface.SubnetID {
+				add(iface.PortID, KindInvalidCIDR, "subnet %s has invalid CIDR %q", ip.SubnetName, ip.CIDR)
+			}
+			continue
+		}
+		address := net.ParseIP(ip.IPAddress)
+		switch {
+		case address == nil:
+			add(iface.PortID, KindInvalidIP, "%q is not an IP address", ip.IPAddress)
+		case !network.Contains(address):
+			add(iface.PortID, KindInvalidIP, "%s is outside subnet %s (%s)", ip.IPAddress, ip.SubnetName, ip.CIDR)
+		}
+	}
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
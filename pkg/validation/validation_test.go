@@ -0,0 +1,248 @@
+package validation
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ibyeong-geon/multinic-agent/pkg/database"
+)
+
+// port builds a valid row on its own subnet 10.0.<n>.0/24
+func port(id, mac, subnet string) database.NodeInterface {
+	cidr := "10.0." + subnet + ".0/24"
+	return database.NodeInterface{
+		PortID:     id,
+		MacAddress: mac,
+		SubnetID:   "subnet-" + subnet,
+		SubnetName: "net-" + subnet,
+		CIDR:       cidr,
+		FixedIPs: []database.FixedIP{{
+			PortID:     id,
+			IPAddress:  "10.0." + subnet + ".5",
+			SubnetID:   "subnet-" + subnet,
+			SubnetName: "net-" + subnet,
+			CIDR:       cidr,
+		}},
+	}
+}
+
+// subport makes the row a trunk subport of parent
+func subport(iface database.NodeInterface, parent string, vlan int) database.NodeInterface {
+	iface.ParentPortID = parent
+	iface.VLANID = vlan
+	return iface
+}
+
+// member puts the row into a bond
+func member(iface database.NodeInterface) database.NodeInterface {
+	iface.Group = &database.InterfaceGroup{GroupID: "g1", GroupName: "bond0", GroupType: "bond", BondMode: "active-backup"}
+	return iface
+}
+
+func withFixedIP(iface database.NodeInterface, ip database.FixedIP) database.NodeInterface {
+	iface.FixedIPs = append(slices.Clone(iface.FixedIPs), ip)
+	return iface
+}
+
+func TestValidateInterfaces(t *testing.T) {
+	tests := []struct {
+		name       string
+		interfaces []database.NodeInterface
+		wantValid  []string
+		// wantProblems lists "port kind" in the order they are reported
+		wantProblems []string
+	}{
+		{
+			name: "valid",
+			interfaces: []database.NodeInterface{
+				port("p1", "fa:16:3e:00:00:01", "1"),
+				port("p2", "fa:16:3e:00:00:02", "2"),
+			},
+			wantValid: []string{"p1", "p2"},
+		},
+		{
+			name: "invalid MAC",
+			interfaces: []database.NodeInterface{
+				port("p1", "fa:16:3e:00:00", "1"),
+				port("p2", "00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:02", "2"),
+			},
+			wantProblems: []string{"p1 invalid-mac", "p2 invalid-mac"},
+		},
+		{
+			name: "duplicate MAC",
+			interfaces: []database.NodeInterface{
+				port("p1", "fa:16:3e:00:00:01", "1"),
+				port("p2", "FA:16:3E:00:00:01", "2"),
+				port("p3", "fa:16:3e:00:00:03", "3"),
+			},
+			wantValid:    []string{"p3"},
+			wantProblems: []string{"p1 duplicate-mac", "p2 duplicate-mac"},
+		},
+		{
+			name: "duplicate subnet",
+			interfaces: []database.NodeInterface{
+				port("p1", "fa:16:3e:00:00:01", "1"),
+				port("p2", "fa:16:3e:00:00:02", "1"),
+				port("p3", "fa:16:3e:00:00:03", "3"),
+			},
+			wantValid:    []string{"p3"},
+			wantProblems: []string{"p1 duplicate-subnet", "p2 duplicate-subnet"},
+		},
+		{
+			// A subport may share the parent's MAC and sit on its subnet
+			name: "trunk subports are exempt",
+			interfaces: []database.NodeInterface{
+				port("p1", "fa:16:3e:00:00:01", "1"),
+				subport(port("s1", "fa:16:3e:00:00:01", "1"), "p1", 10),
+				subport(port("s2", "fa:16:3e:00:00:01", "2"), "p1", 20),
+			},
+			wantValid: []string{"p1", "s1", "s2"},
+		},
+		{
+			name: "bond members share the subnet",
+			interfaces: []database.NodeInterface{
+				member(port("p1", "fa:16:3e:00:00:01", "1")),
+				member(port("p2", "fa:16:3e:00:00:02", "1")),
+			},
+			wantValid: []string{"p1", "p2"},
+		},
+		{
+			name: "bond members must not share a MAC",
+			interfaces: []database.NodeInterface{
+				member(port("p1", "fa:16:3e:00:00:01", "1")),
+				member(port("p2", "fa:16:3e:00:00:01", "1")),
+			},
+			wantProblems: []string{"p1 duplicate-mac", "p2 duplicate-mac"},
+		},
+		{
+			name: "subport VLAN out of range",
+			interfaces: []database.NodeInterface{
+				port("p1", "fa:16:3e:00:00:01", "1"),
+				subport(port("s1", "fa:16:3e:00:00:01", "2"), "p1", 4095),
+				subport(port("s2", "fa:16:3e:00:00:01", "3"), "p1", 0),
+			},
+			wantValid:    []string{"p1"},
+			wantProblems: []string{"s1 invalid-vlan", "s2 invalid-vlan"},
+		},
+		{
+			name: "parent rejected cascades to subports",
+			interfaces: []database.NodeInterface{
+				port("p1", "fa:16:3e:00:00:01", "1"),
+				port("p2", "fa:16:3e:00:00:01", "2"),
+				subport(port("s1", "fa:16:3e:00:00:01", "3"), "p1", 10),
+				port("p3", "fa:16:3e:00:00:03", "4"),
+				subport(port("s3", "fa:16:3e:00:00:03", "5"), "p3", 10),
+			},
+			wantValid:    []string{"p3", "s3"},
+			wantProblems: []string{"p1 duplicate-mac", "p2 duplicate-mac", "s1 parent-rejected"},
+		},
+		{
+			name: "invalid CIDR",
+			interfaces: []database.NodeInterface{func() database.NodeInterface {
+				iface := port("p1", "fa:16:3e:00:00:01", "1")
+				iface.CIDR = "10.0.1.0/33"
+				iface.FixedIPs[0].CIDR = iface.CIDR
+				return iface
+			}()},
+			// Reported once for the port's own subnet
+			wantProblems: []string{"p1 invalid-cidr"},
+		},
+		{
+			name: "fixed IP outside its subnet",
+			interfaces: []database.NodeInterface{
+				withFixedIP(port("p1", "fa:16:3e:00:00:01", "1"), database.FixedIP{
+					IPAddress: "10.0.9.5", SubnetID: "subnet-2", SubnetName: "net-2", CIDR: "10.0.2.0/24",
+				}),
+				port("p2", "fa:16:3e:00:00:02", "3"),
+			},
+			wantValid:    []string{"p2"},
+			wantProblems: []string{"p1 invalid-ip"},
+		},
+		{
+			name: "fixed IP of another family",
+			interfaces: []database.NodeInterface{
+				withFixedIP(port("p1", "fa:16:3e:00:00:01", "1"), database.FixedIP{
+					IPAddress: "2001:db8::5", SubnetID: "subnet-2", SubnetName: "net-2", CIDR: "10.0.2.0/24",
+				}),
+			},
+			wantProblems: []string{"p1 invalid-ip"},
+		},
+		{
+			name: "fixed IP that is not an address",
+			interfaces: []database.NodeInterface{
+				withFixedIP(port("p1", "fa:16:3e:00:00:01", "1"), database.FixedIP{
+					IPAddress: "10.0.2", SubnetID: "subnet-2", SubnetName: "net-2", CIDR: "10.0.2.0/24",
+				}),
+			},
+			wantProblems: []string{"p1 invalid-ip"},
+		},
+		{
+			name: "conflicting route tables",
+			interfaces: []database.NodeInterface{
+				func() database.NodeInterface {
+					iface := withFixedIP(port("p1", "fa:16:3e:00:00:01", "1"), database.FixedIP{
+						IPAddress: "10.0.2.5", SubnetID: "subnet-2", SubnetName: "net-2", CIDR: "10.0.2.0/24", RouteTable: 121,
+					})
+					iface.FixedIPs[0].RouteTable = 120
+					return iface
+				}(),
+				withFixedIP(port("p2", "fa:16:3e:00:00:02", "3"), database.FixedIP{
+					IPAddress: "10.0.4.5", SubnetID: "subnet-4", SubnetName: "net-4", CIDR: "10.0.4.0/24", RouteTable: 130,
+				}),
+			},
+			wantValid:    []string{"p2"},
+			wantProblems: []string{"p1 invalid-option"},
+		},
+		{
+			name: "invalid options",
+			interfaces: []database.NodeInterface{func() database.NodeInterface {
+				iface := port("p1", "fa:16:3e:00:00:01", "1")
+				metric, linkLocal := -1, "ipv4,ipv5"
+				iface.DHCP4RouteMetric = &metric
+				iface.LinkLocal = &linkLocal
+				return iface
+			}()},
+			wantProblems: []string{"p1 invalid-option", "p1 invalid-option"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ValidateInterfaces(tt.interfaces)
+
+			var valid []string
+			for _, iface := range result.Valid {
+				valid = append(valid, iface.PortID)
+			}
+			if !slices.Equal(valid, tt.wantValid) {
+				t.Errorf("valid ports = %v, want %v", valid, tt.wantValid)
+			}
+
+			var problems []string
+			for _, problem := range result.Problems {
+				problems = append(problems, problem.PortID+" "+problem.Kind)
+			}
+			if !slices.Equal(problems, tt.wantProblems) {
+				t.Errorf("problems = %q, want %q", problems, tt.wantProblems)
+			}
+		})
+	}
+}
+
+func TestResultReason(t *testing.T) {
+	result := ValidateInterfaces([]database.NodeInterface{
+		port("p1", "fa:16:3e:00:00:01", "1"),
+		port("p2", "fa:16:3e:00:00:01", "1"),
+		port("p3", "fa:16:3e:00:00:03", "3"),
+	})
+
+	want := "invalid port data: MAC fa:16:3e:00:00:01 is shared by ports p1, p2; subnet net-1 is attached to ports p1, p2"
+	if got := result.Reason("p1"); got != want {
+		t.Errorf("Reason(p1) = %q, want %q", got, want)
+	}
+	if got := result.Reason("p3"); got != "" {
+		t.Errorf("Reason(p3) = %q, want none", got)
+	}
+	if result.Rejected("p3") || !result.Rejected("p2") {
+		t.Errorf("Rejected(p2), Rejected(p3) = %v, %v, want true, false", result.Rejected("p2"), result.Rejected("p3"))
+	}
+}
@@ -3,11 +3,13 @@ CREATE DATABASE IF NOT EXISTS multinic CHARACTER SET utf8mb4 COLLATE utf8mb4_uni
 
 USE multinic;
 
--- 기존 테이블 삭제 (스키마 변경으로 인한)
-DROP TABLE IF EXISTS cr_state;
-DROP TABLE IF EXISTS multi_interface;
-DROP TABLE IF EXISTS node_table;
-DROP TABLE IF EXISTS multi_subnet;
+-- 테스트용 스키마 (pkg/database/migrations/mysql과 동일하게 유지)
+-- 운영 DB는 이 스크립트 대신 `multinic-agent migrate up`으로 스키마를 만듭니다
+CREATE TABLE IF NOT EXISTS schema_version (
+    version INT NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
 
 -- 서브넷 테이블 생성
 CREATE TABLE IF NOT EXISTS multi_subnet (
@@ -16,6 +18,16 @@ CREATE TABLE IF NOT EXISTS multi_subnet (
     subnet_name VARCHAR(255) NOT NULL,
     cidr VARCHAR(255) NOT NULL,
     network_id VARCHAR(36) NOT NULL COMMENT 'OpenStack network ID',
+    gateway_ip VARCHAR(45) NULL COMMENT 'Subnet gateway IP',
+    mtu INT NULL COMMENT 'Interface MTU (NULL: agent default)',
+    policy_routing TINYINT(1) NULL COMMENT 'Per-interface policy routing (NULL: agent setting)',
+    route_table INT NULL COMMENT 'Routing table ID for policy routing (NULL: allocated by agent)',
+    optional TINYINT(1) NULL COMMENT 'Do not wait for the interface at boot (NULL: agent setting)',
+    dhcp4_use_routes TINYINT(1) NULL COMMENT 'DHCP: install routes from the lease (NULL: agent setting)',
+    dhcp4_use_dns TINYINT(1) NULL COMMENT 'DHCP: use DNS servers from the lease (NULL: agent setting)',
+    dhcp4_use_hostname TINYINT(1) NULL COMMENT 'DHCP: use the hostname from the lease (NULL: agent setting)',
+    dhcp4_route_metric INT NULL COMMENT 'DHCP: metric of routes from the lease (NULL: agent setting)',
+    link_local VARCHAR(20) NULL COMMENT 'Link-local families, e.g. ipv6 or ipv4,ipv6; empty disables (NULL: netplan default)',
     status VARCHAR(50) DEFAULT 'active',
     created_at TIMESTAMP NULL,
     modified_at TIMESTAMP NULL,
@@ -33,6 +45,27 @@ CREATE TABLE IF NOT EXISTS node_table (
     deleted_at TIMESTAMP NULL
 );
 
+-- 인터페이스 그룹 테이블 생성 (본드/브리지)
+CREATE TABLE IF NOT EXISTS multi_interface_group (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    group_id VARCHAR(36) NOT NULL UNIQUE,
+    group_name VARCHAR(15) NOT NULL COMMENT 'Interface name on the node (e.g. bond0, br0)',
+    group_type VARCHAR(16) NOT NULL COMMENT 'bond or bridge',
+    attached_node_id VARCHAR(36) NOT NULL,
+    bond_mode VARCHAR(32) NULL COMMENT 'e.g. 802.3ad, active-backup',
+    lacp_rate VARCHAR(8) NULL COMMENT 'slow or fast',
+    mii_monitor_interval INT NULL COMMENT 'milliseconds',
+    primary_port_id VARCHAR(36) NULL COMMENT 'Primary member port (active-backup)',
+    stp TINYINT(1) NULL COMMENT 'Bridge STP',
+    mtu INT NULL,
+    status VARCHAR(50) DEFAULT 'active',
+    created_at TIMESTAMP NULL,
+    modified_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL,
+    FOREIGN KEY (attached_node_id) REFERENCES node_table(attached_node_id),
+    UNIQUE KEY unique_node_group (attached_node_id, group_name)
+);
+
 -- 인터페이스 테이블 생성
 CREATE TABLE IF NOT EXISTS multi_interface (
     id INT AUTO_INCREMENT PRIMARY KEY,
@@ -45,15 +78,81 @@ CREATE TABLE IF NOT EXISTS multi_interface (
     cr_name VARCHAR(255) NOT NULL COMMENT 'OpenstackConfig CR name',
     status VARCHAR(50) DEFAULT 'active',
     netplan_success TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Netplan apply success (0: fail/not applied, 1: success)',
+    netplan_reason VARCHAR(255) NULL COMMENT 'Why netplan was not applied (e.g. protected interface, MAC not found)',
+    parent_port_id VARCHAR(36) NULL COMMENT 'Trunk parent port ID (set for VLAN sub-ports)',
+    vlan_id INT NULL COMMENT 'VLAN ID (segmentation ID) of the sub-port',
+    group_id VARCHAR(36) NULL COMMENT 'Bond/bridge this port is a member of',
+    vnic_type VARCHAR(32) NULL COMMENT 'OpenStack binding:vnic_type (normal, direct)',
+    pci_address VARCHAR(16) NULL COMMENT 'SR-IOV VF PCI address (e.g. 0000:3b:02.1)',
+    pf_pci_address VARCHAR(16) NULL COMMENT 'SR-IOV PF PCI address',
+    sriov_vf_count INT NULL COMMENT 'Desired number of VFs on the PF',
+    sriov_switch_mode VARCHAR(16) NULL COMMENT 'PF embedded-switch-mode (legacy, switchdev)',
     created_at TIMESTAMP NULL,
     modified_at TIMESTAMP NULL,
     deleted_at TIMESTAMP NULL,
     FOREIGN KEY (subnet_id) REFERENCES multi_subnet(subnet_id),
     FOREIGN KEY (attached_node_id) REFERENCES node_table(attached_node_id),
     FOREIGN KEY (attached_node_name) REFERENCES node_table(attached_node_name),
+    FOREIGN KEY (parent_port_id) REFERENCES multi_interface(port_id),
+    FOREIGN KEY (group_id) REFERENCES multi_interface_group(group_id),
     UNIQUE KEY unique_cr_interface (cr_namespace, cr_name, port_id)
 );
 
+-- 포트 고정 IP 테이블 생성 (포트당 여러 서브넷/IP 가능)
+CREATE TABLE IF NOT EXISTS multi_interface_ip (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    port_id VARCHAR(36) NOT NULL,
+    subnet_id VARCHAR(36) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL COMMENT 'OpenStack fixed_ips[].ip_address',
+    status VARCHAR(50) DEFAULT 'active',
+    created_at TIMESTAMP NULL,
+    modified_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL,
+    FOREIGN KEY (port_id) REFERENCES multi_interface(port_id),
+    FOREIGN KEY (subnet_id) REFERENCES multi_subnet(subnet_id),
+    UNIQUE KEY unique_port_ip (port_id, ip_address)
+);
+
+-- 호스트 인터페이스 테이블 생성 (에이전트가 보고하는 노드의 실제 NIC 목록)
+CREATE TABLE IF NOT EXISTS node_host_interface (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    attached_node_name VARCHAR(255) NOT NULL,
+    interface_name VARCHAR(15) NOT NULL,
+    macaddress VARCHAR(17) NOT NULL,
+    permanent_macaddress VARCHAR(17) NULL COMMENT 'Permanent MAC (differs when enslaved to a bond)',
+    driver VARCHAR(64) NULL,
+    pci_address VARCHAR(16) NULL,
+    kind VARCHAR(32) NULL COMMENT 'Link type (device, vlan, bond, bridge, ...)',
+    physical TINYINT(1) NOT NULL DEFAULT 0,
+    oper_state VARCHAR(16) NOT NULL,
+    carrier TINYINT(1) NOT NULL DEFAULT 0,
+    speed INT NOT NULL DEFAULT -1 COMMENT 'Mbps, -1 when unknown',
+    mtu INT NOT NULL,
+    master VARCHAR(15) NULL COMMENT 'Bond or bridge the link is enslaved to',
+    addresses TEXT NULL COMMENT 'Comma separated CIDR addresses',
+    reported_at TIMESTAMP NULL,
+    UNIQUE KEY unique_node_host_interface (attached_node_name, interface_name),
+    KEY idx_host_interface_mac (macaddress)
+);
+
+-- netplan 적용 기록 테이블 생성 (적용 방법, 종료 코드, 출력, 확인 여부)
+CREATE TABLE IF NOT EXISTS netplan_apply_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    attached_node_name VARCHAR(255) NOT NULL,
+    method VARCHAR(64) NOT NULL COMMENT 'netplan-apply, systemd-run, generate+networkctl-reload, ...',
+    success TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Applied and confirmed active on the host',
+    confirmed TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Host state matched the configuration',
+    reverted TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Safe apply restored the previous configuration',
+    exit_code INT NULL COMMENT 'Exit code of the last command run',
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    error TEXT NULL,
+    stdout TEXT NULL COMMENT 'Output of the last command run',
+    stderr TEXT NULL,
+    attempts TEXT NULL COMMENT 'JSON list of every command run',
+    applied_at TIMESTAMP NULL,
+    KEY idx_apply_log_node (attached_node_name, applied_at)
+);
+
 -- CR 상태 테이블 생성
 CREATE TABLE IF NOT EXISTS cr_state (
     id INT AUTO_INCREMENT PRIMARY KEY,
@@ -64,14 +163,21 @@ CREATE TABLE IF NOT EXISTS cr_state (
     UNIQUE KEY unique_cr (cr_namespace, cr_name)
 );
 
+-- 위 스키마가 해당하는 마이그레이션 버전 기록
+INSERT IGNORE INTO schema_version (version, name, applied_at) VALUES (1, 'initial_schema', NOW());
+
 -- 테스트 데이터 삽입
 
 -- 서브넷 데이터
-INSERT INTO multi_subnet (subnet_id, subnet_name, cidr, network_id, created_at, modified_at) VALUES
-('mgmt-subnet-uuid', 'Management Network', '10.0.0.0/24', 'mgmt-network-openstack-id', NOW(), NOW()),
-('data-subnet-1-uuid', 'Data Network 1', '192.168.1.0/24', 'data-network-1-openstack-id', NOW(), NOW()),
-('data-subnet-2-uuid', 'Data Network 2', '192.168.2.0/24', 'data-network-2-openstack-id', NOW(), NOW()),
-('data-subnet-3-uuid', 'Data Network 3', '192.168.3.0/24', 'data-network-3-openstack-id', NOW(), NOW());
+INSERT INTO multi_subnet (subnet_id, subnet_name, cidr, network_id, gateway_ip, created_at, modified_at) VALUES
+('mgmt-subnet-uuid', 'Management Network', '10.0.0.0/24', 'mgmt-network-openstack-id', '10.0.0.1', NOW(), NOW()),
+('data-subnet-1-uuid', 'Data Network 1', '192.168.1.0/24', 'data-network-1-openstack-id', '192.168.1.1', NOW(), NOW()),
+('data-subnet-2-uuid', 'Data Network 2', '192.168.2.0/24', 'data-network-2-openstack-id', '192.168.2.1', NOW(), NOW()),
+('data-subnet-3-uuid', 'Data Network 3', '192.168.3.0/24', 'data-network-3-openstack-id', NULL, NOW(), NOW());
+
+-- VLAN 서브넷 데이터 (트렁크 서브포트용)
+INSERT INTO multi_subnet (subnet_id, subnet_name, cidr, network_id, gateway_ip, mtu, created_at, modified_at) VALUES
+('vlan-subnet-100-uuid', 'VLAN 100 Network', '172.16.100.0/24', 'vlan-network-100-openstack-id', '172.16.100.1', 1400, NOW(), NOW());
 
 -- 노드 데이터 (실제 클러스터 노드 포함)
 INSERT INTO node_table (attached_node_id, attached_node_name, created_at, modified_at) VALUES
@@ -98,11 +204,32 @@ INSERT INTO multi_interface (port_id, subnet_id, macaddress, attached_node_id, a
 ('port-2-3-uuid', 'data-subnet-2-uuid', 'fa:16:3e:66:66:66', 'node-2-uuid', 'worker-node-2', 'openstack-system', 'test-config-2', 0, NOW(), NOW()),
 ('port-2-4-uuid', 'data-subnet-3-uuid', 'fa:16:3e:77:77:77', 'node-2-uuid', 'worker-node-2', 'openstack-system', 'test-config-2', 0, NOW(), NOW());
 
+-- worker-node-1의 VLAN 서브포트 (port-1-2 위의 VLAN 100)
+INSERT INTO multi_interface (port_id, subnet_id, macaddress, attached_node_id, attached_node_name, cr_namespace, cr_name, netplan_success, parent_port_id, vlan_id, created_at, modified_at) VALUES
+('port-1-2-vlan100-uuid', 'vlan-subnet-100-uuid', 'fa:16:3e:22:22:23', 'node-1-uuid', 'worker-node-1', 'openstack-system', 'test-config-1', 0, 'port-1-2-uuid', 100, NOW(), NOW());
+
+-- worker-node-3의 LACP 본드 (두 데이터 포트를 bond0으로 구성)
+INSERT INTO multi_interface_group (group_id, group_name, group_type, attached_node_id, bond_mode, lacp_rate, mii_monitor_interval, created_at, modified_at) VALUES
+('bond-3-0-uuid', 'bond0', 'bond', 'node-3-uuid', '802.3ad', 'fast', 100, NOW(), NOW());
+
+INSERT INTO multi_interface (port_id, subnet_id, macaddress, attached_node_id, attached_node_name, cr_namespace, cr_name, netplan_success, group_id, created_at, modified_at) VALUES
+('port-3-1-uuid', 'data-subnet-1-uuid', 'fa:16:3e:88:88:81', 'node-3-uuid', 'worker-node-3', 'openstack-system', 'test-config-3', 0, 'bond-3-0-uuid', NOW(), NOW()),
+('port-3-2-uuid', 'data-subnet-1-uuid', 'fa:16:3e:88:88:82', 'node-3-uuid', 'worker-node-3', 'openstack-system', 'test-config-3', 0, 'bond-3-0-uuid', NOW(), NOW());
+
+-- 고정 IP 데이터 (고정 IP가 없는 포트는 DHCP로 구성됨)
+INSERT INTO multi_interface_ip (port_id, subnet_id, ip_address, created_at, modified_at) VALUES
+-- worker-node-2의 port-2-4는 두 서브넷에 걸친 고정 IP를 가짐
+('port-2-4-uuid', 'data-subnet-3-uuid', '192.168.3.24', NOW(), NOW()),
+('port-2-4-uuid', 'data-subnet-2-uuid', '192.168.2.24', NOW(), NOW()),
+-- worker-node-3의 bond0 주소 (멤버 포트의 고정 IP는 본드에 할당됨)
+('port-3-1-uuid', 'data-subnet-1-uuid', '192.168.1.31', NOW(), NOW());
+
 -- CR 상태 데이터
 INSERT INTO cr_state (cr_namespace, cr_name, spec_hash) VALUES
 ('openstack-system', 'test-config-cp', 'cp123abc456def'),
 ('openstack-system', 'test-config-1', 'abc123def456789'),
-('openstack-system', 'test-config-2', 'def456ghi789abc');
+('openstack-system', 'test-config-2', 'def456ghi789abc'),
+('openstack-system', 'test-config-3', 'ghi789jkl012def');
 
 -- 데이터 확인
 SELECT 